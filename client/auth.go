@@ -0,0 +1,66 @@
+package xtpclient
+
+import (
+  "crypto/ed25519"
+  "errors"
+
+  pb "github.com/libp2p/go-xtp-ctl/pb"
+  xrpc "github.com/libp2p/go-xtp-ctl/rpc"
+)
+
+// Credentials authenticate a client to servers with a policy. Set one of
+// Token or Key. Clients with a TLS certificate may not need any.
+type Credentials struct {
+  Token string             // shared token
+  Key   ed25519.PrivateKey // signs the server's challenge
+}
+
+// Authenticate authenticates the client with cred, sets its Identity,
+// and updates its Xports: the policy of the identity may allow others.
+// Clients usually authenticate in NewAuthClient, as servers refuse other
+// rpcs until they do.
+func (c *Client) Authenticate(cred *Credentials) error {
+  s, err := c.dial()
+  if err != nil {
+    return err
+  }
+  defer s.Close()
+
+  if c.Identity, err = authenticate(s, cred); err != nil {
+    return err
+  }
+  return c.getTransports(s)
+}
+
+func authenticate(s IoStream, cred *Credentials) (string, error) {
+  var req *pb.AuthReq
+  switch {
+  case cred.Key != nil:
+    method := pb.AuthReq_Challenge
+    res, err := xrpc.AuthReq(s, &pb.AuthReq{Method: &method})
+    if err != nil {
+      return "", err
+    }
+    if len(res.GetChallenge()) == 0 {
+      return "", xrpc.ErrProtocol
+    }
+
+    method = pb.AuthReq_Ed25519
+    req = &pb.AuthReq{
+      Method:    &method,
+      PublicKey: cred.Key.Public().(ed25519.PublicKey),
+      Signature: ed25519.Sign(cred.Key, xrpc.AuthMessage(res.GetChallenge())),
+    }
+  case cred.Token != "":
+    method := pb.AuthReq_Token
+    req = &pb.AuthReq{Method: &method, Token: &cred.Token}
+  default:
+    return "", errors.New("no credentials")
+  }
+
+  res, err := xrpc.AuthReq(s, req)
+  if err != nil {
+    return "", err
+  }
+  return res.GetIdentity(), nil
+}
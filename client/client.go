@@ -1,46 +1,169 @@
 package xtpclient
 
 import (
+  "context"
+  "errors"
+  "fmt"
+  "strings"
+
   ma "github.com/multiformats/go-multiaddr"
-  manet "github.com/multiformats/go-multiaddr-net"
   proto "github.com/gogo/protobuf/proto"
   xnet "github.com/libp2p/go-xtp-ctl/net"
   xrpc "github.com/libp2p/go-xtp-ctl/rpc"
+  xtptrace "github.com/libp2p/go-xtp-ctl/trace"
   pb "github.com/libp2p/go-xtp-ctl/pb"
 )
 
 type Client struct {
-  Conn   xnet.Conn // connection to the server
-  Xports []xnet.Transport
+  Conn     xnet.Conn // connection to the server
+  Xports   []xnet.Transport
+  Hello    *pb.Hello // the server's hello
+  Identity string    // who the server authenticated the client as, if it did
+
+  // Session is the token to resume the session with, once it ended (see
+  // ResumeClient). Each token resumes it once. It is nil if the server
+  // does not keep ended sessions.
+  Session []byte
 }
 
 func NewClient(server ma.Multiaddr) (*Client, error) {
-  c, err := manet.Dial(server)
+  return NewSecureClient(server, nil)
+}
+
+// NewSecureClient is NewClient, with the connection to the server secured
+// with sec (see xnet.Security). The server must use the same layer.
+func NewSecureClient(server ma.Multiaddr, sec *xnet.Security) (*Client, error) {
+  return NewAuthClient(server, sec, nil)
+}
+
+// NewAuthClient is NewSecureClient, authenticated with cred (if not nil)
+// to servers with a policy.
+func NewAuthClient(server ma.Multiaddr, sec *xnet.Security, cred *Credentials) (*Client, error) {
+  c, err := xnet.DialSecure(server, sec)
   if err != nil {
     return nil, err
   }
+  return NewClientConnAuth(c, cred)
+}
 
-  // layer muxer
-  c2, err := xnet.XtpCtlConn(c, false)
+// ResumeClient is NewSecureClient, resuming the ended session with token
+// session (see Client.Session): the client gets the descriptors, and the
+// access, it had on the server. It fails with xrpc.ErrNotFound if the
+// server no longer has the session. On servers with a policy, sec must
+// prove the session's identity, with the same TLS client certificate.
+func ResumeClient(server ma.Multiaddr, sec *xnet.Security, session []byte) (*Client, error) {
+  c, err := xnet.DialSecure(server, sec)
   if err != nil {
     return nil, err
   }
+  return ResumeClientConn(c, session)
+}
 
-  client := &Client{Conn: c2}
-  // first, figure out the transports
-  if err := client.getTransports(); err != nil {
+// ResumeClientConn is ResumeClient, on connection c. See NewClientConn.
+func ResumeClientConn(c xnet.Conn, session []byte) (*Client, error) {
+  client := &Client{Conn: c}
+  if err := client.start(nil, session); err != nil {
     client.Close()
     return nil, err
   }
   return client, nil
 }
 
-func (c *Client) getTransports() error {
-  s, err := c.Conn.Dial()
+// NewClientConn starts a client session on c, an already multiplexed
+// connection to the server. Use it with transports other than tcp, like
+// xtpimpls.MemoryTransport.
+func NewClientConn(c xnet.Conn) (*Client, error) {
+  return NewClientConnAuth(c, nil)
+}
+
+// NewClientConnAuth is NewClientConn, authenticated with cred (if not
+// nil).
+func NewClientConnAuth(c xnet.Conn, cred *Credentials) (*Client, error) {
+  client := &Client{Conn: c}
+  if err := client.start(cred, nil); err != nil {
+    client.Close()
+    return nil, err
+  }
+  return client, nil
+}
+
+// start says hello to the server (resuming session resume, if not nil),
+// authenticates, and then figures out the transports, on the first stream
+// of the session.
+func (c *Client) start(cred *Credentials, resume []byte) error {
+  s, err := c.dial()
   if err != nil {
     return err
   }
+  defer s.Close()
+
+  res, err := xrpc.ResumeReq(s, xrpc.NewHello(clientRPCs, nil), resume)
+  if err != nil {
+    return err
+  }
+  c.Hello, c.Session = res.Hello, res.Session
+  if cred != nil {
+    if c.Identity, err = authenticate(s, cred); err != nil {
+      return err
+    }
+  }
+  return c.getTransports(s)
+}
+
+// openStream opens a new xtp-ctl stream to the server, traced as a child
+// of the span in ctx.
+func (c *Client) openStream(ctx context.Context) (_ xnet.Stream, err error) {
+  _, span := xtptrace.Start(ctx, "xtpclient.OpenStream")
+  defer func() { xtptrace.End(span, err) }()
+  return c.dial()
+}
+
+// dial opens a new xtp-ctl stream to the server.
+func (c *Client) dial() (xnet.Stream, error) {
+  s, err := c.Conn.Dial()
+  if err != nil {
+    return nil, err
+  }
+  return &ctlStream{s, c}, nil
+}
+
+// Stream opens a new xtp-ctl stream to the server, to send rpcs on with
+// the xrpc functions. What it writes is held to the server's
+// MaxMessageSize.
+func (c *Client) Stream() (xnet.Stream, error) {
+  return c.dial()
+}
+
+// Stats returns the server's traffic stats of descriptors ids (see
+// Descriptor), or of all the client's descriptors if there are none.
+func (c *Client) Stats(ids ...int64) ([]*pb.Stats, error) {
+  s, err := c.dial()
+  if err != nil {
+    return nil, err
+  }
+  defer s.Close()
+
+  return xrpc.StatsReq(s, ids)
+}
+
+// Usage returns what the client uses on the server, and what all
+// clients use, with their limits.
+func (c *Client) Usage() (*pb.UsageRes, error) {
+  s, err := c.dial()
+  if err != nil {
+    return nil, err
+  }
+  defer s.Close()
+
+  return xrpc.UsageReq(s)
+}
 
+// Supports returns whether the server handles rpc t.
+func (c *Client) Supports(t pb.RPC_Type) bool {
+  return xrpc.Supports(c.Hello, t)
+}
+
+func (c *Client) getTransports(s xnet.Stream) error {
   items, err := xrpc.ListReq(s, []pb.TType{pb.TType_TTypeTransport})
   if err != nil {
     return err
@@ -71,3 +194,138 @@ func (c *Client) getTransports() error {
 func (c *Client) Close() error {
   return c.Conn.Close()
 }
+
+// ErrNoTransport is returned when the server has no transport for an address.
+var ErrNoTransport = errors.New("server has no transport for address")
+
+// Descriptor is implemented by the remote transports, listeners, dialers,
+// conns and streams of a client. Id returns the server's descriptor id.
+type Descriptor interface {
+  Id() int64
+}
+
+// Transport returns the server transport with code (e.g. "/tcp"), or nil.
+func (c *Client) Transport(code string) xnet.Transport {
+  for _, t := range c.Xports {
+    if t.Code() == code {
+      return t
+    }
+  }
+  return nil
+}
+
+// TransportFor returns the server transport for the multiaddr a: the one
+// whose code matches the most protocols of a. For example, /tcp matches
+// /ip4/1.2.3.4/tcp/80.
+func (c *Client) TransportFor(a ma.Multiaddr) (xnet.Transport, error) {
+  var best xnet.Transport
+  bestn := 0
+  for _, t := range c.Xports {
+    if n := codeMatches(t.Code(), a); n > bestn {
+      best, bestn = t, n
+    }
+  }
+  if best == nil {
+    return nil, fmt.Errorf("%w: %s", ErrNoTransport, a)
+  }
+  return best, nil
+}
+
+// codeMatches returns how many protocols of transport code match a, in
+// a row. It returns 0 if they don't all match.
+func codeMatches(code string, a ma.Multiaddr) int {
+  names := strings.Split(strings.Trim(code, "/"), "/")
+  ps := a.Protocols()
+  for i := 0; i+len(names) <= len(ps); i++ {
+    n := 0
+    for n < len(names) && ps[i+n].Name == names[n] {
+      n++
+    }
+    if n == len(names) {
+      return n
+    }
+  }
+  return 0
+}
+
+// Dial dials raddr through the server, with the transport for raddr.
+// It is a drop-in replacement for manet.Dial.
+func (c *Client) Dial(raddr ma.Multiaddr) (xnet.Conn, error) {
+  return c.DialContext(context.Background(), raddr)
+}
+
+// DialContext is Dial, aborted when ctx is done.
+func (c *Client) DialContext(ctx context.Context, raddr ma.Multiaddr) (xnet.Conn, error) {
+  t, err := c.TransportFor(raddr)
+  if err != nil {
+    return nil, err
+  }
+  return xnet.TransportWithContext(t).DialContext(ctx, raddr)
+}
+
+// Listen listens on laddr at the server, with the transport for laddr.
+// It is a drop-in replacement for manet.Listen.
+func (c *Client) Listen(laddr ma.Multiaddr) (xnet.Listener, error) {
+  t, err := c.TransportFor(laddr)
+  if err != nil {
+    return nil, err
+  }
+  return t.Listen(laddr)
+}
+
+// clientRPCs are the responses the client understands, sent in its Hello.
+var clientRPCs = []pb.RPC_Type{
+  pb.RPC_NoOp,
+  pb.RPC_ListRes,
+  pb.RPC_CloseRes,
+  pb.RPC_ListenRes,
+  pb.RPC_AcceptRes,
+  pb.RPC_DialerRes,
+  pb.RPC_DialRes,
+  pb.RPC_HelloRes,
+  pb.RPC_ShutdownRes,
+  pb.RPC_WatchRes,
+  pb.RPC_StatsRes,
+  pb.RPC_AuthRes,
+  pb.RPC_UsageRes,
+}
+
+// rpcContext runs the blocking rpc f on the xtp-ctl stream s. If ctx is
+// done first, it closes s, which tells the server to abort the rpc.
+func rpcContext(ctx context.Context, s IoStream, f func() error) error {
+  if ctx.Done() == nil {
+    return f() // can't be cancelled.
+  }
+  if err := ctx.Err(); err != nil {
+    return err
+  }
+
+  errc := make(chan error, 1)
+  go func() {
+    errc <- f()
+  }()
+
+  select {
+  case err := <-errc:
+    return err
+  case <-ctx.Done():
+    s.Close()
+    return ctx.Err()
+  }
+}
+
+var (
+  _ Descriptor = (*transport)(nil)
+  _ Descriptor = (*listener)(nil)
+  _ Descriptor = (*dialer)(nil)
+  _ Descriptor = (*conn)(nil)
+  _ Descriptor = (*stream)(nil)
+
+  _ xnet.TransportContext = (*transport)(nil)
+  _ xnet.DialerContext    = (*dialer)(nil)
+  _ xnet.ListenerContext  = (*listener)(nil)
+  _ xnet.ConnContext      = (*conn)(nil)
+  _ xnet.DeadlineStream   = (*stream)(nil)
+  _ xnet.HalfCloseStream  = (*stream)(nil)
+  _ xnet.ResetStream      = (*stream)(nil)
+)
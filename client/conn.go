@@ -1,10 +1,13 @@
 package xtpclient
 
 import (
+  "context"
+
   pb "github.com/libp2p/go-xtp-ctl/pb"
   ma "github.com/multiformats/go-multiaddr"
   xnet "github.com/libp2p/go-xtp-ctl/net"
   xrpc "github.com/libp2p/go-xtp-ctl/rpc"
+  xtptrace "github.com/libp2p/go-xtp-ctl/trace"
 )
 
 type conn struct {
@@ -14,8 +17,15 @@ type conn struct {
   client *Client      // the xtp-ctl client
   laddr  ma.Multiaddr // the local address of this conn
   raddr  ma.Multiaddr // the remote address of this conn
+
+  xnet.ConnDeadline // of Dial and Accept
 }
 
+var _ xnet.DeadlineConn = (*conn)(nil)
+
+func (c *conn) Id() int64 {
+  return c.id
+}
 
 // LocalMultiaddr returns the local Multiaddr associated
 // with this connection
@@ -31,36 +41,78 @@ func (c *conn) RemoteMultiaddr() ma.Multiaddr {
 
 // Dial attempts to open a new stream across Conn to the other side.
 func (c *conn) Dial() (xnet.Stream, error) {
+  return c.DialContext(context.Background())
+}
+
+// DialContext is Dial, aborted when ctx is done.
+func (c *conn) DialContext(ctx context.Context) (_ xnet.Stream, err error) {
+  ctx, span := xtptrace.Start(ctx, "xtpclient.conn.Dial")
+  defer func() { xtptrace.End(span, err) }()
+  ctx, cancel := c.Context(ctx)
+  defer cancel()
+  defer func() { err = c.Err(ctx, err) }()
+
   // open a new data stream
-  s, err := c.client.Conn.Dial()
+  s, err := c.client.openStream(ctx)
   if err != nil {
     return nil, err
   }
 
-  // Send an accept request, wait for an accept response
-  res, err := xrpc.DialReq(s, c.id, nil)
+  // Send a dial request, wait for a dial response
+  var res *pb.DialRes
+  err = rpcContext(ctx, s, func() (err error) {
+    res, err = xrpc.DialReq(xrpc.WithContext(ctx, s), c.id, nil)
+    return err
+  })
   if err != nil {
+    s.Close()
     return nil, err
   }
 
-  return newStream(c.client, s, res.Stream, c)
+  st, err := newStream(c.client, s, res.Stream, c)
+  if err != nil {
+    s.Close()
+    return nil, err
+  }
+  return st, nil
 }
 
 // Accept accepts an incoming conn.Dial from the other side.
 func (c *conn) Accept() (xnet.Stream, error) {
+  return c.AcceptContext(context.Background())
+}
+
+// AcceptContext is Accept, aborted when ctx is done.
+func (c *conn) AcceptContext(ctx context.Context) (_ xnet.Stream, err error) {
+  ctx, span := xtptrace.Start(ctx, "xtpclient.conn.Accept")
+  defer func() { xtptrace.End(span, err) }()
+  ctx, cancel := c.Context(ctx)
+  defer cancel()
+  defer func() { err = c.Err(ctx, err) }()
+
   // open a new data stream
-  s, err := c.client.Conn.Dial()
+  s, err := c.client.openStream(ctx)
   if err != nil {
     return nil, err
   }
 
   // Send an accept request, wait for an accept response
-  res, err := xrpc.AcceptReq(s, c.id)
+  var res *pb.AcceptRes
+  err = rpcContext(ctx, s, func() (err error) {
+    res, err = xrpc.AcceptReq(xrpc.WithContext(ctx, s), c.id)
+    return err
+  })
   if err != nil {
+    s.Close()
     return nil, err
   }
 
-  return newStream(c.client, s, res.Stream, c)
+  st, err := newStream(c.client, s, res.Stream, c)
+  if err != nil {
+    s.Close()
+    return nil, err
+  }
+  return st, nil
 }
 
 // Close closes the dialer.
@@ -70,6 +122,37 @@ func (c *conn) Close() error {
   return err
 }
 
+// dialConn dials raddr from a transport or dialer (id), on a new
+// xtp-ctl stream, which becomes the new conn's ctls.
+func dialConn(ctx context.Context, c *Client, id int64, raddr ma.Multiaddr) (_ *conn, err error) {
+  ctx, span := xtptrace.Start(ctx, "xtpclient.Dial")
+  defer func() { xtptrace.End(span, err) }()
+
+  // open a new control stream
+  s, err := c.openStream(ctx)
+  if err != nil {
+    return nil, err
+  }
+
+  // Send a dial request, wait for the dial response
+  var res *pb.DialRes
+  err = rpcContext(ctx, s, func() (err error) {
+    res, err = xrpc.DialReq(xrpc.WithContext(ctx, s), id, raddr)
+    return err
+  })
+  if err != nil {
+    s.Close()
+    return nil, err
+  }
+
+  cn, err := newConn(c, s, res.Conn)
+  if err != nil {
+    s.Close()
+    return nil, err
+  }
+  return cn, nil
+}
+
 func newConn(c *Client, ctls xnet.Stream, cn *pb.Conn) (*conn, error) {
   if !cn.Valid() {
     return nil, xrpc.ErrInvalidMessage
@@ -0,0 +1,42 @@
+package xtpclient
+
+import (
+  "time"
+
+  xnet "github.com/libp2p/go-xtp-ctl/net"
+)
+
+// ctlStream is an xtp-ctl stream to the server. It keeps the rpcs sent
+// on it within the size the server takes (see xrpc.PeerLimiter).
+type ctlStream struct {
+  xnet.Stream
+  client *Client
+}
+
+// PeerMessageSizeMax returns the largest rpc the server takes, once it
+// said hello.
+func (s *ctlStream) PeerMessageSizeMax() int {
+  return int(s.client.Hello.GetMaxMessageSize())
+}
+
+// CloseWrite half-closes the stream, if it can: the server reads io.EOF.
+func (s *ctlStream) CloseWrite() error {
+  return xnet.CloseWrite(s.Stream)
+}
+
+// CloseRead shuts down the reading side of the stream, if it can.
+func (s *ctlStream) CloseRead() error {
+  return xnet.CloseRead(s.Stream)
+}
+
+func (s *ctlStream) SetDeadline(t time.Time) error {
+  return xnet.SetDeadline(s.Stream, t)
+}
+
+func (s *ctlStream) SetReadDeadline(t time.Time) error {
+  return xnet.SetReadDeadline(s.Stream, t)
+}
+
+func (s *ctlStream) SetWriteDeadline(t time.Time) error {
+  return xnet.SetWriteDeadline(s.Stream, t)
+}
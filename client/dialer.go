@@ -1,6 +1,8 @@
 package xtpclient
 
 import (
+  "context"
+
   pb "github.com/libp2p/go-xtp-ctl/pb"
   ma "github.com/multiformats/go-multiaddr"
   xnet "github.com/libp2p/go-xtp-ctl/net"
@@ -15,6 +17,10 @@ type dialer struct {
   laddr  ma.Multiaddr // the address of this dialer
 }
 
+func (d *dialer) Id() int64 {
+  return d.id
+}
+
 // Multiaddr returns the dialer's (local) Multiaddr.
 func (d *dialer) Multiaddr() ma.Multiaddr {
   return d.laddr
@@ -22,19 +28,12 @@ func (d *dialer) Multiaddr() ma.Multiaddr {
 
 // Dial dials the given multiaddr and sets up a connection.
 func (d *dialer) Dial(raddr ma.Multiaddr) (xnet.Conn, error) {
-  // open a new data stream
-  s, err := d.client.Conn.Dial()
-  if err != nil {
-    return nil, err
-  }
-
-  // Send an accept request, wait for an accept response
-  res, err := xrpc.DialReq(s, d.id, raddr)
-  if err != nil {
-    return nil, err
-  }
+  return d.DialContext(context.Background(), raddr)
+}
 
-  return newConn(d.client, s, res.Conn)
+// DialContext is Dial, aborted when ctx is done.
+func (d *dialer) DialContext(ctx context.Context, raddr ma.Multiaddr) (xnet.Conn, error) {
+  return dialConn(ctx, d.client, d.id, raddr)
 }
 
 // Close closes the dialer.
@@ -1,10 +1,13 @@
 package xtpclient
 
 import (
+  "context"
+
   pb "github.com/libp2p/go-xtp-ctl/pb"
   ma "github.com/multiformats/go-multiaddr"
   xnet "github.com/libp2p/go-xtp-ctl/net"
   xrpc "github.com/libp2p/go-xtp-ctl/rpc"
+  xtptrace "github.com/libp2p/go-xtp-ctl/trace"
 )
 
 type listener struct {
@@ -15,6 +18,10 @@ type listener struct {
   laddr  ma.Multiaddr // the address of this listener.
 }
 
+func (l *listener) Id() int64 {
+  return l.id
+}
+
 // Multiaddr returns the listener's (local) Multiaddr.
 func (l *listener) Multiaddr() ma.Multiaddr {
   return l.laddr
@@ -23,19 +30,37 @@ func (l *listener) Multiaddr() ma.Multiaddr {
 // Accept waits for and returns the next connection to the listener.
 // Returns a Multiaddr friendly Conn
 func (l *listener) Accept() (xnet.Conn, error) {
+  return l.AcceptContext(context.Background())
+}
+
+// AcceptContext is Accept, aborted when ctx is done.
+func (l *listener) AcceptContext(ctx context.Context) (_ xnet.Conn, err error) {
+  ctx, span := xtptrace.Start(ctx, "xtpclient.listener.Accept")
+  defer func() { xtptrace.End(span, err) }()
+
   // open a new data stream
-  s, err := l.client.Conn.Dial()
+  s, err := l.client.openStream(ctx)
   if err != nil {
     return nil, err
   }
 
   // Send an accept request, wait for an accept response
-  res, err := xrpc.AcceptReq(s, l.id)
+  var res *pb.AcceptRes
+  err = rpcContext(ctx, s, func() (err error) {
+    res, err = xrpc.AcceptReq(xrpc.WithContext(ctx, s), l.id)
+    return err
+  })
   if err != nil {
+    s.Close()
     return nil, err
   }
 
-  return newConn(l.client, s, res.Conn)
+  c, err := newConn(l.client, s, res.Conn)
+  if err != nil {
+    s.Close()
+    return nil, err
+  }
+  return c, nil
 }
 
 // Close closes the listener.
@@ -1,7 +1,10 @@
 package xtpclient
 
 import (
+  "errors"
   "io"
+  "sync"
+  "time"
 
   pb "github.com/libp2p/go-xtp-ctl/pb"
   xnet "github.com/libp2p/go-xtp-ctl/net"
@@ -11,13 +14,34 @@ import (
 type stream struct {
   id     int64
   tid    int64
-  ctls   IoStream // the xtp-ctl stream for this conn
-  client *Client  // the xtp-ctl client
-  conn   *conn    // the conn this stream belongs to
+  ctls   xnet.Stream // the xtp-ctl stream, carrying the data
+  client *Client     // the xtp-ctl client
+  conn   *conn       // the conn this stream belongs to
+
+  lk      sync.Mutex
+  wclosed bool
+  rclosed bool
+  eof     bool // the server passed the remote's half-close on
+}
+
+func (s *stream) Id() int64 {
+  return s.id
 }
 
 func (s *stream) Read(buf []byte) (int, error) {
-  return s.ctls.Read(buf)
+  s.lk.Lock()
+  rclosed := s.rclosed
+  s.lk.Unlock()
+  if rclosed {
+    return 0, io.EOF
+  }
+  n, err := s.ctls.Read(buf)
+  if err == io.EOF {
+    s.lk.Lock()
+    s.eof = true
+    s.lk.Unlock()
+  }
+  return n, err
 }
 
 func (s *stream) Write(buf []byte) (int, error) {
@@ -30,10 +54,84 @@ func (s *stream) Conn() xnet.Conn {
 }
 
 
-// Close closes the dialer.
-func (s *stream) Close() error {
-  err := xrpc.CloseReq(s.ctls, s.id)
+// SetDeadline sets the read and write deadlines of the stream's data,
+// which is carried by its ctls.
+func (s *stream) SetDeadline(t time.Time) error {
+  return xnet.SetDeadline(s.ctls, t)
+}
+
+func (s *stream) SetReadDeadline(t time.Time) error {
+  return xnet.SetReadDeadline(s.ctls, t)
+}
+
+func (s *stream) SetWriteDeadline(t time.Time) error {
+  return xnet.SetWriteDeadline(s.ctls, t)
+}
+
+// CloseWrite shuts down the writing side of the stream. The remote reads
+// io.EOF after the data written before: the half-close goes in-band, as
+// the end of ctls. If the remote stream can't be half-closed, the server
+// tears the stream down.
+func (s *stream) CloseWrite() error {
+  s.lk.Lock()
+  if s.wclosed {
+    s.lk.Unlock()
+    return nil
+  }
+  s.wclosed = true
+  s.lk.Unlock()
+  return xnet.CloseWrite(s.ctls)
+}
+
+// CloseRead shuts down the reading side of the stream. Reads return
+// io.EOF, and the server drops the remote's data.
+func (s *stream) CloseRead() error {
+  s.lk.Lock()
+  s.rclosed = true
+  s.lk.Unlock()
+  return s.shutdown(pb.ShutdownReq_Read)
+}
+
+// Reset aborts the stream, both ways. A stream the server already tore
+// down, both sides done, resets fine.
+func (s *stream) Reset() error {
+  err := s.shutdown(pb.ShutdownReq_Reset)
   s.ctls.Close()
+  if errors.Is(err, xrpc.ErrNotFound) {
+    return nil
+  }
+  return err
+}
+
+// shutdown sends a ShutdownReq for the stream, on a new xtp-ctl stream,
+// as the stream's own carries its data.
+func (s *stream) shutdown(how pb.ShutdownReq_How) error {
+  st, err := s.client.dial()
+  if err != nil {
+    return err
+  }
+  defer st.Close()
+  return xrpc.ShutdownReq(st, s.id, how)
+}
+
+// Close closes the stream: it half-closes it, and, unless the remote is
+// done writing already, stops reading, so the server tears it down
+// without waiting for the remote.
+func (s *stream) Close() error {
+  err := s.CloseWrite()
+  s.lk.Lock()
+  reading := !s.eof && !s.rclosed
+  s.rclosed = true
+  s.lk.Unlock()
+
+  if reading {
+    if err2 := s.shutdown(pb.ShutdownReq_Read); err2 != nil && !errors.Is(err2, xrpc.ErrNotFound) {
+      err = err2 // torn down already.
+    }
+  }
+  if err2 := s.ctls.Close(); err == nil {
+    err = err2
+  }
   return err
 }
 
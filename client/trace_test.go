@@ -0,0 +1,130 @@
+package xtpclient_test
+
+import (
+  "context"
+  "sync"
+  "testing"
+
+  ximpls "github.com/libp2p/go-xtp-ctl/impls"
+  xrpc "github.com/libp2p/go-xtp-ctl/rpc"
+  "github.com/libp2p/go-xtp-ctl/xtptest"
+  ma "github.com/multiformats/go-multiaddr"
+
+  "go.opentelemetry.io/otel"
+  sdktrace "go.opentelemetry.io/otel/sdk/trace"
+  "go.opentelemetry.io/otel/trace"
+)
+
+// started records the sampled spans as they start: the server starts its
+// spans before it answers, so they are there once a request returns.
+type started struct {
+  lk    sync.Mutex
+  spans []sdktrace.ReadOnlySpan
+}
+
+func (p *started) OnStart(_ context.Context, s sdktrace.ReadWriteSpan) {
+  p.lk.Lock()
+  p.spans = append(p.spans, s)
+  p.lk.Unlock()
+}
+
+func (p *started) OnEnd(sdktrace.ReadOnlySpan)      {}
+func (p *started) Shutdown(context.Context) error   { return nil }
+func (p *started) ForceFlush(context.Context) error { return nil }
+
+// of returns the spans of trace tid, by name.
+func (p *started) of(tid trace.TraceID) map[string]sdktrace.ReadOnlySpan {
+  p.lk.Lock()
+  defer p.lk.Unlock()
+  spans := map[string]sdktrace.ReadOnlySpan{}
+  for _, s := range p.spans {
+    if s.SpanContext().TraceID() == tid {
+      spans[s.Name()] = s
+    }
+  }
+  return spans
+}
+
+// withTracing traces with an sdk TracerProvider until the test ends.
+func withTracing(t *testing.T) (*started, trace.Tracer) {
+  p := &started{}
+  tp := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(p))
+  prev := otel.GetTracerProvider()
+  otel.SetTracerProvider(tp)
+  t.Cleanup(func() {
+    otel.SetTracerProvider(prev)
+    tp.Shutdown(context.Background())
+  })
+  return p, tp.Tracer("test")
+}
+
+// TestTraceDial checks that a dial is traced on both ends, in one trace.
+func TestTraceDial(t *testing.T) {
+  p, tr := withTracing(t)
+  h := xtptest.New(t, &ximpls.MemoryTransport{})
+
+  addr := ma.StringCast("/memory/trace-dial")
+  l, err := h.Client.Listen(addr)
+  if err != nil {
+    t.Fatal(err)
+  }
+  defer l.Close()
+  go func() {
+    if c, err := l.Accept(); err == nil {
+      c.Close()
+    }
+  }()
+
+  ctx, root := tr.Start(context.Background(), "test")
+  c, err := h.Client.DialContext(ctx, addr)
+  if err != nil {
+    t.Fatal(err)
+  }
+  c.Close()
+  root.End()
+
+  spans := p.of(root.SpanContext().TraceID())
+  parents := []struct{ name, parent string }{
+    {"xtpclient.Dial", "test"},
+    {"xrpc.DialReq", "xtpclient.Dial"},
+    {"xtpserver.DialReq", "xrpc.DialReq"},
+    {"transport.Dial", "xtpserver.DialReq"},
+  }
+  for _, pc := range parents {
+    s, ps := spans[pc.name], spans[pc.parent]
+    if s == nil || ps == nil {
+      t.Fatalf("missing %s or %s, in %d spans", pc.name, pc.parent, len(spans))
+    }
+    if s.Parent().SpanID() != ps.SpanContext().SpanID() {
+      t.Errorf("%s is not a child of %s", pc.name, pc.parent)
+    }
+  }
+  if !spans["xtpserver.DialReq"].Parent().IsRemote() {
+    t.Error("the server span's parent is not remote")
+  }
+}
+
+// TestTraceUnsampled checks that the server honours the sampled flag of
+// the trace context it gets.
+func TestTraceUnsampled(t *testing.T) {
+  p, _ := withTracing(t)
+  h := xtptest.New(t, &ximpls.MemoryTransport{})
+
+  for _, flags := range []trace.TraceFlags{0, trace.FlagsSampled} {
+    sc := trace.NewSpanContext(trace.SpanContextConfig{
+      TraceID:    trace.TraceID{byte(flags) + 1},
+      SpanID:     trace.SpanID{1},
+      TraceFlags: flags,
+      Remote:     true,
+    })
+    ctx := trace.ContextWithRemoteSpanContext(context.Background(), sc)
+    if err := xrpc.NoOpReq(xrpc.WithContext(ctx, h.Stream())); err != nil {
+      t.Fatal(err)
+    }
+
+    _, traced := p.of(sc.TraceID())["xtpserver.NoOp"]
+    if traced != flags.IsSampled() {
+      t.Errorf("flags %s: server span traced: %v", flags, traced)
+    }
+  }
+}
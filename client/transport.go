@@ -1,6 +1,8 @@
 package xtpclient
 
 import (
+  "context"
+
   ma "github.com/multiformats/go-multiaddr"
   xnet "github.com/libp2p/go-xtp-ctl/net"
   xrpc "github.com/libp2p/go-xtp-ctl/rpc"
@@ -14,13 +16,17 @@ type transport struct {
   code   string
 }
 
+func (t *transport) Id() int64 {
+  return t.id
+}
+
 func (t *transport) Code() string {
   return t.code
 }
 
 func (t *transport) Listen(laddr ma.Multiaddr) (xnet.Listener, error) {
   // open a new control stream
-  s, err := t.client.Conn.Dial()
+  s, err := t.client.dial()
   if err != nil {
     return nil, err
   }
@@ -28,31 +34,30 @@ func (t *transport) Listen(laddr ma.Multiaddr) (xnet.Listener, error) {
   // Send a listen request, wait for a listen response
   resl, err := xrpc.ListenReq(s, t.id, laddr)
   if err != nil {
+    s.Close()
     return nil, err
   }
 
-  return newListener(t.client, s, resl)
-}
-
-func (t *transport) Dial(raddr ma.Multiaddr) (xnet.Conn, error) {
-  // open a new data stream
-  s, err := t.client.Conn.Dial()
+  l, err := newListener(t.client, s, resl)
   if err != nil {
+    s.Close()
     return nil, err
   }
+  return l, nil
+}
 
-  // Send a dial request, wait for the dial response
-  res, err := xrpc.DialReq(s, t.id, raddr)
-  if err != nil {
-    return nil, err
-  }
+func (t *transport) Dial(raddr ma.Multiaddr) (xnet.Conn, error) {
+  return t.DialContext(context.Background(), raddr)
+}
 
-  return newConn(t.client, s, res.Conn)
+// DialContext is Dial, aborted when ctx is done.
+func (t *transport) DialContext(ctx context.Context, raddr ma.Multiaddr) (xnet.Conn, error) {
+  return dialConn(ctx, t.client, t.id, raddr)
 }
 
 func (t *transport) Dialer(laddr ma.Multiaddr) (xnet.Dialer, error) {
   // open a new control stream
-  s, err := t.client.Conn.Dial()
+  s, err := t.client.dial()
   if err != nil {
     return nil, err
   }
@@ -60,10 +65,16 @@ func (t *transport) Dialer(laddr ma.Multiaddr) (xnet.Dialer, error) {
   // Send a dialer request, wait for the dialer response
   resd, err := xrpc.DialerReq(s, t.id, laddr)
   if err != nil {
+    s.Close()
     return nil, err
   }
 
-  return newDialer(t.client, s, resd)
+  d, err := newDialer(t.client, s, resd)
+  if err != nil {
+    s.Close()
+    return nil, err
+  }
+  return d, nil
 }
 
 func (t *transport) Close() error {
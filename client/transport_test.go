@@ -0,0 +1,41 @@
+package xtpclient_test
+
+import (
+  "testing"
+
+  ximpls "github.com/libp2p/go-xtp-ctl/impls"
+  xnet "github.com/libp2p/go-xtp-ctl/net"
+  "github.com/libp2p/go-xtp-ctl/net/xnettest"
+  "github.com/libp2p/go-xtp-ctl/xtptest"
+  ma "github.com/multiformats/go-multiaddr"
+)
+
+// TestTransports runs the conformance suite against the client's proxy
+// transports, with the server reached over tcp and over memory.
+func TestTransports(t *testing.T) {
+  harnesses := []struct {
+    name string
+    new  func(testing.TB, ...xnet.Transport) *xtptest.Harness
+  }{
+    {"tcp", xtptest.New},
+    {"memory", xtptest.NewMemory},
+  }
+  xports := []struct {
+    code  string
+    laddr ma.Multiaddr
+  }{
+    {"/tcp", ma.StringCast("/ip4/127.0.0.1/tcp/0")},
+    {"/memory", ma.StringCast("/memory/xnettest-client")},
+  }
+
+  for _, hn := range harnesses {
+    for _, x := range xports {
+      hn, x := hn, x
+      t.Run(hn.name+x.code, func(t *testing.T) {
+        h := hn.new(t, &ximpls.TCPTransport{}, &ximpls.MemoryTransport{})
+        f := func() xnet.Transport { return h.Client.Transport(x.code) }
+        xnettest.SubtestAll(t, f, x.laddr)
+      })
+    }
+  }
+}
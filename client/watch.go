@@ -0,0 +1,41 @@
+package xtpclient
+
+import (
+  pb "github.com/libp2p/go-xtp-ctl/pb"
+  xnet "github.com/libp2p/go-xtp-ctl/net"
+  xrpc "github.com/libp2p/go-xtp-ctl/rpc"
+)
+
+// Watch is a stream of lifecycle events of the server's descriptors:
+// listeners and dialers opened, conns and streams accepted or dialed,
+// anything closed, and failed accepts and dials.
+type Watch struct {
+  s xnet.Stream
+}
+
+// Watch starts watching the descriptors of the given types, on the
+// transports with ids tids. No types (or tids) means all of them.
+// Events that happen after Watch returns are reported by Next.
+func (c *Client) Watch(types []pb.TType, tids ...int64) (*Watch, error) {
+  s, err := c.dial()
+  if err != nil {
+    return nil, err
+  }
+  if err := xrpc.WatchReq(s, types, tids); err != nil {
+    s.Close()
+    return nil, err
+  }
+  return &Watch{s}, nil
+}
+
+// Next blocks until the next event. The event's Item is encoded like a
+// ListRes item. Next fails once the watch is closed, or if it falls too
+// far behind the server.
+func (w *Watch) Next() (*pb.WatchRes, error) {
+  return xrpc.NextWatchRes(w.s)
+}
+
+// Close stops the watch.
+func (w *Watch) Close() error {
+  return w.s.Close()
+}
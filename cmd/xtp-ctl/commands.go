@@ -0,0 +1,293 @@
+package main
+
+import (
+  "errors"
+  "fmt"
+  "os"
+  "os/signal"
+  "strconv"
+  "strings"
+  "syscall"
+  "time"
+
+  ma "github.com/multiformats/go-multiaddr"
+  xclient "github.com/libp2p/go-xtp-ctl/client"
+  xrpc "github.com/libp2p/go-xtp-ctl/rpc"
+  pb "github.com/libp2p/go-xtp-ctl/pb"
+)
+
+var ttypes = map[string]pb.TType{
+  "transport": pb.TType_TTypeTransport,
+  "listener":  pb.TType_TTypeListener,
+  "dialer":    pb.TType_TTypeDialer,
+  "conn":      pb.TType_TTypeConn,
+  "stream":    pb.TType_TTypeStream,
+}
+
+var allTTypes = []pb.TType{
+  pb.TType_TTypeTransport,
+  pb.TType_TTypeListener,
+  pb.TType_TTypeDialer,
+  pb.TType_TTypeConn,
+  pb.TType_TTypeStream,
+}
+
+// parseTypes parses <type>... args. No args means all types.
+func parseTypes(args []string) ([]pb.TType, error) {
+  if len(args) == 0 {
+    return allTTypes, nil
+  }
+  var types []pb.TType
+  for _, a := range args {
+    t, ok := ttypes[a]
+    if !ok {
+      return nil, fmt.Errorf("unknown type: %s", a)
+    }
+    types = append(types, t)
+  }
+  return types, nil
+}
+
+func cmdList(c *xclient.Client, args []string) error {
+  types, err := parseTypes(args)
+  if err != nil {
+    return err
+  }
+
+  s, err := c.Stream()
+  if err != nil {
+    return err
+  }
+  defer s.Close()
+
+  items, err := xrpc.ListReq(s, types)
+  if err != nil {
+    return err
+  }
+
+  var ds []*descriptor
+  for _, i := range items {
+    d, err := newDescriptor(i)
+    if err != nil {
+      return err
+    }
+    ds = append(ds, d)
+  }
+  return printDescriptors(ds)
+}
+
+func cmdClose(c *xclient.Client, args []string) error {
+  if len(args) != 1 {
+    return errors.New("usage: close <id>")
+  }
+  id, err := strconv.ParseInt(args[0], 10, 64)
+  if err != nil {
+    return fmt.Errorf("invalid id: %s", args[0])
+  }
+
+  s, err := c.Stream()
+  if err != nil {
+    return err
+  }
+  defer s.Close()
+
+  return xrpc.CloseReq(s, id)
+}
+
+func cmdListen(c *xclient.Client, args []string) error {
+  tid, addr, err := transportAndAddr(c, args)
+  if err != nil {
+    return err
+  }
+
+  s, err := c.Stream()
+  if err != nil {
+    return err
+  }
+  defer s.Close()
+
+  l, err := xrpc.ListenReq(s, tid, addr)
+  if err != nil {
+    return err
+  }
+
+  d, err := descriptorOf(*l.Id, pb.TType_TTypeListener, l)
+  if err != nil {
+    return err
+  }
+  if err := printDescriptors([]*descriptor{d}); err != nil {
+    return err
+  }
+
+  waitForInterrupt()
+  return xrpc.CloseReq(s, *l.Id)
+}
+
+func cmdDial(c *xclient.Client, args []string) error {
+  tid, addr, err := transportAndAddr(c, args)
+  if err != nil {
+    return err
+  }
+
+  s, err := c.Stream()
+  if err != nil {
+    return err
+  }
+  defer s.Close()
+
+  res, err := xrpc.DialReq(s, tid, addr)
+  if err != nil {
+    return err
+  }
+  if !res.Conn.Valid() {
+    return xrpc.ErrInvalidMessage
+  }
+
+  d, err := descriptorOf(*res.Conn.Id, pb.TType_TTypeConn, res.Conn)
+  if err != nil {
+    return err
+  }
+  if err := printDescriptors([]*descriptor{d}); err != nil {
+    return err
+  }
+
+  waitForInterrupt()
+  return xrpc.CloseReq(s, *res.Conn.Id)
+}
+
+func cmdStats(c *xclient.Client, args []string) error {
+  var ids []int64
+  for _, a := range args {
+    id, err := strconv.ParseInt(a, 10, 64)
+    if err != nil {
+      return fmt.Errorf("invalid id: %s", a)
+    }
+    ids = append(ids, id)
+  }
+
+  ss, err := c.Stats(ids...)
+  if err != nil {
+    return err
+  }
+  return printStats(ss)
+}
+
+func cmdUsage(c *xclient.Client, args []string) error {
+  res, err := c.Usage()
+  if err != nil {
+    return err
+  }
+  return printUsage(res)
+}
+
+func cmdWatch(c *xclient.Client, args []string) error {
+  types, err := parseTypes(args)
+  if err != nil {
+    return err
+  }
+
+  w, err := c.Watch(types)
+  if err != nil {
+    return err
+  }
+  defer w.Close()
+
+  go func() {
+    waitForInterrupt()
+    w.Close()
+  }()
+
+  for {
+    res, err := w.Next()
+    if err != nil {
+      if xrpc.ErrCode(err) == pb.ErrCode_ErrCodeClosed {
+        return nil // interrupted.
+      }
+      return err
+    }
+    if err := printEvent(res); err != nil {
+      return err
+    }
+  }
+}
+
+func cmdNoOp(c *xclient.Client, args []string) error {
+  s, err := c.Stream()
+  if err != nil {
+    return err
+  }
+  defer s.Close()
+
+  start := time.Now()
+  if err := xrpc.NoOpReq(s); err != nil {
+    return err
+  }
+  rtt := time.Since(start)
+
+  if jsonOut {
+    return printJSON(map[string]string{"rtt": rtt.String()})
+  }
+  fmt.Printf("noop: %s\n", rtt)
+  return nil
+}
+
+func cmdVersion(c *xclient.Client, args []string) error {
+  h := c.Hello
+  if jsonOut {
+    return printJSON(h)
+  }
+
+  var rpcs []string
+  for _, t := range h.GetRpcs() {
+    rpcs = append(rpcs, t.String())
+  }
+  fmt.Printf("impl:     %s\n", h.GetImpl())
+  fmt.Printf("version:  %d\n", h.GetVersion())
+  fmt.Printf("max msg:  %d\n", h.GetMaxMessageSize())
+  fmt.Printf("rpcs:     %s\n", strings.Join(rpcs, " "))
+  fmt.Printf("features: %s\n", strings.Join(h.GetFeatures(), " "))
+  if c.Identity != "" {
+    fmt.Printf("identity: %s\n", c.Identity)
+  }
+  return nil
+}
+
+// transportAndAddr parses <transport> <multiaddr> args, and looks up the
+// transport's id on the server.
+func transportAndAddr(c *xclient.Client, args []string) (int64, ma.Multiaddr, error) {
+  if len(args) != 2 {
+    return 0, nil, errors.New("expected <transport> <multiaddr>")
+  }
+  addr, err := ma.NewMultiaddr(args[1])
+  if err != nil {
+    return 0, nil, fmt.Errorf("invalid multiaddr: %s", err)
+  }
+
+  tid, err := transportId(c, args[0])
+  if err != nil {
+    return 0, nil, err
+  }
+  return tid, addr, nil
+}
+
+// transportId returns the id of the server transport with the given code.
+func transportId(c *xclient.Client, code string) (int64, error) {
+  t := c.Transport(code)
+  if t == nil {
+    t = c.Transport("/" + code)
+  }
+  if t == nil {
+    return 0, fmt.Errorf("server has no transport %s", code)
+  }
+  return t.(xclient.Descriptor).Id(), nil
+}
+
+func waitForInterrupt() {
+  if !jsonOut {
+    fmt.Fprintln(os.Stderr, "holding it open. interrupt to close.")
+  }
+  sigs := make(chan os.Signal, 1)
+  signal.Notify(sigs, syscall.SIGINT, syscall.SIGTERM)
+  <-sigs
+  signal.Stop(sigs)
+}
@@ -0,0 +1,178 @@
+package main
+
+import (
+  "errors"
+  "fmt"
+  "io"
+  "log"
+  "os"
+
+  ma "github.com/multiformats/go-multiaddr"
+  manet "github.com/multiformats/go-multiaddr-net"
+  xclient "github.com/libp2p/go-xtp-ctl/client"
+  xnet "github.com/libp2p/go-xtp-ctl/net"
+)
+
+// data commands: these move bytes through streams of the server's
+// transports, like netcat and socat do.
+
+func cmdNc(c *xclient.Client, args []string) error {
+  if len(args) != 1 {
+    return errors.New("usage: nc <multiaddr>")
+  }
+  raddr, t, err := addrAndTransport(c, args[0])
+  if err != nil {
+    return err
+  }
+
+  s, err := dialStream(t, raddr)
+  if err != nil {
+    return err
+  }
+  defer s.Conn().Close()
+  defer s.Close()
+
+  return pipeStdio(s)
+}
+
+func cmdServe(c *xclient.Client, args []string) error {
+  if len(args) != 1 {
+    return errors.New("usage: serve <multiaddr>")
+  }
+  laddr, t, err := addrAndTransport(c, args[0])
+  if err != nil {
+    return err
+  }
+
+  l, err := t.Listen(laddr)
+  if err != nil {
+    return err
+  }
+  defer l.Close()
+  fmt.Fprintf(os.Stderr, "listening on %s\n", l.Multiaddr())
+
+  cn, err := l.Accept()
+  if err != nil {
+    return err
+  }
+  defer cn.Close()
+
+  s, err := cn.Accept()
+  if err != nil {
+    return err
+  }
+  defer s.Close()
+
+  return pipeStdio(s)
+}
+
+func cmdForward(c *xclient.Client, args []string) error {
+  if len(args) != 2 {
+    return errors.New("usage: forward <local multiaddr> <remote multiaddr>")
+  }
+  laddr, err := ma.NewMultiaddr(args[0])
+  if err != nil {
+    return fmt.Errorf("invalid multiaddr: %s", err)
+  }
+  raddr, t, err := addrAndTransport(c, args[1])
+  if err != nil {
+    return err
+  }
+
+  l, err := manet.Listen(laddr)
+  if err != nil {
+    return err
+  }
+  defer l.Close()
+  fmt.Fprintf(os.Stderr, "forwarding %s to %s\n", l.Multiaddr(), raddr)
+
+  for {
+    lc, err := l.Accept()
+    if err != nil {
+      return err
+    }
+
+    go func() {
+      defer lc.Close()
+
+      s, err := dialStream(t, raddr)
+      if err != nil {
+        log.Printf("forward %s: %s", lc.RemoteMultiaddr(), err)
+        return
+      }
+      defer s.Conn().Close()
+      defer s.Close()
+
+      pipe(lc, s)
+    }()
+  }
+}
+
+// dialStream dials raddr with t, and opens a stream on the new conn.
+func dialStream(t xnet.Transport, raddr ma.Multiaddr) (xnet.Stream, error) {
+  cn, err := t.Dial(raddr)
+  if err != nil {
+    return nil, err
+  }
+
+  s, err := cn.Dial()
+  if err != nil {
+    cn.Close()
+    return nil, err
+  }
+  return s, nil
+}
+
+// addrAndTransport parses a multiaddr, and picks the client transport
+// able to handle it.
+func addrAndTransport(c *xclient.Client, addr string) (ma.Multiaddr, xnet.Transport, error) {
+  a, err := ma.NewMultiaddr(addr)
+  if err != nil {
+    return nil, nil, fmt.Errorf("invalid multiaddr: %s", err)
+  }
+
+  t, err := c.TransportFor(a)
+  if err != nil {
+    return nil, nil, err
+  }
+  return a, t, nil
+}
+
+// pipeStdio copies stdin to s, and s to stdout. The end of stdin is
+// passed on as a half-close of s. It returns once s has no more data for
+// us.
+func pipeStdio(s xnet.Stream) error {
+  go func() {
+    io.Copy(s, os.Stdin)
+    xnet.CloseWrite(s)
+  }()
+
+  _, err := io.Copy(os.Stdout, s)
+  return err
+}
+
+// pipe copies data both ways between a and b, passing the end of each
+// direction on as a half-close, until both are done. If either fails,
+// both are closed.
+func pipe(a, b io.ReadWriteCloser) {
+  done := make(chan struct{}, 2)
+  cp := func(dst, src io.ReadWriteCloser) {
+    if _, err := io.Copy(dst, src); err != nil || closeWrite(dst) != nil {
+      a.Close()
+      b.Close()
+    }
+    done <- struct{}{}
+  }
+  go cp(a, b)
+  go cp(b, a)
+  <-done
+  <-done
+}
+
+// closeWrite shuts down the writing side of c, if it can.
+func closeWrite(c io.Closer) error {
+  if hc, ok := c.(interface{ CloseWrite() error }); ok {
+    return hc.CloseWrite()
+  }
+  return xnet.ErrNoHalfClose
+}
@@ -0,0 +1,57 @@
+package main
+
+import (
+  "fmt"
+  "io"
+  "net"
+  "testing"
+)
+
+// tcpPair returns both ends of a loopback tcp conn.
+func tcpPair(t *testing.T) (*net.TCPConn, *net.TCPConn) {
+  l, err := net.Listen("tcp", "127.0.0.1:0")
+  if err != nil {
+    t.Fatal(err)
+  }
+  defer l.Close()
+
+  c1, err := net.Dial("tcp", l.Addr().String())
+  if err != nil {
+    t.Fatal(err)
+  }
+  c2, err := l.Accept()
+  if err != nil {
+    t.Fatal(err)
+  }
+  t.Cleanup(func() { c1.Close(); c2.Close() })
+  return c1.(*net.TCPConn), c2.(*net.TCPConn)
+}
+
+// TestPipeHalfClose checks that pipe passes the end of a request on, so
+// a server that answers once it read it all can, and that the answer
+// gets back in full.
+func TestPipeHalfClose(t *testing.T) {
+  user, a := tcpPair(t)
+  b, srv := tcpPair(t)
+  go pipe(a, b)
+
+  go func() {
+    req, _ := io.ReadAll(srv)
+    fmt.Fprintf(srv, "got %q", req)
+    srv.CloseWrite()
+  }()
+
+  if _, err := io.WriteString(user, "hello"); err != nil {
+    t.Fatal(err)
+  }
+  if err := user.CloseWrite(); err != nil {
+    t.Fatal(err)
+  }
+  res, err := io.ReadAll(user)
+  if err != nil {
+    t.Fatal(err)
+  }
+  if string(res) != `got "hello"` {
+    t.Fatalf("response: %q", res)
+  }
+}
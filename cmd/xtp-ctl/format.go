@@ -0,0 +1,263 @@
+package main
+
+import (
+  "encoding/json"
+  "fmt"
+  "os"
+  "sort"
+  "strconv"
+  "strings"
+  "text/tabwriter"
+  "time"
+
+  ma "github.com/multiformats/go-multiaddr"
+  proto "github.com/gogo/protobuf/proto"
+  pb "github.com/libp2p/go-xtp-ctl/pb"
+)
+
+// descriptor is the printable form of a ListRes_Item.
+type descriptor struct {
+  Id              int64  `json:"id"`
+  Type            string `json:"type"`
+  Transport       string `json:"transport,omitempty"`
+  TransportId     int64  `json:"transportId,omitempty"`
+  ConnId          int64  `json:"connId,omitempty"`
+  Multiaddr       string `json:"multiaddr,omitempty"`
+  LocalMultiaddr  string `json:"localMultiaddr,omitempty"`
+  RemoteMultiaddr string `json:"remoteMultiaddr,omitempty"`
+}
+
+func newDescriptor(i *pb.ListRes_Item) (*descriptor, error) {
+  var m proto.Message
+  switch *i.Type {
+  case pb.TType_TTypeTransport:
+    m = &pb.Transport{}
+  case pb.TType_TTypeListener:
+    m = &pb.Listener{}
+  case pb.TType_TTypeDialer:
+    m = &pb.Dialer{}
+  case pb.TType_TTypeConn:
+    m = &pb.Conn{}
+  case pb.TType_TTypeStream:
+    m = &pb.Stream{}
+  default:
+    return &descriptor{Id: *i.Id, Type: typeName(*i.Type)}, nil
+  }
+
+  if err := proto.Unmarshal(i.Value, m); err != nil {
+    return nil, err
+  }
+  return descriptorOf(*i.Id, *i.Type, m)
+}
+
+func descriptorOf(id int64, typ pb.TType, m proto.Message) (*descriptor, error) {
+  d := &descriptor{Id: id, Type: typeName(typ)}
+  switch m := m.(type) {
+  case *pb.Transport:
+    d.Transport = m.GetTransport()
+  case *pb.Listener:
+    d.TransportId = m.GetTransportId()
+    d.Multiaddr = maString(m.Multiaddr)
+  case *pb.Dialer:
+    d.TransportId = m.GetTransportId()
+    d.Multiaddr = maString(m.Multiaddr)
+  case *pb.Conn:
+    d.TransportId = m.GetTransportId()
+    d.LocalMultiaddr = maString(m.LocalMultiaddr)
+    d.RemoteMultiaddr = maString(m.RemoteMultiaddr)
+  case *pb.Stream:
+    d.TransportId = m.GetTransportId()
+    d.ConnId = m.GetConnId()
+    d.LocalMultiaddr = maString(m.LocalMultiaddr)
+    d.RemoteMultiaddr = maString(m.RemoteMultiaddr)
+  default:
+    return nil, fmt.Errorf("unknown descriptor message: %T", m)
+  }
+  return d, nil
+}
+
+func printDescriptors(ds []*descriptor) error {
+  if jsonOut {
+    if ds == nil {
+      ds = []*descriptor{} // print [], not null
+    }
+    return printJSON(ds)
+  }
+
+  w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+  fmt.Fprintln(w, "ID\tTYPE\tTRANSPORT\tCONN\tADDRS")
+  for _, d := range ds {
+    fmt.Fprintf(w, "%d\t%s\t%s\t%s\t%s\n", d.Id, d.Type, d.transportCol(), idCol(d.ConnId), d.addrsCol())
+  }
+  return w.Flush()
+}
+
+// event is the printable form of a WatchRes.
+type event struct {
+  Event      string      `json:"event"`
+  Descriptor *descriptor `json:"descriptor"`
+  Error      string      `json:"error,omitempty"`
+}
+
+// printEvent prints one watch event, as a line of json or text.
+func printEvent(res *pb.WatchRes) error {
+  d, err := newDescriptor(res.Item)
+  if err != nil {
+    return err
+  }
+  e := &event{Event: strings.ToLower(res.GetEvent().String()), Descriptor: d, Error: res.GetError()}
+  if jsonOut {
+    return json.NewEncoder(os.Stdout).Encode(e)
+  }
+
+  fmt.Printf("%-8s %-4d %-9s %-9s %-4s %s", e.Event, d.Id, d.Type, d.transportCol(), idCol(d.ConnId), d.addrsCol())
+  if e.Error != "" {
+    fmt.Printf(" error: %s", e.Error)
+  }
+  fmt.Println()
+  return nil
+}
+
+// stats is the printable form of a pb.Stats.
+type stats struct {
+  Id           int64      `json:"id"`
+  BytesIn      int64      `json:"bytesIn"`
+  BytesOut     int64      `json:"bytesOut"`
+  Conns        int64      `json:"conns"`
+  Streams      int64      `json:"streams"`
+  Errors       int64      `json:"errors"`
+  OpenedAt     time.Time  `json:"openedAt"`
+  LastActivity *time.Time `json:"lastActivity,omitempty"`
+}
+
+func printStats(ss []*pb.Stats) error {
+  var rows []*stats
+  for _, s := range ss {
+    r := &stats{
+      Id:       s.GetId(),
+      BytesIn:  s.GetBytesIn(),
+      BytesOut: s.GetBytesOut(),
+      Conns:    s.GetConns(),
+      Streams:  s.GetStreams(),
+      Errors:   s.GetErrors(),
+      OpenedAt: time.Unix(0, s.GetOpenedAt()),
+    }
+    if t := s.GetLastActivity(); t != 0 {
+      last := time.Unix(0, t)
+      r.LastActivity = &last
+    }
+    rows = append(rows, r)
+  }
+  sort.Slice(rows, func(i, j int) bool { return rows[i].Id < rows[j].Id })
+
+  if jsonOut {
+    if rows == nil {
+      rows = []*stats{} // print [], not null
+    }
+    return printJSON(rows)
+  }
+
+  now := time.Now()
+  w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+  fmt.Fprintln(w, "ID\tIN\tOUT\tCONNS\tSTREAMS\tERRORS\tAGE\tIDLE")
+  for _, r := range rows {
+    idle := "-"
+    if r.LastActivity != nil {
+      idle = now.Sub(*r.LastActivity).Round(time.Second).String()
+    }
+    fmt.Fprintf(w, "%d\t%d\t%d\t%d\t%d\t%d\t%s\t%s\n", r.Id, r.BytesIn, r.BytesOut,
+      r.Conns, r.Streams, r.Errors, now.Sub(r.OpenedAt).Round(time.Second), idle)
+  }
+  return w.Flush()
+}
+
+func (d *descriptor) transportCol() string {
+  if d.Transport != "" {
+    return d.Transport
+  }
+  return idCol(d.TransportId)
+}
+
+func (d *descriptor) addrsCol() string {
+  if d.Multiaddr != "" {
+    return d.Multiaddr
+  }
+  if d.LocalMultiaddr == "" && d.RemoteMultiaddr == "" {
+    return "-"
+  }
+  return d.LocalMultiaddr + " <-> " + d.RemoteMultiaddr
+}
+
+func idCol(id int64) string {
+  if id == 0 {
+    return "-"
+  }
+  return fmt.Sprint(id)
+}
+
+func typeName(t pb.TType) string {
+  return strings.ToLower(strings.TrimPrefix(t.String(), "TType"))
+}
+
+func maString(b []byte) string {
+  if b == nil {
+    return ""
+  }
+  a, err := ma.NewMultiaddrBytes(b)
+  if err != nil {
+    return fmt.Sprintf("<invalid: %x>", b)
+  }
+  return a.String()
+}
+
+func printJSON(v interface{}) error {
+  enc := json.NewEncoder(os.Stdout)
+  enc.SetIndent("", "  ")
+  return enc.Encode(v)
+}
+
+// usageRow is one resource of a UsageRes, for printing.
+type usageRow struct {
+  Resource    string `json:"resource"`
+  Used        int64  `json:"used"`
+  Limit       int64  `json:"limit"`
+  ServerUsed  int64  `json:"serverUsed"`
+  ServerLimit int64  `json:"serverLimit"`
+}
+
+func printUsage(res *pb.UsageRes) error {
+  u, l := res.GetUsage(), res.GetLimits()
+  su, sl := res.GetServerUsage(), res.GetServerLimits()
+  rows := []*usageRow{
+    {"listeners", u.GetListeners(), l.GetListeners(), su.GetListeners(), sl.GetListeners()},
+    {"dialers", u.GetDialers(), l.GetDialers(), su.GetDialers(), sl.GetDialers()},
+    {"conns", u.GetConns(), l.GetConns(), su.GetConns(), sl.GetConns()},
+    {"streams", u.GetStreams(), l.GetStreams(), su.GetStreams(), sl.GetStreams()},
+    {"pendingAccepts", u.GetPendingAccepts(), l.GetPendingAccepts(), su.GetPendingAccepts(), sl.GetPendingAccepts()},
+    {"inFlight", u.GetInFlight(), l.GetInFlight(), su.GetInFlight(), sl.GetInFlight()},
+    {"bytes", u.GetBytes(), 0, su.GetBytes(), 0},
+    {"bandwidth", 0, l.GetBandwidth(), 0, sl.GetBandwidth()},
+  }
+
+  if jsonOut {
+    return printJSON(rows)
+  }
+
+  // limits of 0 are none.
+  limit := func(n int64) string {
+    if n == 0 {
+      return "-"
+    }
+    return strconv.FormatInt(n, 10)
+  }
+  w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+  fmt.Fprintln(w, "RESOURCE\tUSED\tLIMIT\tSERVER\tSERVER LIMIT")
+  for _, r := range rows {
+    if r.Resource == "bandwidth" {
+      fmt.Fprintf(w, "%s\t-\t%s\t-\t%s\n", r.Resource, limit(r.Limit), limit(r.ServerLimit))
+      continue
+    }
+    fmt.Fprintf(w, "%s\t%d\t%s\t%d\t%s\n", r.Resource, r.Used, limit(r.Limit), r.ServerUsed, limit(r.ServerLimit))
+  }
+  return w.Flush()
+}
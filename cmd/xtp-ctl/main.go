@@ -0,0 +1,166 @@
+// xtp-ctl is a command line client for xtp-ctl servers (see xtpd).
+// It inspects and drives the descriptors the server holds for it. Each
+// run is a session of its own: descriptors belong to the session that
+// opened them, so it does not see (nor close) other clients' ones.
+//
+//   xtp-ctl -server /ip4/127.0.0.1/tcp/4040 list
+//
+package main
+
+import (
+  "crypto/ed25519"
+  "crypto/x509"
+  "encoding/pem"
+  "errors"
+  "flag"
+  "fmt"
+  "os"
+  "strings"
+
+  ma "github.com/multiformats/go-multiaddr"
+  xclient "github.com/libp2p/go-xtp-ctl/client"
+  xnet "github.com/libp2p/go-xtp-ctl/net"
+)
+
+const defaultServer = "/ip4/127.0.0.1/tcp/4040"
+
+type command struct {
+  name  string
+  args  string
+  help  string
+  run   func(c *xclient.Client, args []string) error
+}
+
+var commands = []command{
+  {"list", "[<type>...]", "list this session's descriptors (transport, listener, dialer, conn, stream)", cmdList},
+  {"close", "<id>", "close a descriptor of this session", cmdClose},
+  {"stats", "[<id>...]", "show traffic stats of this session's descriptors (all, if no ids)", cmdStats},
+  {"usage", "", "show what this client, and all clients, use on the server, and the limits", cmdUsage},
+  {"watch", "[<type>...]", "print descriptor events as they happen, until interrupted", cmdWatch},
+  {"listen", "<transport> <multiaddr>", "open a listener, and hold it until interrupted", cmdListen},
+  {"dial", "<transport> <multiaddr>", "dial a conn, and hold it until interrupted", cmdDial},
+  {"noop", "", "send a NoOp rpc, and report the round trip time", cmdNoOp},
+  {"version", "", "show the server's protocol version and features", cmdVersion},
+  {"nc", "<multiaddr>", "dial a stream, and pipe it to stdin/stdout", cmdNc},
+  {"serve", "<multiaddr>", "listen, accept a stream, and pipe it to stdin/stdout", cmdServe},
+  {"forward", "<local> <remote>", "forward local (tcp) conns to streams dialed to remote", cmdForward},
+}
+
+var jsonOut bool
+
+func main() {
+  server := flag.String("server", defaultServer, "multiaddr of the xtp-ctl server")
+  flag.BoolVar(&jsonOut, "json", false, "output json instead of tables")
+  useTLS := flag.Bool("tls", false, "connect with TLS (implied by the other -tls flags)")
+  var tf xnet.TLSFiles
+  flag.StringVar(&tf.Cert, "tls-cert", "", "PEM client certificate, for mutual TLS")
+  flag.StringVar(&tf.Key, "tls-key", "", "PEM private key of -tls-cert")
+  flag.StringVar(&tf.CA, "tls-ca", "", "PEM CA certificates to verify the server with (default system roots)")
+  flag.StringVar(&tf.ServerName, "tls-name", "", "name to verify the server's certificate against (default its address)")
+  pins := flag.String("tls-pin", "", "comma separated base64 SHA-256 pins of the server keys to accept")
+  var cred xclient.Credentials
+  flag.StringVar(&cred.Token, "token", "", "token to authenticate with (default $XTPCTL_TOKEN)")
+  keyPath := flag.String("key", "", "PEM ed25519 private key to authenticate with")
+  flag.Usage = usage
+  flag.Parse()
+
+  // read after Parse, so -h does not show the secret as the default.
+  if cred.Token == "" {
+    cred.Token = os.Getenv("XTPCTL_TOKEN")
+  }
+
+  if flag.NArg() < 1 {
+    usage()
+    os.Exit(2)
+  }
+
+  cmd := findCommand(flag.Arg(0))
+  if cmd == nil {
+    fmt.Fprintf(os.Stderr, "xtp-ctl: unknown command: %s\n", flag.Arg(0))
+    usage()
+    os.Exit(2)
+  }
+
+  if *pins != "" {
+    tf.Pins = strings.Split(*pins, ",")
+  }
+  sec, err := tf.Security(false)
+  if err != nil {
+    fmt.Fprintf(os.Stderr, "xtp-ctl: %s\n", err)
+    os.Exit(2)
+  }
+  if sec == nil && *useTLS {
+    sec = &xnet.Security{}
+  }
+  if *keyPath != "" {
+    if cred.Key, err = readKey(*keyPath); err != nil {
+      fmt.Fprintf(os.Stderr, "xtp-ctl: %s\n", err)
+      os.Exit(2)
+    }
+  }
+  var credp *xclient.Credentials
+  if cred.Token != "" || cred.Key != nil {
+    credp = &cred
+  }
+
+  if err := run(*server, sec, credp, cmd, flag.Args()[1:]); err != nil {
+    fmt.Fprintf(os.Stderr, "xtp-ctl %s: %s\n", cmd.name, err)
+    os.Exit(1)
+  }
+}
+
+func run(server string, sec *xnet.Security, cred *xclient.Credentials, cmd *command, args []string) error {
+  saddr, err := ma.NewMultiaddr(server)
+  if err != nil {
+    return fmt.Errorf("invalid server addr: %s", err)
+  }
+
+  c, err := xclient.NewAuthClient(saddr, sec, cred)
+  if err != nil {
+    return err
+  }
+  defer c.Close()
+
+  return cmd.run(c, args)
+}
+
+// readKey reads a PEM (PKCS #8) ed25519 private key, as written by
+// openssl genpkey -algorithm ed25519.
+func readKey(path string) (ed25519.PrivateKey, error) {
+  buf, err := os.ReadFile(path)
+  if err != nil {
+    return nil, err
+  }
+  block, _ := pem.Decode(buf)
+  if block == nil {
+    return nil, fmt.Errorf("%s: no PEM key", path)
+  }
+  k, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+  if err != nil {
+    return nil, fmt.Errorf("%s: %s", path, err)
+  }
+  ek, ok := k.(ed25519.PrivateKey)
+  if !ok {
+    return nil, errors.New(path + ": not an ed25519 key")
+  }
+  return ek, nil
+}
+
+func findCommand(name string) *command {
+  for i := range commands {
+    if commands[i].name == name {
+      return &commands[i]
+    }
+  }
+  return nil
+}
+
+func usage() {
+  fmt.Fprintf(os.Stderr, "usage: xtp-ctl [-server <multiaddr>] [-json] [-tls] [-token <token> | -key <file>] <command> [<args>]\n\ncommands:\n")
+  for _, c := range commands {
+    fmt.Fprintf(os.Stderr, "  %-8s %-28s %s\n", c.name, c.args, c.help)
+  }
+  fmt.Fprintf(os.Stderr, "\neach run is a session of its own: other clients' descriptors are not visible.\n")
+  fmt.Fprintf(os.Stderr, "\nflags:\n")
+  flag.PrintDefaults()
+}
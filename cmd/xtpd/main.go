@@ -0,0 +1,257 @@
+// xtpd runs an xtp-ctl server, offering a set of transports to its clients.
+//
+//   xtpd -listen /ip4/127.0.0.1/tcp/4040 -transports /tcp
+//
+package main
+
+import (
+  "context"
+  "encoding/json"
+  "flag"
+  "fmt"
+  "log"
+  "log/slog"
+  "net"
+  "net/http"
+  "os"
+  "os/signal"
+  "strings"
+  "syscall"
+  "time"
+
+  ma "github.com/multiformats/go-multiaddr"
+  ximpls "github.com/libp2p/go-xtp-ctl/impls"
+  xnet "github.com/libp2p/go-xtp-ctl/net"
+  xserver "github.com/libp2p/go-xtp-ctl/server"
+
+  "go.opentelemetry.io/otel"
+  "go.opentelemetry.io/otel/exporters/stdout/stdouttrace"
+  sdktrace "go.opentelemetry.io/otel/sdk/trace"
+)
+
+const defaultListen = "/ip4/127.0.0.1/tcp/4040"
+
+// Config is the xtpd config file format (json).
+type Config struct {
+  Listen     string        // xtp-ctl multiaddr to listen on
+  Transports []string      // transport codes to offer, e.g. "/tcp"
+  Metrics    string        // host:port to serve Prometheus metrics on, if set
+  LogLevel   string        // debug, info, warn or error
+  Trace      string        // file to write rpc trace spans to (json lines), if set
+  TLS        xnet.TLSFiles // secures the xtp-ctl port, if set
+  Policy     string        // json policy file: who may connect, and do what. see xserver.Policy
+
+  GracePeriod string // how long a disconnected client can resume its session, e.g. "30s"
+
+  Limits       *xserver.Limits // for all clients together, if set
+  ClientLimits *xserver.Limits // for each client, unless the policy gives it some
+}
+
+func main() {
+  log.SetPrefix("xtpd: ")
+
+  cfgPath := flag.String("config", "", "path to a json config file")
+  listen := flag.String("listen", "", "xtp-ctl multiaddr to listen on (default "+defaultListen+")")
+  xports := flag.String("transports", "", "comma separated transport codes to offer (default /tcp)")
+  metrics := flag.String("metrics", "", "host:port to serve Prometheus metrics on, at /metrics")
+  logLevel := flag.String("log-level", "", "debug, info, warn or error (default info). debug logs every rpc")
+  trace := flag.String("trace", "", "file to write rpc trace spans to, as json lines")
+  var tf xnet.TLSFiles
+  flag.StringVar(&tf.Cert, "tls-cert", "", "PEM server certificate. turns TLS on")
+  flag.StringVar(&tf.Key, "tls-key", "", "PEM private key of -tls-cert")
+  flag.StringVar(&tf.CA, "tls-ca", "", "PEM CA certificates to verify clients with (mutual TLS)")
+  pins := flag.String("tls-pin", "", "comma separated base64 SHA-256 pins of the client keys to accept (mutual TLS)")
+  policy := flag.String("policy", "", "json policy file, to authenticate clients and limit what they may do")
+  grace := flag.String("grace", "", "how long to keep the descriptors of a disconnected client, for it to resume its session, e.g. 30s (default 0)")
+  flag.Usage = usage
+  flag.Parse()
+
+  cfg := Config{Listen: defaultListen, Transports: []string{"/tcp"}, LogLevel: "info"}
+  if *cfgPath != "" {
+    if err := readConfig(*cfgPath, &cfg); err != nil {
+      log.Fatal(err)
+    }
+  }
+
+  // flags override the config file
+  if *listen != "" {
+    cfg.Listen = *listen
+  }
+  if *xports != "" {
+    cfg.Transports = strings.Split(*xports, ",")
+  }
+  if *metrics != "" {
+    cfg.Metrics = *metrics
+  }
+  if *logLevel != "" {
+    cfg.LogLevel = *logLevel
+  }
+  if *trace != "" {
+    cfg.Trace = *trace
+  }
+  if tf.Cert != "" {
+    cfg.TLS.Cert, cfg.TLS.Key = tf.Cert, tf.Key
+  }
+  if tf.CA != "" {
+    cfg.TLS.CA = tf.CA
+  }
+  if *pins != "" {
+    cfg.TLS.Pins = strings.Split(*pins, ",")
+  }
+  if *policy != "" {
+    cfg.Policy = *policy
+  }
+  if *grace != "" {
+    cfg.GracePeriod = *grace
+  }
+
+  if err := run(cfg); err != nil {
+    log.Fatal(err)
+  }
+}
+
+func run(cfg Config) error {
+  var level slog.Level
+  if err := level.UnmarshalText([]byte(cfg.LogLevel)); err != nil {
+    return fmt.Errorf("invalid log level: %s", cfg.LogLevel)
+  }
+  logger := slog.New(slog.NewTextHandler(os.Stderr, &slog.HandlerOptions{Level: level}))
+
+  laddr, err := ma.NewMultiaddr(cfg.Listen)
+  if err != nil {
+    return fmt.Errorf("invalid listen addr: %s", err)
+  }
+
+  ts, err := transports(cfg.Transports)
+  if err != nil {
+    return err
+  }
+
+  var grace time.Duration
+  if cfg.GracePeriod != "" {
+    if grace, err = time.ParseDuration(cfg.GracePeriod); err != nil {
+      return fmt.Errorf("invalid grace period: %s", cfg.GracePeriod)
+    }
+  }
+
+  sec, err := cfg.TLS.Security(true)
+  if err != nil {
+    return err
+  }
+
+  s, err := xserver.NewSecureServer(laddr, ts, sec)
+  if err != nil {
+    return err
+  }
+  // until it serves, failing closes the server, and its listener.
+  serving := false
+  defer func() {
+    if !serving {
+      s.Close()
+    }
+  }()
+  s.Logger = logger
+  s.Limits, s.ClientLimits = cfg.Limits, cfg.ClientLimits
+  s.GracePeriod = grace
+
+  if cfg.Policy != "" {
+    if s.Policy, err = xserver.LoadPolicy(cfg.Policy); err != nil {
+      return err
+    }
+  }
+
+  if cfg.Trace != "" {
+    f, err := os.OpenFile(cfg.Trace, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0644)
+    if err != nil {
+      return fmt.Errorf("trace: %s", err)
+    }
+    defer f.Close()
+    exp, err := stdouttrace.New(stdouttrace.WithWriter(f))
+    if err != nil {
+      return fmt.Errorf("trace: %s", err)
+    }
+    tp := sdktrace.NewTracerProvider(sdktrace.WithBatcher(exp))
+    defer tp.Shutdown(context.Background()) // flushes the last spans.
+    otel.SetTracerProvider(tp)
+  }
+
+  if cfg.Metrics != "" {
+    if err := serveMetrics(cfg.Metrics, s, logger); err != nil {
+      return err
+    }
+  }
+
+  serving = true
+  sigs := make(chan os.Signal, 1)
+  signal.Notify(sigs, syscall.SIGINT, syscall.SIGTERM)
+  done := make(chan struct{})
+  go func() {
+    sig := <-sigs
+    logger.Info("shutting down", "signal", sig.String())
+    close(done)
+    s.Close()
+  }()
+
+  logger.Info("listening", "addr", s.Listener.Multiaddr().String(), "transports", cfg.Transports, "tls", sec != nil)
+  err = s.Serve()
+  select {
+  case <-done:
+    return nil // closed on purpose.
+  default:
+    s.Close()
+    return err
+  }
+}
+
+// serveMetrics serves the metrics of s over http, at addr/metrics.
+func serveMetrics(addr string, s *xserver.Server, logger *slog.Logger) error {
+  l, err := net.Listen("tcp", addr)
+  if err != nil {
+    return fmt.Errorf("metrics: %s", err)
+  }
+
+  mux := http.NewServeMux()
+  mux.Handle("/metrics", s.MetricsHandler())
+  go func() {
+    logger.Error("serving metrics", "err", http.Serve(l, mux))
+  }()
+  logger.Info("serving metrics", "url", fmt.Sprintf("http://%s/metrics", l.Addr()))
+  return nil
+}
+
+// transports constructs the transports for the given codes.
+func transports(codes []string) ([]xnet.Transport, error) {
+  var ts []xnet.Transport
+  for _, code := range codes {
+    code = strings.TrimSpace(code)
+    if !strings.HasPrefix(code, "/") {
+      code = "/" + code
+    }
+
+    t := ximpls.NewTransport(code)
+    if t == nil {
+      return nil, fmt.Errorf("unknown transport: %s", code)
+    }
+    ts = append(ts, t)
+  }
+  return ts, nil
+}
+
+func readConfig(path string, cfg *Config) error {
+  f, err := os.Open(path)
+  if err != nil {
+    return err
+  }
+  defer f.Close()
+
+  if err := json.NewDecoder(f).Decode(cfg); err != nil {
+    return fmt.Errorf("invalid config %s: %s", path, err)
+  }
+  return nil
+}
+
+func usage() {
+  fmt.Fprintf(os.Stderr, "usage: xtpd [-config <path>] [-listen <multiaddr>] [-transports <codes>] [-metrics <host:port>] [-log-level <level>] [-trace <file>] [-grace <duration>] [-tls-cert <file> -tls-key <file> [-tls-ca <file>] [-tls-pin <pins>]] [-policy <file>]\n\n")
+  fmt.Fprintf(os.Stderr, "config file (json):\n  {\"Listen\": %q, \"Transports\": [\"/tcp\"], \"Metrics\": \"127.0.0.1:9090\"}\n\n", defaultListen)
+  flag.PrintDefaults()
+}
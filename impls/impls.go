@@ -0,0 +1,23 @@
+// Package xtpimpls includes xnet.Transport implementations, for xtp-ctl
+// servers to offer to their clients.
+package xtpimpls
+
+import (
+  xnet "github.com/libp2p/go-xtp-ctl/net"
+)
+
+// Transports maps transport codes (see xnet.Transport.Code) to
+// constructors for the transports in this package.
+var Transports = map[string]func() xnet.Transport{
+  "/tcp":    func() xnet.Transport { return &TCPTransport{} },
+  "/memory": func() xnet.Transport { return &MemoryTransport{} },
+}
+
+// NewTransport returns a new transport for code, or nil if there is none.
+func NewTransport(code string) xnet.Transport {
+  mk, ok := Transports[code]
+  if !ok {
+    return nil
+  }
+  return mk()
+}
@@ -0,0 +1,20 @@
+package xtpimpls_test
+
+import (
+  "testing"
+
+  ximpls "github.com/libp2p/go-xtp-ctl/impls"
+  xnet "github.com/libp2p/go-xtp-ctl/net"
+  "github.com/libp2p/go-xtp-ctl/net/xnettest"
+  ma "github.com/multiformats/go-multiaddr"
+)
+
+func TestTCPTransport(t *testing.T) {
+  f := func() xnet.Transport { return &ximpls.TCPTransport{} }
+  xnettest.SubtestAll(t, f, ma.StringCast("/ip4/127.0.0.1/tcp/0"))
+}
+
+func TestMemoryTransport(t *testing.T) {
+  f := func() xnet.Transport { return &ximpls.MemoryTransport{} }
+  xnettest.SubtestAll(t, f, ma.StringCast("/memory/xnettest"))
+}
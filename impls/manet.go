@@ -3,6 +3,7 @@ package xtpimpls
 import (
   "errors"
   "sync"
+  "time"
 
   ma "github.com/multiformats/go-multiaddr"
   manet "github.com/multiformats/go-multiaddr-net"
@@ -103,14 +104,28 @@ func (s *singleStream) Conn() xnet.Conn {
 }
 
 func (s *singleStream) Read(buf []byte) (int, error) {
-  return s.C.Read(buf)
+  return s.C.C.Read(buf)
 }
 
 func (s *singleStream) Write(buf []byte) (int, error) {
-  return s.C.Write(buf)
+  return s.C.C.Write(buf)
 }
 
 func (s *singleStream) Close() error {
   return s.C.Close()
 }
 
+func (s *singleStream) SetDeadline(t time.Time) error {
+  return s.C.C.SetDeadline(t)
+}
+
+func (s *singleStream) SetReadDeadline(t time.Time) error {
+  return s.C.C.SetReadDeadline(t)
+}
+
+func (s *singleStream) SetWriteDeadline(t time.Time) error {
+  return s.C.C.SetWriteDeadline(t)
+}
+
+var _ xnet.DeadlineStream = (*singleStream)(nil)
+
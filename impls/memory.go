@@ -0,0 +1,280 @@
+package xtpimpls
+
+import (
+  "context"
+  "fmt"
+  "net"
+  "sync"
+  "syscall"
+
+  ma "github.com/multiformats/go-multiaddr"
+  xnet "github.com/libp2p/go-xtp-ctl/net"
+)
+
+// P_MEMORY is the multiaddr protocol code of /memory/<name> addresses.
+const P_MEMORY = 0x0309
+
+func init() {
+  if ma.ProtocolWithName("memory").Code != 0 {
+    return // already known.
+  }
+  err := ma.AddProtocol(ma.Protocol{
+    Name:       "memory",
+    Code:       P_MEMORY,
+    VCode:      ma.CodeToVarint(P_MEMORY),
+    Size:       ma.LengthPrefixedVarSize,
+    Transcoder: ma.NewTranscoderFromFunctions(memoryStB, memoryBtS, nil),
+  })
+  if err != nil {
+    panic(err)
+  }
+}
+
+func memoryStB(s string) ([]byte, error) {
+  if s == "" {
+    return nil, fmt.Errorf("empty memory address name")
+  }
+  return []byte(s), nil
+}
+
+func memoryBtS(b []byte) (string, error) {
+  return string(b), nil
+}
+
+// memoryBacklog is how many dialed conns wait for a listener's Accept. More
+// are refused.
+const memoryBacklog = 16
+
+// memNames is the namespace of memory listeners, shared by all
+// MemoryTransports in the process.
+var memNames = struct {
+  sync.Mutex
+  listeners map[string]*memListener
+  dials     int
+}{listeners: make(map[string]*memListener)}
+
+// MemoryTransport is an in-process xnet.Transport. Listeners register their
+// /memory/<name> address in a namespace shared by the whole process, and
+// dialed conns are in-memory pipes, layered with a stream muxer (yamux)
+// like TCP conns.
+type MemoryTransport struct {
+  lk        sync.Mutex
+  listeners map[*memListener]struct{}
+}
+
+var _ xnet.TransportContext = (*MemoryTransport)(nil)
+
+func (t *MemoryTransport) Code() string { return "/memory" }
+
+func (t *MemoryTransport) Dial(raddr ma.Multiaddr) (xnet.Conn, error) {
+  return dialMemory(context.Background(), nil, raddr)
+}
+
+func (t *MemoryTransport) DialContext(ctx context.Context, raddr ma.Multiaddr) (xnet.Conn, error) {
+  return dialMemory(ctx, nil, raddr)
+}
+
+func (t *MemoryTransport) Dialer(laddr ma.Multiaddr) (xnet.Dialer, error) {
+  if _, err := memoryName(laddr); err != nil {
+    return nil, err
+  }
+  return &memDialer{laddr}, nil
+}
+
+func (t *MemoryTransport) Listen(laddr ma.Multiaddr) (xnet.Listener, error) {
+  name, err := memoryName(laddr)
+  if err != nil {
+    return nil, err
+  }
+
+  l := &memListener{
+    t:      t,
+    name:   name,
+    laddr:  laddr,
+    conns:  make(chan *memConn, memoryBacklog),
+    closed: make(chan struct{}),
+  }
+
+  memNames.Lock()
+  if _, found := memNames.listeners[name]; found {
+    memNames.Unlock()
+    return nil, fmt.Errorf("listen %s: %w", laddr, syscall.EADDRINUSE)
+  }
+  memNames.listeners[name] = l
+  memNames.Unlock()
+
+  t.lk.Lock()
+  if t.listeners == nil {
+    t.listeners = make(map[*memListener]struct{})
+  }
+  t.listeners[l] = struct{}{}
+  t.lk.Unlock()
+  return l, nil
+}
+
+// Close closes the listeners opened with t.
+func (t *MemoryTransport) Close() error {
+  t.lk.Lock()
+  ls := t.listeners
+  t.listeners = nil
+  t.lk.Unlock()
+
+  for l := range ls {
+    l.Close()
+  }
+  return nil
+}
+
+func (t *MemoryTransport) rmListener(l *memListener) {
+  t.lk.Lock()
+  delete(t.listeners, l)
+  t.lk.Unlock()
+}
+
+type memListener struct {
+  t     *MemoryTransport
+  name  string
+  laddr ma.Multiaddr
+  conns chan *memConn // dialed conns, waiting to be accepted
+
+  lk     sync.Mutex // held to enqueue conns, and to close
+  once   sync.Once
+  closed chan struct{}
+}
+
+// enqueue queues c to be accepted. It fails if the listener is closed,
+// or its backlog full.
+func (l *memListener) enqueue(c *memConn) bool {
+  l.lk.Lock()
+  defer l.lk.Unlock()
+
+  select {
+  case <-l.closed:
+    return false
+  default:
+  }
+  select {
+  case l.conns <- c:
+    return true
+  default:
+    return false
+  }
+}
+
+func (l *memListener) Accept() (xnet.Conn, error) {
+  // a closed listener accepts nothing, even if conns are waiting.
+  select {
+  case <-l.closed:
+    return nil, net.ErrClosed
+  default:
+  }
+
+  select {
+  case c := <-l.conns:
+    return xnet.XtpCtlConn(c, true)
+  case <-l.closed:
+    return nil, net.ErrClosed
+  }
+}
+
+func (l *memListener) Multiaddr() ma.Multiaddr { return l.laddr }
+
+func (l *memListener) Close() error {
+  l.once.Do(func() {
+    memNames.Lock()
+    if memNames.listeners[l.name] == l {
+      delete(memNames.listeners, l.name)
+    }
+    memNames.Unlock()
+    l.t.rmListener(l)
+
+    // refuse the conns no one accepted. no more are enqueued after.
+    l.lk.Lock()
+    defer l.lk.Unlock()
+    close(l.closed)
+    for {
+      select {
+      case c := <-l.conns:
+        c.Close()
+      default:
+        return
+      }
+    }
+  })
+  return nil
+}
+
+type memDialer struct {
+  laddr ma.Multiaddr
+}
+
+func (d *memDialer) Dial(raddr ma.Multiaddr) (xnet.Conn, error) {
+  return dialMemory(context.Background(), d.laddr, raddr)
+}
+
+func (d *memDialer) DialContext(ctx context.Context, raddr ma.Multiaddr) (xnet.Conn, error) {
+  return dialMemory(ctx, d.laddr, raddr)
+}
+
+func (d *memDialer) Multiaddr() ma.Multiaddr { return d.laddr }
+func (d *memDialer) Close() error { return nil }
+
+// dialMemory connects to the listener at raddr with a pipe, and layers a
+// muxer on it. Without laddr, the conn gets a fresh local address.
+func dialMemory(ctx context.Context, laddr, raddr ma.Multiaddr) (xnet.Conn, error) {
+  name, err := memoryName(raddr)
+  if err != nil {
+    return nil, err
+  }
+
+  memNames.Lock()
+  l := memNames.listeners[name]
+  if laddr == nil {
+    memNames.dials++
+    laddr, err = ma.NewMultiaddr(fmt.Sprintf("/memory/dial-%d", memNames.dials))
+  }
+  memNames.Unlock()
+  if err != nil {
+    return nil, err
+  }
+  if l == nil {
+    return nil, fmt.Errorf("dial %s: %w", raddr, syscall.ECONNREFUSED)
+  }
+  if err := ctx.Err(); err != nil {
+    return nil, err
+  }
+
+  p1, p2 := net.Pipe()
+  c1 := &memConn{p1, laddr, raddr}
+  c2 := &memConn{p2, raddr, laddr}
+
+  // like tcp, a closed listener or a full backlog refuses the conn.
+  if !l.enqueue(c2) {
+    p1.Close()
+    p2.Close()
+    return nil, fmt.Errorf("dial %s: %w", raddr, syscall.ECONNREFUSED)
+  }
+  return xnet.XtpCtlConn(c1, false)
+}
+
+// memoryName returns the name in a /memory/<name> multiaddr.
+func memoryName(a ma.Multiaddr) (string, error) {
+  if a == nil {
+    return "", fmt.Errorf("not a memory multiaddr: %v", a)
+  }
+  ps := a.Protocols()
+  if len(ps) != 1 || ps[0].Code != P_MEMORY {
+    return "", fmt.Errorf("not a memory multiaddr: %s", a)
+  }
+  return a.ValueForProtocol(P_MEMORY)
+}
+
+// memConn is one end of an in-memory pipe. It is a manet.Conn.
+type memConn struct {
+  net.Conn
+  laddr ma.Multiaddr
+  raddr ma.Multiaddr
+}
+
+func (c *memConn) LocalMultiaddr() ma.Multiaddr { return c.laddr }
+func (c *memConn) RemoteMultiaddr() ma.Multiaddr { return c.raddr }
@@ -0,0 +1,108 @@
+package xtpimpls_test
+
+import (
+  "errors"
+  "fmt"
+  "sync"
+  "testing"
+  "time"
+
+  ximpls "github.com/libp2p/go-xtp-ctl/impls"
+  xnet "github.com/libp2p/go-xtp-ctl/net"
+  ma "github.com/multiformats/go-multiaddr"
+)
+
+// TestMemoryCloseRace dials a listener while it closes. No dialed conn
+// may be left hanging: each is refused, or closed with the listener.
+func TestMemoryCloseRace(t *testing.T) {
+  addr := ma.StringCast("/memory/close-race")
+  for i := 0; i < 50; i++ {
+    tpt := &ximpls.MemoryTransport{}
+    l, err := tpt.Listen(addr)
+    if err != nil {
+      t.Fatal(err)
+    }
+
+    var wg sync.WaitGroup
+    conns := make(chan xnet.Conn, 8)
+    for j := 0; j < 8; j++ {
+      wg.Add(1)
+      go func() {
+        defer wg.Done()
+        if c, err := tpt.Dial(addr); err == nil {
+          conns <- c
+        }
+      }()
+    }
+    l.Close()
+    wg.Wait()
+    close(conns)
+
+    for c := range conns {
+      if err := deadConn(c); err != nil {
+        t.Fatal(err)
+      }
+      c.Close()
+    }
+  }
+}
+
+// deadConn checks that the peer of c is gone.
+func deadConn(c xnet.Conn) error {
+  s, err := c.Dial()
+  if err != nil {
+    return nil
+  }
+  defer s.Close()
+  xnet.SetReadDeadline(s, time.Now().Add(2*time.Second))
+  _, err = s.Read(make([]byte, 1))
+  if err == nil {
+    return errors.New("read from a conn no one accepted")
+  }
+  if ne, ok := err.(interface{ Timeout() bool }); ok && ne.Timeout() {
+    return fmt.Errorf("conn left hanging: %s", err)
+  }
+  return nil
+}
+
+func TestMemoryBacklog(t *testing.T) {
+  tpt := &ximpls.MemoryTransport{}
+  addr := ma.StringCast("/memory/backlog")
+  l, err := tpt.Listen(addr)
+  if err != nil {
+    t.Fatal(err)
+  }
+  defer l.Close()
+
+  var conns []xnet.Conn
+  defer func() {
+    for _, c := range conns {
+      c.Close()
+    }
+  }()
+  for {
+    c, err := tpt.Dial(addr)
+    if err != nil {
+      break // refused: the backlog is full.
+    }
+    conns = append(conns, c)
+    if len(conns) > 1000 {
+      t.Fatal("backlog never fills up")
+    }
+  }
+  if len(conns) == 0 {
+    t.Fatal("no conn dialed")
+  }
+
+  // accepting one makes room for one.
+  c, err := l.Accept()
+  if err != nil {
+    t.Fatal(err)
+  }
+  c.Close()
+  c2, err := tpt.Dial(addr)
+  if err != nil {
+    t.Fatal("dial after accept:", err)
+  }
+  conns = append(conns, c2)
+}
@@ -1,4 +1,121 @@
 package xtpimpls
 
-type TCPTransport struct {}
+import (
+  "context"
+  "fmt"
+  "net"
 
+  ma "github.com/multiformats/go-multiaddr"
+  manet "github.com/multiformats/go-multiaddr-net"
+  xnet "github.com/libp2p/go-xtp-ctl/net"
+)
+
+// TCPTransport is an xnet.Transport over TCP. Every TCP connection is
+// layered with a stream muxer (yamux), so a Conn carries many Streams.
+type TCPTransport struct{}
+
+var _ xnet.TransportContext = (*TCPTransport)(nil)
+
+func (t *TCPTransport) Code() string { return "/tcp" }
+
+func (t *TCPTransport) Dial(raddr ma.Multiaddr) (xnet.Conn, error) {
+  return dialTCP(context.Background(), nil, raddr)
+}
+
+func (t *TCPTransport) DialContext(ctx context.Context, raddr ma.Multiaddr) (xnet.Conn, error) {
+  return dialTCP(ctx, nil, raddr)
+}
+
+func (t *TCPTransport) Dialer(laddr ma.Multiaddr) (xnet.Dialer, error) {
+  if err := checkTCP(laddr); err != nil {
+    return nil, err
+  }
+  return &tcpDialer{laddr}, nil
+}
+
+func (t *TCPTransport) Listen(laddr ma.Multiaddr) (xnet.Listener, error) {
+  if err := checkTCP(laddr); err != nil {
+    return nil, err
+  }
+  l, err := manet.Listen(laddr)
+  if err != nil {
+    return nil, err
+  }
+  return &tcpListener{l}, nil
+}
+
+func (t *TCPTransport) Close() error {
+  return nil
+}
+
+type tcpListener struct {
+  L manet.Listener
+}
+
+func (l *tcpListener) Accept() (xnet.Conn, error) {
+  c, err := l.L.Accept()
+  if err != nil {
+    return nil, err
+  }
+  return xnet.XtpCtlConn(c, true)
+}
+
+func (l *tcpListener) Multiaddr() ma.Multiaddr { return l.L.Multiaddr() }
+func (l *tcpListener) Close() error { return l.L.Close() }
+
+type tcpDialer struct {
+  laddr ma.Multiaddr
+}
+
+func (d *tcpDialer) Dial(raddr ma.Multiaddr) (xnet.Conn, error) {
+  return dialTCP(context.Background(), d.laddr, raddr)
+}
+
+func (d *tcpDialer) DialContext(ctx context.Context, raddr ma.Multiaddr) (xnet.Conn, error) {
+  return dialTCP(ctx, d.laddr, raddr)
+}
+
+func (d *tcpDialer) Multiaddr() ma.Multiaddr { return d.laddr }
+func (d *tcpDialer) Close() error { return nil }
+
+// dialTCP dials raddr (from laddr, if not nil), and layers a muxer on it.
+func dialTCP(ctx context.Context, laddr, raddr ma.Multiaddr) (xnet.Conn, error) {
+  if err := checkTCP(raddr); err != nil {
+    return nil, err
+  }
+  network, host, err := manet.DialArgs(raddr)
+  if err != nil {
+    return nil, err
+  }
+
+  var d net.Dialer
+  if laddr != nil {
+    if d.LocalAddr, err = manet.ToNetAddr(laddr); err != nil {
+      return nil, err
+    }
+  }
+
+  nc, err := d.DialContext(ctx, network, host)
+  if err != nil {
+    return nil, err
+  }
+  c, err := manet.WrapNetConn(nc)
+  if err != nil {
+    nc.Close()
+    return nil, err
+  }
+  return xnet.XtpCtlConn(c, false)
+}
+
+// checkTCP returns an error if a is not a tcp multiaddr.
+func checkTCP(a ma.Multiaddr) error {
+  if a == nil {
+    return fmt.Errorf("not a tcp multiaddr: %v", a)
+  }
+  for _, p := range a.Protocols() {
+    if p.Code == ma.P_TCP {
+      return nil
+    }
+  }
+  return fmt.Errorf("not a tcp multiaddr: %s", a)
+}
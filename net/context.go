@@ -0,0 +1,278 @@
+package xtpctlnet
+
+import (
+  "context"
+  "io"
+  "net"
+  "sync"
+
+  ma "github.com/multiformats/go-multiaddr"
+)
+
+// The interfaces below add context.Context variants of the blocking
+// methods in net.go. Implementations that can abort their operations
+// should implement them. The *WithContext functions adapt the others.
+
+// TransportContext is a Transport that can dial with a context.
+type TransportContext interface {
+  Transport
+
+  // DialContext is Dial, aborted when ctx is done.
+  DialContext(ctx context.Context, raddr ma.Multiaddr) (Conn, error)
+}
+
+// DialerContext is a Dialer that can dial with a context.
+type DialerContext interface {
+  Dialer
+
+  // DialContext is Dial, aborted when ctx is done.
+  DialContext(ctx context.Context, raddr ma.Multiaddr) (Conn, error)
+}
+
+// ListenerContext is a Listener that can accept with a context.
+type ListenerContext interface {
+  Listener
+
+  // AcceptContext is Accept, aborted when ctx is done.
+  AcceptContext(ctx context.Context) (Conn, error)
+}
+
+// ConnContext is a Conn that can open and accept streams with a context.
+type ConnContext interface {
+  Conn
+
+  // DialContext is Dial, aborted when ctx is done.
+  DialContext(ctx context.Context) (Stream, error)
+
+  // AcceptContext is Accept, aborted when ctx is done.
+  AcceptContext(ctx context.Context) (Stream, error)
+}
+
+// TransportWithContext returns t as a TransportContext. If t does not
+// implement it, DialContext returns when ctx is done, and the conn the
+// pending Dial returns later (if any) is closed.
+func TransportWithContext(t Transport) TransportContext {
+  if tc, ok := t.(TransportContext); ok {
+    return tc
+  }
+  return &ctxTransport{t}
+}
+
+// DialerWithContext returns d as a DialerContext. See TransportWithContext.
+func DialerWithContext(d Dialer) DialerContext {
+  if dc, ok := d.(DialerContext); ok {
+    return dc
+  }
+  return &ctxDialer{d}
+}
+
+// ListenerWithContext returns l as a ListenerContext. If l does not
+// implement it, accepts run one at a time in the background, and a conn
+// accepted after its caller gave up goes to the next caller: none are lost.
+func ListenerWithContext(l Listener) ListenerContext {
+  if lc, ok := l.(ListenerContext); ok {
+    return lc
+  }
+  cl := &ctxListener{Listener: l}
+  cl.a = newAcceptor(func() (io.Closer, error) { return l.Accept() })
+  return cl
+}
+
+// ConnWithContext returns c as a ConnContext. See TransportWithContext
+// and ListenerWithContext.
+func ConnWithContext(c Conn) ConnContext {
+  if cc, ok := c.(ConnContext); ok {
+    return cc
+  }
+  cc := &ctxConn{Conn: c}
+  cc.a = newAcceptor(func() (io.Closer, error) { return c.Accept() })
+  return cc
+}
+
+type ctxTransport struct {
+  Transport
+}
+
+func (t *ctxTransport) DialContext(ctx context.Context, raddr ma.Multiaddr) (Conn, error) {
+  v, err := doContext(ctx, func() (io.Closer, error) { return t.Dial(raddr) })
+  if err != nil {
+    return nil, err
+  }
+  return v.(Conn), nil
+}
+
+type ctxDialer struct {
+  Dialer
+}
+
+func (d *ctxDialer) DialContext(ctx context.Context, raddr ma.Multiaddr) (Conn, error) {
+  v, err := doContext(ctx, func() (io.Closer, error) { return d.Dial(raddr) })
+  if err != nil {
+    return nil, err
+  }
+  return v.(Conn), nil
+}
+
+type ctxListener struct {
+  Listener
+  a *acceptor
+}
+
+func (l *ctxListener) Accept() (Conn, error) {
+  return l.AcceptContext(context.Background())
+}
+
+func (l *ctxListener) AcceptContext(ctx context.Context) (Conn, error) {
+  v, err := l.a.Accept(ctx)
+  if err != nil {
+    return nil, err
+  }
+  return v.(Conn), nil
+}
+
+func (l *ctxListener) Close() error {
+  err := l.Listener.Close()
+  l.a.Close()
+  return err
+}
+
+type ctxConn struct {
+  Conn
+  a *acceptor
+}
+
+func (c *ctxConn) DialContext(ctx context.Context) (Stream, error) {
+  v, err := doContext(ctx, func() (io.Closer, error) { return c.Dial() })
+  if err != nil {
+    return nil, err
+  }
+  return v.(Stream), nil
+}
+
+func (c *ctxConn) Accept() (Stream, error) {
+  return c.AcceptContext(context.Background())
+}
+
+func (c *ctxConn) AcceptContext(ctx context.Context) (Stream, error) {
+  v, err := c.a.Accept(ctx)
+  if err != nil {
+    return nil, err
+  }
+  return v.(Stream), nil
+}
+
+func (c *ctxConn) Close() error {
+  err := c.Conn.Close()
+  c.a.Close()
+  return err
+}
+
+// doContext runs the blocking f, returning early if ctx is done first.
+// Whatever f returns after that is closed, as no one will use it.
+func doContext(ctx context.Context, f func() (io.Closer, error)) (io.Closer, error) {
+  if err := ctx.Err(); err != nil {
+    return nil, err
+  }
+
+  res := make(chan acceptResult, 1)
+  go func() {
+    v, err := f()
+    res <- acceptResult{v, err}
+  }()
+
+  select {
+  case r := <-res:
+    return r.v, r.err
+  case <-ctx.Done():
+    go func() {
+      if r := <-res; r.err == nil {
+        r.v.Close()
+      }
+    }()
+    return nil, ctx.Err()
+  }
+}
+
+type acceptResult struct {
+  v   io.Closer
+  err error
+}
+
+// acceptor runs blocking accepts in the background, one at a time, and
+// only when a caller asks for one. A caller that gives up leaves its
+// accept running, and the result goes to the next caller.
+type acceptor struct {
+  accept func() (io.Closer, error)
+  want   chan struct{}
+  res    chan acceptResult
+  closed chan struct{}
+  once   sync.Once
+}
+
+func newAcceptor(accept func() (io.Closer, error)) *acceptor {
+  a := &acceptor{
+    accept: accept,
+    want:   make(chan struct{}, 1),
+    res:    make(chan acceptResult, 1),
+    closed: make(chan struct{}),
+  }
+  go a.loop()
+  return a
+}
+
+func (a *acceptor) Accept(ctx context.Context) (io.Closer, error) {
+  if err := ctx.Err(); err != nil {
+    return nil, err
+  }
+
+  select {
+  case a.want <- struct{}{}:
+  default: // an accept is already coming.
+  }
+
+  select {
+  case r := <-a.res:
+    return r.v, r.err
+  case <-ctx.Done():
+    return nil, ctx.Err()
+  case <-a.closed:
+    return nil, net.ErrClosed
+  }
+}
+
+func (a *acceptor) loop() {
+  for {
+    select {
+    case <-a.want:
+    case <-a.closed:
+      return
+    }
+
+    v, err := a.accept()
+    select {
+    case a.res <- acceptResult{v, err}:
+    case <-a.closed:
+      if err == nil {
+        v.Close() // no one will take it.
+      }
+      return
+    }
+  }
+}
+
+// Close stops the acceptor. The underlying listener (or conn) must be
+// closed too, to unblock a pending accept.
+func (a *acceptor) Close() {
+  a.once.Do(func() {
+    close(a.closed)
+
+    // close a result no one took.
+    select {
+    case r := <-a.res:
+      if r.err == nil {
+        r.v.Close()
+      }
+    default:
+    }
+  })
+}
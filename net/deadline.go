@@ -0,0 +1,84 @@
+package xtpctlnet
+
+import (
+  "context"
+  "errors"
+  "os"
+  "sync"
+  "time"
+)
+
+// ConnDeadline implements the deadline of a DeadlineConn, for conns with
+// context variants of Dial and Accept (see ConnContext): they run with
+// the context Context returns. The zero value has no deadline.
+type ConnDeadline struct {
+  lk      sync.Mutex
+  t       time.Time
+  changed chan struct{} // closed when t changes
+}
+
+// SetDeadline sets the deadline. Pending operations get it too.
+func (d *ConnDeadline) SetDeadline(t time.Time) error {
+  d.lk.Lock()
+  d.t = t
+  if d.changed != nil {
+    close(d.changed)
+  }
+  d.changed = make(chan struct{})
+  d.lk.Unlock()
+  return nil
+}
+
+func (d *ConnDeadline) get() (time.Time, <-chan struct{}) {
+  d.lk.Lock()
+  defer d.lk.Unlock()
+  if d.changed == nil {
+    d.changed = make(chan struct{})
+  }
+  return d.t, d.changed
+}
+
+// Context returns a context of parent that is done at the deadline, even
+// one set after. Pass the error of the operation run with it through Err.
+func (d *ConnDeadline) Context(parent context.Context) (context.Context, context.CancelFunc) {
+  ctx, cancel := context.WithCancelCause(parent)
+  if t, _ := d.get(); !t.IsZero() && !time.Now().Before(t) {
+    cancel(os.ErrDeadlineExceeded)
+    return ctx, func() {}
+  }
+  go func() {
+    for {
+      t, changed := d.get()
+      var timer *time.Timer
+      var expired <-chan time.Time
+      if !t.IsZero() {
+        timer = time.NewTimer(time.Until(t))
+        expired = timer.C
+      }
+
+      select {
+      case <-expired:
+        cancel(os.ErrDeadlineExceeded)
+        return
+      case <-changed:
+      case <-ctx.Done():
+      }
+      if timer != nil {
+        timer.Stop()
+      }
+      if ctx.Err() != nil {
+        return
+      }
+    }
+  }()
+  return ctx, func() { cancel(context.Canceled) }
+}
+
+// Err returns the error of an operation that failed with err, run with
+// ctx from Context: os.ErrDeadlineExceeded if the deadline passed.
+func (d *ConnDeadline) Err(ctx context.Context, err error) error {
+  if err != nil && errors.Is(context.Cause(ctx), os.ErrDeadlineExceeded) {
+    return os.ErrDeadlineExceeded
+  }
+  return err
+}
@@ -5,7 +5,9 @@
 package xtpctlnet
 
 import (
+  "errors"
   "io"
+  "time"
 
   ma "github.com/multiformats/go-multiaddr"
   // manet "github.com/multiformats/go-multiaddr-net"
@@ -28,12 +30,8 @@ type Transport interface {
   Close() error
 }
 
+// Listener accepts Conns. See NetListener for a net.Listener adapter.
 type Listener interface {
-  // manet.Listener
-
-  // // NetListener returns a compatible net.Listener.
-  // NetListener() net.Listener
-
   // Accept waits for and returns the next connection to the listener.
   // Returns a Multiaddr friendly Conn
   Accept() (Conn, error)
@@ -41,9 +39,6 @@ type Listener interface {
   // Multiaddr returns the listener's (local) Multiaddr.
   Multiaddr() ma.Multiaddr
 
-  // // Addr returns the net.Listener's network address.
-  // Addr() net.Addr
-
   // Close closes the listener.
   // Any blocked Accept operations will be unblocked and return errors.
   Close() error
@@ -79,6 +74,7 @@ type Conn interface {
   Close() error
 }
 
+// Stream is a bidirectional byte stream. See NetConn for a net.Conn adapter.
 type Stream interface {
   io.Reader
   io.Writer
@@ -87,3 +83,114 @@ type Stream interface {
   // Conn returns the connection this stream belongs to.
   Conn() Conn
 }
+
+// DeadlineStream is a Stream with deadlines, like net.Conn. A deadline is
+// an absolute time after which blocked and future Reads (or Writes) fail
+// with a timeout, instead of hanging. The zero time means no deadline.
+//
+// Streams implement it optionally; see SetDeadline and NetConn.
+type DeadlineStream interface {
+  Stream
+
+  SetDeadline(t time.Time) error
+  SetReadDeadline(t time.Time) error
+  SetWriteDeadline(t time.Time) error
+}
+
+// ErrNoDeadlines is returned when setting deadlines on a Stream (or a
+// Conn) that does not support them.
+var ErrNoDeadlines = errors.New("stream does not support deadlines")
+
+// SetDeadline sets the read and write deadlines of s, if it supports them.
+func SetDeadline(s Stream, t time.Time) error {
+  if ds, ok := s.(DeadlineStream); ok {
+    return ds.SetDeadline(t)
+  }
+  return ErrNoDeadlines
+}
+
+// SetReadDeadline sets the read deadline of s, if it supports them.
+func SetReadDeadline(s Stream, t time.Time) error {
+  if ds, ok := s.(DeadlineStream); ok {
+    return ds.SetReadDeadline(t)
+  }
+  return ErrNoDeadlines
+}
+
+// SetWriteDeadline sets the write deadline of s, if it supports them.
+func SetWriteDeadline(s Stream, t time.Time) error {
+  if ds, ok := s.(DeadlineStream); ok {
+    return ds.SetWriteDeadline(t)
+  }
+  return ErrNoDeadlines
+}
+
+// DeadlineConn is a Conn with a deadline on opening and accepting
+// streams: after it, blocked and future Dials and Accepts fail with
+// os.ErrDeadlineExceeded, a timeout. The zero time means no deadline.
+// It does not apply to the streams; see DeadlineStream.
+//
+// Conns implement it optionally; see SetConnDeadline and ConnDeadline.
+type DeadlineConn interface {
+  Conn
+
+  SetDeadline(t time.Time) error
+}
+
+// SetConnDeadline sets the deadline of c, if it supports them.
+func SetConnDeadline(c Conn, t time.Time) error {
+  if dc, ok := c.(DeadlineConn); ok {
+    return dc.SetDeadline(t)
+  }
+  return ErrNoDeadlines
+}
+
+// HalfCloseStream is a Stream that can shut down one direction, like
+// *net.TCPConn. Streams implement it optionally; see CloseWrite.
+type HalfCloseStream interface {
+  Stream
+
+  // CloseWrite shuts down the writing side. The other side reads io.EOF
+  // after the data written before, and can still send data back.
+  CloseWrite() error
+
+  // CloseRead shuts down the reading side.
+  CloseRead() error
+}
+
+// ResetStream is a Stream that can be aborted. Unlike Close, Reset
+// discards data in flight, and the other side gets an error instead of
+// io.EOF. Streams implement it optionally; see Reset.
+type ResetStream interface {
+  Stream
+
+  Reset() error
+}
+
+// ErrNoHalfClose is returned when half-closing a Stream that does not
+// support it.
+var ErrNoHalfClose = errors.New("stream does not support half-close")
+
+// CloseWrite shuts down the writing side of s, if it supports it.
+func CloseWrite(s Stream) error {
+  if hs, ok := s.(HalfCloseStream); ok {
+    return hs.CloseWrite()
+  }
+  return ErrNoHalfClose
+}
+
+// CloseRead shuts down the reading side of s, if it supports it.
+func CloseRead(s Stream) error {
+  if hs, ok := s.(HalfCloseStream); ok {
+    return hs.CloseRead()
+  }
+  return ErrNoHalfClose
+}
+
+// Reset aborts s, or closes it if it can't be reset.
+func Reset(s Stream) error {
+  if rs, ok := s.(ResetStream); ok {
+    return rs.Reset()
+  }
+  return s.Close()
+}
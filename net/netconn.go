@@ -0,0 +1,456 @@
+package xtpctlnet
+
+import (
+  "io"
+  "net"
+  "os"
+  "sync"
+  "time"
+
+  ma "github.com/multiformats/go-multiaddr"
+  manet "github.com/multiformats/go-multiaddr-net"
+)
+
+// The adapters below let code written for Go's net package (http.Server,
+// grpc.Server, ...) run over xnet Streams. Each Stream is one net.Conn.
+
+// Addr is a net.Addr for multiaddrs that have no net package equivalent.
+type Addr struct {
+  Multiaddr ma.Multiaddr
+}
+
+// Network returns the name of the last protocol of the multiaddr.
+func (a *Addr) Network() string {
+  ps := a.Multiaddr.Protocols()
+  if len(ps) == 0 {
+    return "multiaddr"
+  }
+  return ps[len(ps)-1].Name
+}
+
+func (a *Addr) String() string {
+  return a.Multiaddr.String()
+}
+
+// NetAddr returns the net.Addr for m. Addresses the net package knows
+// (tcp, udp, ip) become their net types, others an *Addr.
+func NetAddr(m ma.Multiaddr) net.Addr {
+  if m == nil {
+    return nil
+  }
+  if a, err := manet.ToNetAddr(m); err == nil {
+    return a
+  }
+  return &Addr{m}
+}
+
+// NetConn returns s as a net.Conn. Its addresses are those of s.Conn().
+// Streams that are not DeadlineStreams get deadlines emulated; a timed out
+// Write may still complete in the background.
+func NetConn(s Stream) net.Conn {
+  if d, ok := s.(DeadlineStream); ok {
+    return &netConn{s: s, d: d, closed: make(chan struct{})}
+  }
+
+  c := &netConn{
+    s:      s,
+    data:   make(chan readResult),
+    wsem:   make(chan struct{}, 1),
+    closed: make(chan struct{}),
+    rd:     newDeadline(),
+    wd:     newDeadline(),
+  }
+  go c.readLoop()
+  return c
+}
+
+type netConn struct {
+  s Stream
+  d DeadlineStream // native deadlines, if any
+
+  // the native deadlines, to report their errors as timeouts.
+  dlk      sync.Mutex
+  rdl, wdl time.Time
+
+  once   sync.Once
+  closed chan struct{}
+
+  // deadline emulation.
+  data   chan readResult // reads done in the background
+  rlk    sync.Mutex      // serializes reads of rest
+  rest   []byte          // unread data from the last read
+  rerr   error           // sticky read error
+  wsem   chan struct{}   // held by the current write
+  rd, wd *deadline
+}
+
+type readResult struct {
+  buf []byte
+  err error
+}
+
+func (c *netConn) readLoop() {
+  for {
+    buf := make([]byte, 32<<10)
+    n, err := c.s.Read(buf)
+    select {
+    case c.data <- readResult{buf[:n], err}:
+    case <-c.closed:
+      return
+    }
+    if err != nil {
+      return
+    }
+  }
+}
+
+func (c *netConn) Read(buf []byte) (int, error) {
+  if c.d != nil {
+    return c.native(&c.rdl, func() (int, error) { return c.s.Read(buf) })
+  }
+
+  c.rlk.Lock()
+  defer c.rlk.Unlock()
+  if err := c.emulatedErr(c.rd); err != nil {
+    return 0, err
+  }
+  if len(c.rest) == 0 && c.rerr == nil {
+    select {
+    case r := <-c.data:
+      c.rest, c.rerr = r.buf, r.err
+    case <-c.rd.wait():
+      return 0, os.ErrDeadlineExceeded
+    case <-c.closed:
+      return 0, net.ErrClosed
+    }
+  }
+
+  n := copy(buf, c.rest)
+  c.rest = c.rest[n:]
+  if len(c.rest) == 0 && c.rerr != nil {
+    return n, c.rerr
+  }
+  return n, nil
+}
+
+func (c *netConn) Write(buf []byte) (int, error) {
+  if c.d != nil {
+    return c.native(&c.wdl, func() (int, error) { return c.s.Write(buf) })
+  }
+
+  if err := c.emulatedErr(c.wd); err != nil {
+    return 0, err
+  }
+  select {
+  case c.wsem <- struct{}{}:
+  case <-c.wd.wait():
+    return 0, os.ErrDeadlineExceeded
+  case <-c.closed:
+    return 0, net.ErrClosed
+  }
+
+  // the write may outlive this call, and buf is the caller's again then.
+  buf = append([]byte(nil), buf...)
+  done := make(chan readResult, 1)
+  go func() {
+    n, err := c.s.Write(buf)
+    <-c.wsem
+    done <- readResult{buf[:n], err}
+  }()
+
+  select {
+  case r := <-done:
+    return len(r.buf), r.err
+  case <-c.wd.wait():
+    return 0, os.ErrDeadlineExceeded
+  case <-c.closed:
+    return 0, net.ErrClosed
+  }
+}
+
+// emulatedErr fails reads and writes past deadline d before they start,
+// as the selects on their results may pick data over the deadline.
+func (c *netConn) emulatedErr(d *deadline) error {
+  if isClosed(c.closed) {
+    return net.ErrClosed
+  }
+  if isClosed(d.wait()) {
+    return os.ErrDeadlineExceeded
+  }
+  return nil
+}
+
+// Close closes the stream. Streams may only half-close, so pending and
+// later reads and writes fail with net.ErrClosed here.
+func (c *netConn) Close() error {
+  c.once.Do(func() { close(c.closed) })
+  err := c.s.Close()
+  if c.d != nil {
+    c.d.SetDeadline(time.Now()) // unblock pending reads and writes.
+  }
+  return err
+}
+
+func (c *netConn) LocalAddr() net.Addr  { return NetAddr(c.s.Conn().LocalMultiaddr()) }
+func (c *netConn) RemoteAddr() net.Addr { return NetAddr(c.s.Conn().RemoteMultiaddr()) }
+
+// native runs op, a read or write of a DeadlineStream with deadline dl.
+// It fails at once past the deadline, as streams may still have room to
+// buffer, and turns errors past it into os.ErrDeadlineExceeded, as
+// streams don't all report timeouts as net.Errors.
+func (c *netConn) native(dl *time.Time, op func() (int, error)) (int, error) {
+  if err := c.opErr(nil, dl); err != nil {
+    return 0, err
+  }
+  n, err := op()
+  if err != nil {
+    err = c.opErr(err, dl)
+  }
+  return n, err
+}
+
+func (c *netConn) opErr(err error, dl *time.Time) error {
+  if isClosed(c.closed) {
+    return net.ErrClosed
+  }
+  c.dlk.Lock()
+  t := *dl
+  c.dlk.Unlock()
+  if !t.IsZero() && !time.Now().Before(t) {
+    return os.ErrDeadlineExceeded
+  }
+  return err
+}
+
+func (c *netConn) setDeadlines(rdl, wdl *time.Time, t time.Time) {
+  c.dlk.Lock()
+  if rdl != nil {
+    *rdl = t
+  }
+  if wdl != nil {
+    *wdl = t
+  }
+  c.dlk.Unlock()
+}
+
+func (c *netConn) SetDeadline(t time.Time) error {
+  if c.d != nil {
+    c.setDeadlines(&c.rdl, &c.wdl, t)
+    return c.d.SetDeadline(t)
+  }
+  c.rd.set(t)
+  c.wd.set(t)
+  return nil
+}
+
+func (c *netConn) SetReadDeadline(t time.Time) error {
+  if c.d != nil {
+    c.setDeadlines(&c.rdl, nil, t)
+    return c.d.SetReadDeadline(t)
+  }
+  c.rd.set(t)
+  return nil
+}
+
+func (c *netConn) SetWriteDeadline(t time.Time) error {
+  if c.d != nil {
+    c.setDeadlines(nil, &c.wdl, t)
+    return c.d.SetWriteDeadline(t)
+  }
+  c.wd.set(t)
+  return nil
+}
+
+// deadline is a channel closed when a settable deadline passes.
+type deadline struct {
+  lk     sync.Mutex
+  timer  *time.Timer
+  cancel chan struct{}
+}
+
+func newDeadline() *deadline {
+  return &deadline{cancel: make(chan struct{})}
+}
+
+// set sets the deadline. The zero time means no deadline.
+func (d *deadline) set(t time.Time) {
+  d.lk.Lock()
+  defer d.lk.Unlock()
+
+  if d.timer != nil && !d.timer.Stop() {
+    <-d.cancel // the timer already closed it. wait for that.
+  }
+  d.timer = nil
+
+  expired := isClosed(d.cancel)
+  if t.IsZero() {
+    if expired {
+      d.cancel = make(chan struct{})
+    }
+    return
+  }
+
+  if dur := time.Until(t); dur > 0 {
+    if expired {
+      d.cancel = make(chan struct{})
+    }
+    cancel := d.cancel
+    d.timer = time.AfterFunc(dur, func() {
+      close(cancel)
+    })
+    return
+  }
+
+  if !expired {
+    close(d.cancel)
+  }
+}
+
+// wait returns a channel closed when the deadline passes.
+func (d *deadline) wait() chan struct{} {
+  d.lk.Lock()
+  defer d.lk.Unlock()
+  return d.cancel
+}
+
+func isClosed(c chan struct{}) bool {
+  select {
+  case <-c:
+    return true
+  default:
+    return false
+  }
+}
+
+// NetListener returns l as a net.Listener. It accepts the conns of l, and
+// returns their incoming streams as net.Conns. Closing it closes l and
+// the accepted conns.
+func NetListener(l Listener) net.Listener {
+  nl := &netListener{
+    l:      l,
+    conns:  make(map[Conn]struct{}),
+    accept: make(chan net.Conn),
+    closed: make(chan struct{}),
+  }
+  go nl.acceptConns()
+  return nl
+}
+
+type netListener struct {
+  l Listener
+
+  lk    sync.Mutex
+  conns map[Conn]struct{}
+  err   error // why acceptConns stopped
+
+  accept chan net.Conn
+  once   sync.Once
+  closed chan struct{}
+}
+
+func (nl *netListener) acceptConns() {
+  for {
+    c, err := nl.l.Accept()
+    if err != nil {
+      nl.lk.Lock()
+      nl.err = err
+      nl.lk.Unlock()
+      nl.Close()
+      return
+    }
+
+    nl.lk.Lock()
+    if isClosed(nl.closed) { // raced with Close.
+      nl.lk.Unlock()
+      c.Close()
+      return
+    }
+    nl.conns[c] = struct{}{}
+    nl.lk.Unlock()
+    go nl.acceptStreams(c)
+  }
+}
+
+func (nl *netListener) acceptStreams(c Conn) {
+  defer func() {
+    nl.lk.Lock()
+    delete(nl.conns, c)
+    nl.lk.Unlock()
+    c.Close()
+  }()
+
+  for {
+    s, err := c.Accept()
+    if err != nil {
+      return
+    }
+    select {
+    case nl.accept <- NetConn(s):
+    case <-nl.closed:
+      s.Close()
+      return
+    }
+  }
+}
+
+func (nl *netListener) Accept() (net.Conn, error) {
+  select {
+  case c := <-nl.accept:
+    return c, nil
+  case <-nl.closed:
+    nl.lk.Lock()
+    err := nl.err
+    nl.lk.Unlock()
+    if err == nil || err == io.EOF {
+      err = net.ErrClosed
+    }
+    return nil, err
+  }
+}
+
+func (nl *netListener) Close() error {
+  var err error
+  nl.once.Do(func() {
+    nl.lk.Lock()
+    close(nl.closed)
+    nl.lk.Unlock()
+    err = nl.l.Close()
+
+    nl.lk.Lock()
+    for c := range nl.conns {
+      c.Close()
+    }
+    nl.lk.Unlock()
+  })
+  return err
+}
+
+func (nl *netListener) Addr() net.Addr {
+  return NetAddr(nl.l.Multiaddr())
+}
+
+// ConnListener returns a net.Listener for the incoming streams of c.
+// Closing it closes c.
+func ConnListener(c Conn) net.Listener {
+  return &connListener{c}
+}
+
+type connListener struct {
+  c Conn
+}
+
+func (cl *connListener) Accept() (net.Conn, error) {
+  s, err := cl.c.Accept()
+  if err != nil {
+    return nil, err
+  }
+  return NetConn(s), nil
+}
+
+func (cl *connListener) Close() error {
+  return cl.c.Close()
+}
+
+func (cl *connListener) Addr() net.Addr {
+  return NetAddr(cl.c.LocalMultiaddr())
+}
@@ -0,0 +1,387 @@
+package xtpctlnet_test
+
+import (
+  "bytes"
+  "errors"
+  "fmt"
+  "io"
+  "net"
+  "net/http"
+  "os"
+  "sync"
+  "sync/atomic"
+  "testing"
+  "time"
+
+  ximpls "github.com/libp2p/go-xtp-ctl/impls"
+  xnet "github.com/libp2p/go-xtp-ctl/net"
+  "github.com/libp2p/go-xtp-ctl/xtptest"
+  ma "github.com/multiformats/go-multiaddr"
+  "golang.org/x/net/nettest"
+)
+
+var pipes int64
+
+// streamPair returns the two ends of a stream between conns of l and
+// dial. The pair is closed with the test.
+func streamPair(t *testing.T, l xnet.Listener, dial func() (xnet.Conn, error)) (a, b xnet.Stream, err error) {
+  type accepted struct {
+    c   xnet.Conn
+    s   xnet.Stream
+    err error
+  }
+  ch := make(chan accepted, 1)
+  go func() {
+    c, err := l.Accept()
+    if err != nil {
+      ch <- accepted{err: err}
+      return
+    }
+    s, err := c.Accept()
+    ch <- accepted{c, s, err}
+  }()
+
+  c, err := dial()
+  if err != nil {
+    return nil, nil, err
+  }
+  t.Cleanup(func() { c.Close() })
+  a, err = c.Dial()
+  if err != nil {
+    return nil, nil, err
+  }
+  // streams may only reach the listener with their first data.
+  if _, err := a.Write([]byte{0}); err != nil {
+    return nil, nil, err
+  }
+
+  r := <-ch
+  if r.c != nil {
+    t.Cleanup(func() { r.c.Close() })
+  }
+  if r.err != nil {
+    return nil, nil, r.err
+  }
+  if _, err := io.ReadFull(r.s, make([]byte, 1)); err != nil {
+    return nil, nil, err
+  }
+  return a, r.s, nil
+}
+
+// memoryPipe makes pairs of smux streams, with native deadlines.
+func memoryPipe(t *testing.T, wrap func(xnet.Stream) xnet.Stream) nettest.MakePipe {
+  return func() (net.Conn, net.Conn, func(), error) {
+    tpt := &ximpls.MemoryTransport{}
+    addr := ma.StringCast(fmt.Sprintf("/memory/pipe-%d", atomic.AddInt64(&pipes, 1)))
+    l, err := tpt.Listen(addr)
+    if err != nil {
+      return nil, nil, nil, err
+    }
+    defer l.Close()
+
+    a, b, err := streamPair(t, l, func() (xnet.Conn, error) { return tpt.Dial(addr) })
+    if err != nil {
+      return nil, nil, nil, err
+    }
+    c1, c2 := xnet.NetConn(wrap(a)), xnet.NetConn(wrap(b))
+    return c1, c2, func() { c1.Close(); c2.Close() }, nil
+  }
+}
+
+// noDeadlines hides the deadlines of a stream, so NetConn emulates them.
+type noDeadlines struct {
+  xnet.Stream
+}
+
+func native(s xnet.Stream) xnet.Stream { return s }
+
+func emulated(s xnet.Stream) xnet.Stream { return noDeadlines{s} }
+
+func TestNetConnNative(t *testing.T) {
+  nettest.TestConn(t, memoryPipe(t, native))
+}
+
+func TestNetConnEmulated(t *testing.T) {
+  nettest.TestConn(t, memoryPipe(t, emulated))
+}
+
+func TestNetConnClientStream(t *testing.T) {
+  h := xtptest.NewMemory(t, &ximpls.MemoryTransport{})
+  l, err := h.Client.Listen(ma.StringCast("/memory/client-stream"))
+  if err != nil {
+    t.Fatal(err)
+  }
+  defer l.Close()
+  dialer := h.NewClient()
+
+  nettest.TestConn(t, func() (net.Conn, net.Conn, func(), error) {
+    a, b, err := streamPair(t, l, func() (xnet.Conn, error) { return dialer.Dial(l.Multiaddr()) })
+    if err != nil {
+      return nil, nil, nil, err
+    }
+    c1, c2 := xnet.NetConn(a), xnet.NetConn(b)
+    return c1, c2, func() { c1.Close(); c2.Close() }, nil
+  })
+}
+
+func netConnPair(t *testing.T, wrap func(xnet.Stream) xnet.Stream) (net.Conn, net.Conn) {
+  c1, c2, stop, err := memoryPipe(t, wrap)()
+  if err != nil {
+    t.Fatal(err)
+  }
+  t.Cleanup(stop)
+  return c1, c2
+}
+
+func isTimeout(err error) bool {
+  var ne net.Error
+  return errors.Is(err, os.ErrDeadlineExceeded) && errors.As(err, &ne) && ne.Timeout()
+}
+
+// TestNetConnDeadlineRearm moves an emulated deadline: later before it
+// passes, to the past, and off after it passed.
+func TestNetConnDeadlineRearm(t *testing.T) {
+  c1, c2 := netConnPair(t, emulated)
+  buf := make([]byte, 1)
+
+  start := time.Now()
+  c1.SetReadDeadline(start.Add(50 * time.Millisecond))
+  c1.SetReadDeadline(start.Add(300 * time.Millisecond))
+  if _, err := c1.Read(buf); !isTimeout(err) {
+    t.Fatal("expected a timeout, got", err)
+  }
+  if d := time.Since(start); d < 250*time.Millisecond {
+    t.Fatal("the first deadline still fired, after", d)
+  }
+
+  // expired deadlines stay expired, until moved.
+  if _, err := c1.Read(buf); !isTimeout(err) {
+    t.Fatal("expected a timeout, got", err)
+  }
+  c1.SetReadDeadline(time.Now().Add(time.Hour))
+  c1.SetReadDeadline(time.Now().Add(-time.Second))
+  if _, err := c1.Read(buf); !isTimeout(err) {
+    t.Fatal("past deadline: expected a timeout, got", err)
+  }
+
+  c1.SetReadDeadline(time.Time{})
+  go c2.Write([]byte("x"))
+  if _, err := c1.Read(buf); err != nil || buf[0] != 'x' {
+    t.Fatal("read without a deadline:", err)
+  }
+}
+
+// TestNetConnWriteTimeout times out an emulated Write that the peer does
+// not read. The Write completes in the background, before the next one.
+func TestNetConnWriteTimeout(t *testing.T) {
+  c1, c2 := netConnPair(t, emulated)
+
+  big := bytes.Repeat([]byte("a"), 4<<20) // beyond the stream's window.
+  c1.SetWriteDeadline(time.Now().Add(50 * time.Millisecond))
+  if _, err := c1.Write(big); !isTimeout(err) {
+    t.Fatal("expected a timeout, got", err)
+  }
+  if _, err := c1.Write([]byte("b")); !isTimeout(err) {
+    t.Fatal("expected a timeout, got", err)
+  }
+
+  done := make(chan error, 1)
+  go func() {
+    c1.SetWriteDeadline(time.Time{})
+    _, err := c1.Write([]byte("b"))
+    done <- err
+  }()
+
+  got := make([]byte, len(big)+1)
+  if _, err := io.ReadFull(c2, got); err != nil {
+    t.Fatal(err)
+  }
+  if !bytes.Equal(got[:len(big)], big) || got[len(big)] != 'b' {
+    t.Fatal("the timed out write did not finish before the next one")
+  }
+  if err := <-done; err != nil {
+    t.Fatal(err)
+  }
+}
+
+func TestNetAddr(t *testing.T) {
+  cases := []struct {
+    addr    string
+    network string
+    str     string
+  }{
+    {"/ip4/127.0.0.1/tcp/80", "tcp", "127.0.0.1:80"},
+    {"/ip6/::1/udp/53", "udp", "[::1]:53"},
+    {"/memory/a", "memory", "/memory/a"},
+  }
+  for _, c := range cases {
+    a := xnet.NetAddr(ma.StringCast(c.addr))
+    if a.Network() != c.network || a.String() != c.str {
+      t.Errorf("%s: got %s %s", c.addr, a.Network(), a)
+    }
+  }
+
+  if _, ok := xnet.NetAddr(ma.StringCast("/ip4/127.0.0.1/tcp/80")).(*net.TCPAddr); !ok {
+    t.Error("tcp is not a *net.TCPAddr")
+  }
+  if _, ok := xnet.NetAddr(ma.StringCast("/memory/a")).(*xnet.Addr); !ok {
+    t.Error("memory is not an *xnet.Addr")
+  }
+  if xnet.NetAddr(nil) != nil {
+    t.Error("nil multiaddr")
+  }
+}
+
+// TestNetListenerClose closes a NetListener while it accepts. Accept
+// returns net.ErrClosed, and no dialed conn is left hanging.
+func TestNetListenerClose(t *testing.T) {
+  for i := 0; i < 20; i++ {
+    tpt := &ximpls.MemoryTransport{}
+    addr := ma.StringCast(fmt.Sprintf("/memory/listener-%d", i))
+    l, err := tpt.Listen(addr)
+    if err != nil {
+      t.Fatal(err)
+    }
+    nl := xnet.NetListener(l)
+
+    var wg sync.WaitGroup
+    errs := make(chan error, 4)
+    for j := 0; j < 4; j++ {
+      wg.Add(1)
+      go func() {
+        defer wg.Done()
+        for {
+          c, err := nl.Accept()
+          if err != nil {
+            errs <- err
+            return
+          }
+          c.Close()
+        }
+      }()
+    }
+
+    conns := make(chan xnet.Conn, 8)
+    for j := 0; j < 8; j++ {
+      wg.Add(1)
+      go func() {
+        defer wg.Done()
+        c, err := tpt.Dial(addr)
+        if err != nil {
+          return
+        }
+        conns <- c
+        if s, err := c.Dial(); err == nil {
+          s.Write([]byte("x"))
+        }
+      }()
+    }
+    nl.Close()
+    wg.Wait()
+    close(errs)
+    close(conns)
+
+    for err := range errs {
+      if !errors.Is(err, net.ErrClosed) {
+        t.Fatal("accept after close:", err)
+      }
+    }
+    for c := range conns {
+      if err := deadConn(c); err != nil {
+        t.Fatal(err)
+      }
+      c.Close()
+    }
+  }
+}
+
+// deadConn checks that the peer of c is gone.
+func deadConn(c xnet.Conn) error {
+  s, err := c.Dial()
+  if err != nil {
+    return nil
+  }
+  defer s.Close()
+  s.Write([]byte("x"))
+  xnet.SetReadDeadline(s, time.Now().Add(2*time.Second))
+  _, err = s.Read(make([]byte, 1))
+  if err == nil {
+    return errors.New("read from a closed listener's conn")
+  }
+  if isTimeout(err) {
+    return fmt.Errorf("conn left hanging: %s", err)
+  }
+  return nil
+}
+
+type failingListener struct {
+  xnet.Listener
+  err error
+}
+
+func (l *failingListener) Accept() (xnet.Conn, error) { return nil, l.err }
+
+func TestNetListenerError(t *testing.T) {
+  tpt := &ximpls.MemoryTransport{}
+  l, err := tpt.Listen(ma.StringCast("/memory/failing"))
+  if err != nil {
+    t.Fatal(err)
+  }
+  broken := errors.New("broken")
+  nl := xnet.NetListener(&failingListener{l, broken})
+  defer nl.Close()
+
+  if _, err := nl.Accept(); err != broken {
+    t.Fatal("expected the listener's error, got", err)
+  }
+}
+
+func TestHTTPOverNetListener(t *testing.T) {
+  h := xtptest.NewMemory(t, &ximpls.TCPTransport{})
+  l, err := h.Client.Listen(ma.StringCast("/ip4/127.0.0.1/tcp/0"))
+  if err != nil {
+    t.Fatal(err)
+  }
+  nl := xnet.NetListener(l)
+  defer nl.Close()
+  if _, ok := nl.Addr().(*net.TCPAddr); !ok {
+    t.Fatal("listener address is not a *net.TCPAddr:", nl.Addr())
+  }
+
+  srv := &http.Server{Handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+    fmt.Fprintf(w, "hello %s", r.URL.Path)
+  })}
+  go srv.Serve(nl)
+  defer srv.Close()
+
+  c, err := h.NewClient().Dial(l.Multiaddr())
+  if err != nil {
+    t.Fatal(err)
+  }
+  defer c.Close()
+  hc := &http.Client{Transport: &http.Transport{
+    Dial: func(_, _ string) (net.Conn, error) {
+      s, err := c.Dial()
+      if err != nil {
+        return nil, err
+      }
+      return xnet.NetConn(s), nil
+    },
+  }}
+  defer hc.CloseIdleConnections()
+
+  for i := 0; i < 3; i++ {
+    res, err := hc.Get(fmt.Sprintf("http://xtp/p%d", i))
+    if err != nil {
+      t.Fatal(err)
+    }
+    body, err := io.ReadAll(res.Body)
+    res.Body.Close()
+    if err != nil {
+      t.Fatal(err)
+    }
+    if want := fmt.Sprintf("hello /p%d", i); string(body) != want {
+      t.Fatalf("got %q, want %q", body, want)
+    }
+  }
+}
@@ -1,6 +1,11 @@
 package xtpctlnet
 
 import (
+  "context"
+  "io"
+  "sync"
+  "time"
+
   ma "github.com/multiformats/go-multiaddr"
   manet "github.com/multiformats/go-multiaddr-net"
   ymux "gx/ipfs/QmSHTSkxXGQgaHWz91oZV3CDy3hmKmDgpjbYRT6niACG4E/go-smux-yamux"
@@ -10,18 +15,42 @@ import (
 // XtpCtlConn wraps a raw manet.Conn with the necessary
 // protocols for XTP-Ctl. For now this means:
 // - yamux
-// the server parameter is used by yamux.
+// the server parameter is used by yamux. To secure c first, see
+// SecureConn. The muxed transports of xtpimpls layer their conns with it
+// too.
 func XtpCtlConn(c manet.Conn, server bool) (Conn, error) {
   tr := ymux.DefaultTransport
   sc, err := tr.NewConn(c, server)
-  return &smuxConn{c, sc}, err
+  if err != nil {
+    c.Close()
+    return nil, err
+  }
+  return newSmuxConn(c, sc), nil
 }
 
 type smuxConn struct {
   C manet.Conn
   S smux.Conn
+  ConnDeadline
+
+  a *acceptor
 }
 
+func newSmuxConn(c manet.Conn, sc smux.Conn) *smuxConn {
+  conn := &smuxConn{C: c, S: sc}
+  conn.a = newAcceptor(func() (io.Closer, error) {
+    s, err := sc.AcceptStream()
+    if err != nil {
+      return nil, err
+    }
+    return &smuxStream{C: conn, S: s}, nil
+  })
+  return conn
+}
+
+var _ ConnContext = (*smuxConn)(nil)
+var _ DeadlineConn = (*smuxConn)(nil)
+
 func (c *smuxConn) LocalMultiaddr() ma.Multiaddr {
   return c.C.LocalMultiaddr()
 }
@@ -31,22 +60,56 @@ func (c *smuxConn) RemoteMultiaddr() ma.Multiaddr {
 }
 
 func (c *smuxConn) Dial() (Stream, error) {
-  s, err := c.S.OpenStream()
-  return &smuxStream{c, s}, err
+  return c.DialContext(context.Background())
+}
+
+func (c *smuxConn) DialContext(ctx context.Context) (Stream, error) {
+  ctx, cancel := c.Context(ctx)
+  defer cancel()
+  v, err := doContext(ctx, func() (io.Closer, error) {
+    s, err := c.S.OpenStream()
+    if err != nil {
+      return nil, err
+    }
+    return &smuxStream{C: c, S: s}, nil
+  })
+  if err != nil {
+    return nil, c.Err(ctx, err)
+  }
+  return v.(Stream), nil
 }
 
 func (c *smuxConn) Accept() (Stream, error) {
-  s, err := c.S.AcceptStream()
-  return &smuxStream{c, s}, err
+  return c.AcceptContext(context.Background())
+}
+
+func (c *smuxConn) AcceptContext(ctx context.Context) (Stream, error) {
+  ctx, cancel := c.Context(ctx)
+  defer cancel()
+  v, err := c.a.Accept(ctx)
+  if err != nil {
+    return nil, c.Err(ctx, err)
+  }
+  return v.(Stream), nil
 }
 
 func (c *smuxConn) Close() error {
-  return c.S.Close()
+  err := c.S.Close()
+  c.a.Close()
+  return err
+}
+
+// IsClosed returns whether the session is closed, or broken.
+func (c *smuxConn) IsClosed() bool {
+  return c.S.IsClosed()
 }
 
 type smuxStream struct {
   C Conn
   S smux.Stream
+
+  lk      sync.Mutex
+  rclosed bool
 }
 
 func (s *smuxStream) Conn() Conn {
@@ -54,7 +117,20 @@ func (s *smuxStream) Conn() Conn {
 }
 
 func (s *smuxStream) Read(buf []byte) (int, error) {
-  return s.S.Read(buf)
+  if s.readClosed() {
+    return 0, io.EOF
+  }
+  n, err := s.S.Read(buf)
+  if err != nil && s.readClosed() {
+    err = io.EOF // unblocked by CloseRead.
+  }
+  return n, err
+}
+
+func (s *smuxStream) readClosed() bool {
+  s.lk.Lock()
+  defer s.lk.Unlock()
+  return s.rclosed
 }
 
 func (s *smuxStream) Write(buf []byte) (int, error) {
@@ -65,6 +141,47 @@ func (s *smuxStream) Close() error {
   return s.S.Close()
 }
 
+// CloseWrite shuts down the writing side. A muxer stream's Close only
+// does that (yamux sends a FIN): the remote can still write back.
+func (s *smuxStream) CloseWrite() error {
+  return s.S.Close()
+}
+
+// CloseRead shuts down the reading side. Reads return io.EOF, pending
+// ones too.
+func (s *smuxStream) CloseRead() error {
+  s.lk.Lock()
+  s.rclosed = true
+  s.lk.Unlock()
+  return s.S.SetReadDeadline(time.Now()) // unblock pending reads.
+}
+
+// Reset aborts the stream, if the muxer supports it. Otherwise, it
+// closes it.
+func (s *smuxStream) Reset() error {
+  if rs, ok := s.S.(interface{ Reset() error }); ok {
+    return rs.Reset()
+  }
+  s.CloseRead()
+  return s.S.Close()
+}
+
+func (s *smuxStream) SetDeadline(t time.Time) error {
+  return s.S.SetDeadline(t)
+}
+
+func (s *smuxStream) SetReadDeadline(t time.Time) error {
+  return s.S.SetReadDeadline(t)
+}
+
+func (s *smuxStream) SetWriteDeadline(t time.Time) error {
+  return s.S.SetWriteDeadline(t)
+}
+
+var _ DeadlineStream = (*smuxStream)(nil)
+var _ HalfCloseStream = (*smuxStream)(nil)
+var _ ResetStream = (*smuxStream)(nil)
+
 type smuxListener struct {
   L manet.Listener
 }
@@ -74,26 +191,30 @@ func (l *smuxListener) Multiaddr() ma.Multiaddr {
 }
 
 func (l *smuxListener) Accept() (Conn, error) {
-  c, err := l.L.Accept()
-  if err != nil {
-    return nil, err
+  for {
+    c, err := l.L.Accept()
+    if err != nil {
+      return nil, err
+    }
+    sc, err := XtpCtlConn(c, true)
+    if err != nil {
+      continue // dropped. one bad peer does not fail the listener.
+    }
+    return sc, nil
   }
-  return XtpCtlConn(c, true)
 }
 
 func (l *smuxListener) Close() error {
   return l.L.Close()
 }
 
+// Listen listens for xtp-ctl conns on laddr, in plaintext. See
+// ListenSecure.
 func Listen(laddr ma.Multiaddr) (Listener, error) {
-  l, err := manet.Listen(laddr)
-  return &smuxListener{l}, err
+  return ListenSecure(laddr, nil)
 }
 
+// Dial dials an xtp-ctl conn to raddr, in plaintext. See DialSecure.
 func Dial(raddr ma.Multiaddr) (Conn, error) {
-  c, err := manet.Dial(raddr)
-  if err != nil {
-    return nil, err
-  }
-  return XtpCtlConn(c, false)
+  return DialSecure(raddr, nil)
 }
@@ -0,0 +1,76 @@
+package xtpctlnet_test
+
+import (
+  "crypto/ecdsa"
+  "crypto/elliptic"
+  "crypto/rand"
+  "crypto/tls"
+  "crypto/x509"
+  "crypto/x509/pkix"
+  "errors"
+  "math/big"
+  "testing"
+  "time"
+
+  xnet "github.com/libp2p/go-xtp-ctl/net"
+  "github.com/libp2p/go-xtp-ctl/net/xnettest"
+  ma "github.com/multiformats/go-multiaddr"
+)
+
+// ctlTransport is an xnet.Transport over the xtp-ctl conns of Listen and
+// Dial, secured with sec, so the suite checks XtpCtlConn.
+type ctlTransport struct {
+  server, client *xnet.Security
+}
+
+func (t *ctlTransport) Code() string { return "/tcp" }
+
+func (t *ctlTransport) Dial(raddr ma.Multiaddr) (xnet.Conn, error) {
+  return xnet.DialSecure(raddr, t.client)
+}
+
+func (t *ctlTransport) Dialer(laddr ma.Multiaddr) (xnet.Dialer, error) {
+  return nil, errors.New("no dialers")
+}
+
+func (t *ctlTransport) Listen(laddr ma.Multiaddr) (xnet.Listener, error) {
+  return xnet.ListenSecure(laddr, t.server)
+}
+
+func (t *ctlTransport) Close() error { return nil }
+
+func TestXtpCtlConn(t *testing.T) {
+  f := func() xnet.Transport { return &ctlTransport{} }
+  xnettest.SubtestAll(t, f, ma.StringCast("/ip4/127.0.0.1/tcp/0"))
+}
+
+func TestXtpCtlConnTLS(t *testing.T) {
+  cert := selfSigned(t)
+  server := &xnet.Security{TLS: &tls.Config{Certificates: []tls.Certificate{cert}}}
+  client := &xnet.Security{PinnedKeys: [][]byte{xnet.KeyPin(cert.Leaf)}}
+
+  f := func() xnet.Transport { return &ctlTransport{server, client} }
+  xnettest.SubtestAll(t, f, ma.StringCast("/ip4/127.0.0.1/tcp/0"))
+}
+
+func selfSigned(t *testing.T) tls.Certificate {
+  key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+  if err != nil {
+    t.Fatal(err)
+  }
+  tmpl := &x509.Certificate{
+    SerialNumber: big.NewInt(1),
+    Subject:      pkix.Name{CommonName: "xtpd"},
+    NotBefore:    time.Now().Add(-time.Hour),
+    NotAfter:     time.Now().Add(time.Hour),
+  }
+  der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+  if err != nil {
+    t.Fatal(err)
+  }
+  leaf, err := x509.ParseCertificate(der)
+  if err != nil {
+    t.Fatal(err)
+  }
+  return tls.Certificate{Certificate: [][]byte{der}, PrivateKey: key, Leaf: leaf}
+}
@@ -0,0 +1,339 @@
+package xtpctlnet
+
+import (
+  "bytes"
+  "crypto/sha256"
+  "crypto/tls"
+  "crypto/x509"
+  "encoding/base64"
+  "errors"
+  "fmt"
+  "net"
+  "os"
+  "sync"
+  "time"
+
+  ma "github.com/multiformats/go-multiaddr"
+  manet "github.com/multiformats/go-multiaddr-net"
+)
+
+// HandshakeTimeout bounds the TLS handshake of xtp-ctl connections.
+var HandshakeTimeout = 10 * time.Second
+
+// ErrKeyNotPinned is returned when the peer's key is not one of the
+// pinned keys.
+var ErrKeyNotPinned = errors.New("peer key not pinned")
+
+// Security configures the security layer of xtp-ctl connections, between
+// the raw connection and yamux. A nil *Security is plaintext.
+type Security struct {
+  // TLS is the tls config. Servers need a certificate. Set ClientAuth
+  // (and ClientCAs) for mutual TLS, and RootCAs and ServerName for
+  // clients to verify servers with.
+  TLS *tls.Config
+
+  // PinnedKeys, if set, are the SHA-256 hashes of the public keys (the
+  // DER SubjectPublicKeyInfo) the peer may have. See KeyPin. With pins,
+  // clients accept self signed certificates (unless RootCAs is set),
+  // and servers require a client certificate (unless ClientAuth is set).
+  PinnedKeys [][]byte
+}
+
+// KeyPin returns the pin of cert's public key, for Security.PinnedKeys.
+func KeyPin(cert *x509.Certificate) []byte {
+  h := sha256.Sum256(cert.RawSubjectPublicKeyInfo)
+  return h[:]
+}
+
+// config returns the tls config for one side of a connection.
+func (sec *Security) config(server bool) *tls.Config {
+  cfg := &tls.Config{}
+  if sec.TLS != nil {
+    cfg = sec.TLS.Clone()
+  }
+  if len(sec.PinnedKeys) == 0 {
+    return cfg
+  }
+
+  if server && cfg.ClientAuth == tls.NoClientCert {
+    cfg.ClientAuth = tls.RequireAnyClientCert
+  }
+  if !server && cfg.RootCAs == nil {
+    cfg.InsecureSkipVerify = true // the pins verify the server.
+  }
+  verify := cfg.VerifyConnection
+  cfg.VerifyConnection = func(cs tls.ConnectionState) error {
+    if err := sec.checkPin(cs.PeerCertificates); err != nil {
+      return err
+    }
+    if verify != nil {
+      return verify(cs)
+    }
+    return nil
+  }
+  return cfg
+}
+
+func (sec *Security) checkPin(certs []*x509.Certificate) error {
+  if len(certs) == 0 {
+    return fmt.Errorf("%w: no certificate", ErrKeyNotPinned)
+  }
+  pin := KeyPin(certs[0])
+  for _, p := range sec.PinnedKeys {
+    if bytes.Equal(p, pin) {
+      return nil
+    }
+  }
+  return fmt.Errorf("%w: %s", ErrKeyNotPinned, base64.StdEncoding.EncodeToString(pin))
+}
+
+// SecureConn runs the handshake of sec on c, and returns the secured conn.
+// It returns c as is if sec is nil. On failure, c is closed.
+func SecureConn(c manet.Conn, server bool, sec *Security) (manet.Conn, error) {
+  if sec == nil {
+    return c, nil
+  }
+
+  cfg := sec.config(server)
+  var tc *tls.Conn
+  if server {
+    tc = tls.Server(c, cfg)
+  } else {
+    if cfg.ServerName == "" && !cfg.InsecureSkipVerify {
+      cfg.ServerName = hostOf(c.RemoteMultiaddr())
+    }
+    tc = tls.Client(c, cfg)
+  }
+
+  tc.SetDeadline(time.Now().Add(HandshakeTimeout))
+  if err := tc.Handshake(); err != nil {
+    c.Close()
+    return nil, err
+  }
+  tc.SetDeadline(time.Time{})
+  return &tlsConn{tc, c.LocalMultiaddr(), c.RemoteMultiaddr(), len(sec.PinnedKeys) > 0}, nil
+}
+
+// hostOf returns the ip or dns name of a, or "".
+func hostOf(a ma.Multiaddr) string {
+  for _, code := range []int{ma.P_IP4, ma.P_IP6, ma.P_DNS4, ma.P_DNS6} {
+    if v, err := a.ValueForProtocol(code); err == nil {
+      return v
+    }
+  }
+  return ""
+}
+
+type tlsConn struct {
+  *tls.Conn
+  laddr  ma.Multiaddr
+  raddr  ma.Multiaddr
+  pinned bool // the peer's key is one of the pinned keys
+}
+
+func (c *tlsConn) LocalMultiaddr() ma.Multiaddr  { return c.laddr }
+func (c *tlsConn) RemoteMultiaddr() ma.Multiaddr { return c.raddr }
+
+// ConnectionState returns the tls state of c, if it is an xtp-ctl conn
+// secured with TLS.
+func ConnectionState(c Conn) (tls.ConnectionState, bool) {
+  tc, ok := tlsConnOf(c)
+  if !ok {
+    return tls.ConnectionState{}, false
+  }
+  return tc.ConnectionState(), true
+}
+
+// PeerCertificate returns the certificate of the peer of c, if c is an
+// xtp-ctl conn secured with TLS, and the certificate is verified: by a
+// CA, or by a pin. Pinned certificates have no VerifiedChains.
+func PeerCertificate(c Conn) (*x509.Certificate, bool) {
+  tc, ok := tlsConnOf(c)
+  if !ok {
+    return nil, false
+  }
+  st := tc.ConnectionState()
+  if len(st.PeerCertificates) == 0 || len(st.VerifiedChains) == 0 && !tc.pinned {
+    return nil, false
+  }
+  return st.PeerCertificates[0], true
+}
+
+func tlsConnOf(c Conn) (*tlsConn, bool) {
+  sc, ok := c.(*smuxConn)
+  if !ok {
+    return nil, false
+  }
+  tc, ok := sc.C.(*tlsConn)
+  return tc, ok
+}
+
+// secureListener runs the handshakes of the conns it accepts in the
+// background, so a slow (or hostile) peer does not hold up the others.
+// Conns that fail their handshake are dropped.
+type secureListener struct {
+  manet.Listener
+  sec *Security
+
+  conns   chan manet.Conn
+  done    chan struct{}
+  errOnce sync.Once
+  err     error
+}
+
+func newSecureListener(l manet.Listener, sec *Security) *secureListener {
+  sl := &secureListener{
+    Listener: l,
+    sec:      sec,
+    conns:    make(chan manet.Conn),
+    done:     make(chan struct{}),
+  }
+  go sl.acceptLoop()
+  return sl
+}
+
+func (l *secureListener) acceptLoop() {
+  var delay time.Duration // backoff of temporary errors
+  for {
+    c, err := l.Listener.Accept()
+    if err != nil {
+      var te interface{ Temporary() bool }
+      if !errors.As(err, &te) || !te.Temporary() {
+        l.fail(err)
+        return
+      }
+      if delay = 2 * delay; delay == 0 {
+        delay = 5 * time.Millisecond
+      } else if delay > time.Second {
+        delay = time.Second
+      }
+      select {
+      case <-time.After(delay):
+        continue
+      case <-l.done:
+        return
+      }
+    }
+    delay = 0
+
+    go func() {
+      c2, err := SecureConn(c, true, l.sec)
+      if err != nil {
+        return // dropped. SecureConn closed c.
+      }
+      select {
+      case l.conns <- c2:
+      case <-l.done:
+        c2.Close()
+      }
+    }()
+  }
+}
+
+func (l *secureListener) fail(err error) {
+  l.errOnce.Do(func() {
+    l.err = err
+    close(l.done)
+  })
+}
+
+func (l *secureListener) Accept() (manet.Conn, error) {
+  select {
+  case c := <-l.conns:
+    return c, nil
+  case <-l.done:
+    return nil, l.err
+  }
+}
+
+func (l *secureListener) Close() error {
+  err := l.Listener.Close()
+  l.fail(net.ErrClosed)
+  return err
+}
+
+// ListenSecure is Listen, with the conns secured with sec.
+func ListenSecure(laddr ma.Multiaddr, sec *Security) (Listener, error) {
+  l, err := manet.Listen(laddr)
+  if err != nil {
+    return nil, err
+  }
+  if sec != nil {
+    l = newSecureListener(l, sec)
+  }
+  return &smuxListener{l}, nil
+}
+
+// DialSecure is Dial, with the conn secured with sec.
+func DialSecure(raddr ma.Multiaddr, sec *Security) (Conn, error) {
+  c, err := manet.Dial(raddr)
+  if err != nil {
+    return nil, err
+  }
+  c2, err := SecureConn(c, false, sec)
+  if err != nil {
+    return nil, err
+  }
+  return XtpCtlConn(c2, false)
+}
+
+// TLSFiles are the settings of a Security, as files (and pins) given in
+// flags or config files.
+type TLSFiles struct {
+  Cert       string   // PEM certificate (chain) to present
+  Key        string   // PEM private key of Cert
+  CA         string   // PEM CA certificates to verify the peer with. Servers then require client certificates.
+  Pins       []string // base64 KeyPins of the keys the peer may have
+  ServerName string   // name to verify the server's certificate against, for clients
+}
+
+// IsSet returns whether f configures anything, i.e. TLS is on.
+func (f TLSFiles) IsSet() bool {
+  return f.Cert != "" || f.Key != "" || f.CA != "" || len(f.Pins) > 0 || f.ServerName != ""
+}
+
+// Security loads the files, into the Security of a server or a client.
+// It returns nil if f is not set.
+func (f TLSFiles) Security(server bool) (*Security, error) {
+  if !f.IsSet() {
+    return nil, nil
+  }
+
+  cfg := &tls.Config{ServerName: f.ServerName}
+  if f.Cert != "" || f.Key != "" {
+    cert, err := tls.LoadX509KeyPair(f.Cert, f.Key)
+    if err != nil {
+      return nil, fmt.Errorf("tls: loading certificate: %s", err)
+    }
+    cfg.Certificates = []tls.Certificate{cert}
+  } else if server {
+    return nil, errors.New("tls: servers need a certificate and key")
+  }
+
+  if f.CA != "" {
+    pem, err := os.ReadFile(f.CA)
+    if err != nil {
+      return nil, fmt.Errorf("tls: %s", err)
+    }
+    pool := x509.NewCertPool()
+    if !pool.AppendCertsFromPEM(pem) {
+      return nil, fmt.Errorf("tls: no certificates in %s", f.CA)
+    }
+    if server {
+      cfg.ClientCAs = pool
+      cfg.ClientAuth = tls.RequireAndVerifyClientCert
+    } else {
+      cfg.RootCAs = pool
+    }
+  }
+
+  sec := &Security{TLS: cfg}
+  for _, p := range f.Pins {
+    pin, err := base64.StdEncoding.DecodeString(p)
+    if err != nil || len(pin) != sha256.Size {
+      return nil, fmt.Errorf("tls: invalid pin: %s", p)
+    }
+    sec.PinnedKeys = append(sec.PinnedKeys, pin)
+  }
+  return sec, nil
+}
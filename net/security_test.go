@@ -0,0 +1,255 @@
+package xtpctlnet_test
+
+import (
+  "crypto/ecdsa"
+  "crypto/elliptic"
+  "crypto/rand"
+  "crypto/tls"
+  "crypto/x509"
+  "crypto/x509/pkix"
+  "encoding/base64"
+  "encoding/pem"
+  "errors"
+  "math/big"
+  "net"
+  "os"
+  "path/filepath"
+  "testing"
+  "time"
+
+  xnet "github.com/libp2p/go-xtp-ctl/net"
+  ma "github.com/multiformats/go-multiaddr"
+)
+
+// testCA issues certificates, valid for 127.0.0.1.
+type testCA struct {
+  cert *x509.Certificate
+  key  *ecdsa.PrivateKey
+  pool *x509.CertPool
+}
+
+func newTestCA(t *testing.T) *testCA {
+  key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+  if err != nil {
+    t.Fatal(err)
+  }
+  tmpl := &x509.Certificate{
+    SerialNumber:          big.NewInt(1),
+    Subject:               pkix.Name{CommonName: "test ca"},
+    NotBefore:             time.Now().Add(-time.Hour),
+    NotAfter:              time.Now().Add(time.Hour),
+    IsCA:                  true,
+    BasicConstraintsValid: true,
+    KeyUsage:              x509.KeyUsageCertSign,
+  }
+  der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+  if err != nil {
+    t.Fatal(err)
+  }
+  cert, err := x509.ParseCertificate(der)
+  if err != nil {
+    t.Fatal(err)
+  }
+  pool := x509.NewCertPool()
+  pool.AddCert(cert)
+  return &testCA{cert, key, pool}
+}
+
+func (ca *testCA) issue(t *testing.T, cn string) tls.Certificate {
+  key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+  if err != nil {
+    t.Fatal(err)
+  }
+  tmpl := &x509.Certificate{
+    SerialNumber: big.NewInt(time.Now().UnixNano()),
+    Subject:      pkix.Name{CommonName: cn},
+    NotBefore:    time.Now().Add(-time.Hour),
+    NotAfter:     time.Now().Add(time.Hour),
+    IPAddresses:  []net.IP{net.IPv4(127, 0, 0, 1)},
+    KeyUsage:     x509.KeyUsageDigitalSignature,
+    ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth, x509.ExtKeyUsageClientAuth},
+  }
+  der, err := x509.CreateCertificate(rand.Reader, tmpl, ca.cert, &key.PublicKey, ca.key)
+  if err != nil {
+    t.Fatal(err)
+  }
+  leaf, err := x509.ParseCertificate(der)
+  if err != nil {
+    t.Fatal(err)
+  }
+  return tls.Certificate{Certificate: [][]byte{der}, PrivateKey: key, Leaf: leaf}
+}
+
+// files writes cert, and the CA, to PEM files for TLSFiles.
+func (ca *testCA) files(t *testing.T, cert tls.Certificate) xnet.TLSFiles {
+  dir := t.TempDir()
+  write := func(name, typ string, der []byte) string {
+    path := filepath.Join(dir, name)
+    if err := os.WriteFile(path, pem.EncodeToMemory(&pem.Block{Type: typ, Bytes: der}), 0600); err != nil {
+      t.Fatal(err)
+    }
+    return path
+  }
+  key, err := x509.MarshalPKCS8PrivateKey(cert.PrivateKey)
+  if err != nil {
+    t.Fatal(err)
+  }
+  return xnet.TLSFiles{
+    Cert: write("cert.pem", "CERTIFICATE", cert.Certificate[0]),
+    Key:  write("key.pem", "PRIVATE KEY", key),
+    CA:   write("ca.pem", "CERTIFICATE", ca.cert.Raw),
+  }
+}
+
+// secureListener listens with server, and returns the conns it accepts.
+func secureListener(t *testing.T, server *xnet.Security) (ma.Multiaddr, <-chan xnet.Conn) {
+  l, err := xnet.ListenSecure(ma.StringCast("/ip4/127.0.0.1/tcp/0"), server)
+  if err != nil {
+    t.Fatal(err)
+  }
+  t.Cleanup(func() { l.Close() })
+
+  conns := make(chan xnet.Conn, 10)
+  go func() {
+    for {
+      c, err := l.Accept()
+      if err != nil {
+        return
+      }
+      t.Cleanup(func() { c.Close() })
+      conns <- c
+    }
+  }()
+  return l.Multiaddr(), conns
+}
+
+// connect dials addr with client, and returns the server's end of the
+// conn, or the error of whichever end refused it.
+func connect(t *testing.T, addr ma.Multiaddr, conns <-chan xnet.Conn, client *xnet.Security) (xnet.Conn, error) {
+  c, err := xnet.DialSecure(addr, client)
+  if err != nil {
+    return nil, err
+  }
+  t.Cleanup(func() { c.Close() })
+
+  // with TLS 1.3, clients finish their handshake before the server checks
+  // their certificate: a refused client sees its conn fail.
+  failed := make(chan error, 1)
+  go func() {
+    s, err := c.Dial()
+    if err == nil {
+      _, err = s.Read(make([]byte, 1))
+    }
+    failed <- err
+  }()
+  select {
+  case sc := <-conns:
+    return sc, nil
+  case err := <-failed:
+    return nil, err
+  case <-time.After(5 * time.Second):
+    t.Fatal("neither end finished or refused the handshake")
+    return nil, nil
+  }
+}
+
+func TestSecurityCA(t *testing.T) {
+  ca := newTestCA(t)
+  addr, conns := secureListener(t, &xnet.Security{TLS: &tls.Config{
+    Certificates: []tls.Certificate{ca.issue(t, "server")},
+  }})
+
+  if _, err := connect(t, addr, conns, &xnet.Security{TLS: &tls.Config{RootCAs: ca.pool}}); err != nil {
+    t.Fatal("client of the CA:", err)
+  }
+  var unknown x509.UnknownAuthorityError
+  if _, err := connect(t, addr, conns, &xnet.Security{TLS: &tls.Config{RootCAs: newTestCA(t).pool}}); !errors.As(err, &unknown) {
+    t.Fatal("client of another CA: expected an unknown authority, got", err)
+  }
+}
+
+func TestSecurityMutualTLS(t *testing.T) {
+  ca := newTestCA(t)
+  server, err := ca.files(t, ca.issue(t, "server")).Security(true)
+  if err != nil {
+    t.Fatal(err)
+  }
+  if server.TLS.ClientAuth != tls.RequireAndVerifyClientCert {
+    t.Fatal("a server with a CA does not require client certificates:", server.TLS.ClientAuth)
+  }
+  addr, conns := secureListener(t, server)
+
+  client, err := ca.files(t, ca.issue(t, "alice")).Security(false)
+  if err != nil {
+    t.Fatal(err)
+  }
+  sc, err := connect(t, addr, conns, client)
+  if err != nil {
+    t.Fatal("client of the CA:", err)
+  }
+  if cert, ok := xnet.PeerCertificate(sc); !ok || cert.Subject.CommonName != "alice" {
+    t.Fatal("the server did not verify the client's certificate:", cert, ok)
+  }
+
+  noCert := &xnet.Security{TLS: &tls.Config{RootCAs: ca.pool}}
+  if _, err := connect(t, addr, conns, noCert); err == nil {
+    t.Fatal("a client without a certificate connected")
+  }
+  other := newTestCA(t)
+  otherCert := &xnet.Security{TLS: &tls.Config{RootCAs: ca.pool, Certificates: []tls.Certificate{other.issue(t, "alice")}}}
+  if _, err := connect(t, addr, conns, otherCert); err == nil {
+    t.Fatal("a client with a certificate of another CA connected")
+  }
+}
+
+func TestSecurityPins(t *testing.T) {
+  scert, ccert, other := selfSigned(t), selfSigned(t), selfSigned(t)
+  addr, conns := secureListener(t, &xnet.Security{
+    TLS:        &tls.Config{Certificates: []tls.Certificate{scert}},
+    PinnedKeys: [][]byte{xnet.KeyPin(ccert.Leaf)},
+  })
+
+  pinned := &xnet.Security{
+    TLS:        &tls.Config{Certificates: []tls.Certificate{ccert}},
+    PinnedKeys: [][]byte{xnet.KeyPin(scert.Leaf)},
+  }
+  sc, err := connect(t, addr, conns, pinned)
+  if err != nil {
+    t.Fatal("pinned client:", err)
+  }
+  // pinned certificates verify the client, though they have no chains.
+  if cert, ok := xnet.PeerCertificate(sc); !ok || !cert.Equal(ccert.Leaf) {
+    t.Fatal("the server did not verify the pinned client's certificate:", cert, ok)
+  }
+
+  // the client pins another server key.
+  wrongServer := &xnet.Security{
+    TLS:        &tls.Config{Certificates: []tls.Certificate{ccert}},
+    PinnedKeys: [][]byte{xnet.KeyPin(other.Leaf)},
+  }
+  if _, err := connect(t, addr, conns, wrongServer); !errors.Is(err, xnet.ErrKeyNotPinned) {
+    t.Fatal("pin mismatch: expected ErrKeyNotPinned, got", err)
+  }
+
+  // the server does not pin the client's key.
+  wrongClient := &xnet.Security{
+    TLS:        &tls.Config{Certificates: []tls.Certificate{other}},
+    PinnedKeys: [][]byte{xnet.KeyPin(scert.Leaf)},
+  }
+  if _, err := connect(t, addr, conns, wrongClient); err == nil {
+    t.Fatal("a client with an unpinned key connected")
+  }
+}
+
+func TestTLSFilesPins(t *testing.T) {
+  pin := base64.StdEncoding.EncodeToString(xnet.KeyPin(selfSigned(t).Leaf))
+  sec, err := xnet.TLSFiles{Pins: []string{pin}}.Security(false)
+  if err != nil || len(sec.PinnedKeys) != 1 {
+    t.Fatal(sec, err)
+  }
+  for _, bad := range []string{"not base64!", base64.StdEncoding.EncodeToString([]byte("short"))} {
+    if _, err := (xnet.TLSFiles{Pins: []string{bad}}).Security(false); err == nil {
+      t.Errorf("pin %q: expected an error", bad)
+    }
+  }
+}
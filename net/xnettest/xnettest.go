@@ -0,0 +1,660 @@
+// Package xnettest is a conformance suite for xnet.Transport implementations.
+// It checks the contracts documented in the xnet interfaces. Use it from a
+// transport's tests:
+//
+//   func TestTCP(t *testing.T) {
+//     f := func() xnet.Transport { return &xtpimpls.TCPTransport{} }
+//     xnettest.SubtestAll(t, f, ma.StringCast("/ip4/127.0.0.1/tcp/0"))
+//   }
+//
+package xnettest
+
+import (
+  "bytes"
+  "errors"
+  "fmt"
+  "io"
+  "io/ioutil"
+  "os"
+  "sync"
+  "testing"
+  "time"
+
+  ma "github.com/multiformats/go-multiaddr"
+  xnet "github.com/libp2p/go-xtp-ctl/net"
+)
+
+// Timeout bounds every blocking operation in the suite, so a broken
+// transport fails instead of hanging.
+var Timeout = 10 * time.Second
+
+// Subtest is one conformance test. f returns a fresh transport, and laddr
+// is an address the transport can listen on (or make a Dialer with).
+type Subtest func(t *testing.T, f func() xnet.Transport, laddr ma.Multiaddr)
+
+// Subtests are all the conformance tests, by name.
+var Subtests = []struct {
+  Name string
+  Test Subtest
+}{
+  {"Code", SubtestCode},
+  {"ListenDialAccept", SubtestListenDialAccept},
+  {"Multiaddrs", SubtestMultiaddrs},
+  {"StreamReadWrite", SubtestStreamReadWrite},
+  {"StreamClose", SubtestStreamClose},
+  {"StreamHalfClose", SubtestStreamHalfClose},
+  {"StreamReset", SubtestStreamReset},
+  {"ConcurrentStreams", SubtestConcurrentStreams},
+  {"ListenerCloseUnblocksAccept", SubtestListenerCloseUnblocksAccept},
+  {"ConnCloseUnblocksAccept", SubtestConnCloseUnblocksAccept},
+  {"ConnDeadline", SubtestConnDeadline},
+  {"Dialer", SubtestDialer},
+  {"Errors", SubtestErrors},
+}
+
+// SubtestAll runs all the conformance tests against the transports f returns.
+func SubtestAll(t *testing.T, f func() xnet.Transport, laddr ma.Multiaddr) {
+  for _, st := range Subtests {
+    st := st
+    t.Run(st.Name, func(t *testing.T) {
+      st.Test(t, f, laddr)
+    })
+  }
+}
+
+// SubtestCode checks that the transport has a multiaddr protocol code.
+func SubtestCode(t *testing.T, f func() xnet.Transport, laddr ma.Multiaddr) {
+  tpt := f()
+  defer tpt.Close()
+
+  code := tpt.Code()
+  if len(code) < 2 || code[0] != '/' {
+    t.Fatalf("bad transport code: %q", code)
+  }
+}
+
+// SubtestListenDialAccept checks that a dialed conn is accepted by the
+// listener, and that both ends open streams.
+func SubtestListenDialAccept(t *testing.T, f func() xnet.Transport, laddr ma.Multiaddr) {
+  tpt := f()
+  defer tpt.Close()
+
+  c1, c2, l := connPair(t, tpt, laddr)
+  defer l.Close()
+  defer c1.Close()
+  defer c2.Close()
+
+  // streams both ways.
+  streamPair(t, c1, c2)
+  streamPair(t, c2, c1)
+}
+
+// SubtestMultiaddrs checks the listener's and conns' addresses.
+func SubtestMultiaddrs(t *testing.T, f func() xnet.Transport, laddr ma.Multiaddr) {
+  tpt := f()
+  defer tpt.Close()
+
+  c1, c2, l := connPair(t, tpt, laddr)
+  defer l.Close()
+  defer c1.Close()
+  defer c2.Close()
+
+  lma := l.Multiaddr()
+  if !c1.RemoteMultiaddr().Equal(lma) {
+    t.Errorf("dialed conn remote addr %s != listener addr %s", c1.RemoteMultiaddr(), lma)
+  }
+  if !c2.LocalMultiaddr().Equal(lma) {
+    t.Errorf("accepted conn local addr %s != listener addr %s", c2.LocalMultiaddr(), lma)
+  }
+  if c1.LocalMultiaddr() == nil || c2.RemoteMultiaddr() == nil {
+    t.Errorf("conn missing addrs: %v %v", c1.LocalMultiaddr(), c2.RemoteMultiaddr())
+  }
+
+  s1, s2 := streamPair(t, c1, c2)
+  defer s1.Close()
+  defer s2.Close()
+  if s1.Conn() != c1 {
+    t.Error("stream.Conn() is not the conn it was dialed on")
+  }
+  if s2.Conn() != c2 {
+    t.Error("stream.Conn() is not the conn it was accepted on")
+  }
+}
+
+// SubtestStreamReadWrite checks that data flows both ways on a stream,
+// unchanged and in order.
+func SubtestStreamReadWrite(t *testing.T, f func() xnet.Transport, laddr ma.Multiaddr) {
+  tpt := f()
+  defer tpt.Close()
+
+  c1, c2, l := connPair(t, tpt, laddr)
+  defer l.Close()
+  defer c1.Close()
+  defer c2.Close()
+
+  s1, s2 := streamPair(t, c1, c2)
+  defer s1.Close()
+  defer s2.Close()
+
+  for i := 0; i < 10; i++ {
+    checkPipe(t, s1, s2, payload(i, 1<<10))
+    checkPipe(t, s2, s1, payload(i, 1<<10))
+  }
+
+  // large writes get through whole.
+  checkPipe(t, s1, s2, payload(42, 1<<20))
+}
+
+// SubtestStreamClose checks that closing a stream ends the other side's
+// reads with io.EOF, after the data written before the close.
+func SubtestStreamClose(t *testing.T, f func() xnet.Transport, laddr ma.Multiaddr) {
+  tpt := f()
+  defer tpt.Close()
+
+  c1, c2, l := connPair(t, tpt, laddr)
+  defer l.Close()
+  defer c1.Close()
+  defer c2.Close()
+
+  s1, s2 := streamPair(t, c1, c2)
+  defer s2.Close()
+
+  msg := payload(7, 1<<10)
+  if _, err := s1.Write(msg); err != nil {
+    t.Fatal(err)
+  }
+  if err := s1.Close(); err != nil {
+    t.Fatal(err)
+  }
+
+  var buf []byte
+  err := within(t, "reading to EOF", func() error {
+    var err error
+    buf, err = ioutil.ReadAll(s2)
+    return err
+  })
+  if err != nil {
+    t.Fatal("read after remote close:", err)
+  }
+  if !bytes.Equal(buf, msg) {
+    t.Fatalf("read %d bytes before EOF, expected %d", len(buf), len(msg))
+  }
+
+  if _, err := s1.Write(msg); err == nil {
+    t.Error("write on a closed stream succeeded")
+  }
+}
+
+// SubtestStreamHalfClose checks that after CloseWrite, the other side
+// reads io.EOF, and can still reply.
+func SubtestStreamHalfClose(t *testing.T, f func() xnet.Transport, laddr ma.Multiaddr) {
+  tpt := f()
+  defer tpt.Close()
+
+  c1, c2, l := connPair(t, tpt, laddr)
+  defer l.Close()
+  defer c1.Close()
+  defer c2.Close()
+
+  s1, s2 := streamPair(t, c1, c2)
+  defer s1.Close()
+  defer s2.Close()
+
+  if _, ok := s1.(xnet.HalfCloseStream); !ok {
+    t.Skip("stream has no half-close")
+  }
+
+  msg := payload(9, 1<<10)
+  if _, err := s1.Write(msg); err != nil {
+    t.Fatal(err)
+  }
+  if err := xnet.CloseWrite(s1); err != nil {
+    t.Fatal("CloseWrite:", err)
+  }
+
+  var buf []byte
+  err := within(t, "reading to EOF", func() error {
+    var err error
+    buf, err = ioutil.ReadAll(s2)
+    return err
+  })
+  if err != nil {
+    t.Fatal("read after remote CloseWrite:", err)
+  }
+  if !bytes.Equal(buf, msg) {
+    t.Fatalf("read %d bytes before EOF, expected %d", len(buf), len(msg))
+  }
+  if _, err := s1.Write(msg); err == nil {
+    t.Error("write after CloseWrite succeeded")
+  }
+
+  // the reply still gets through, up to the other side's own EOF.
+  reply := payload(10, 1<<10)
+  if _, err := s2.Write(reply); err != nil {
+    t.Fatal("reply:", err)
+  }
+  if err := xnet.CloseWrite(s2); err != nil {
+    t.Fatal("CloseWrite:", err)
+  }
+  err = within(t, "reading the reply", func() error {
+    var err error
+    buf, err = ioutil.ReadAll(s1)
+    return err
+  })
+  if err != nil {
+    t.Fatal("read of the reply:", err)
+  }
+  if !bytes.Equal(buf, reply) {
+    t.Fatalf("read %d bytes of the reply, expected %d", len(buf), len(reply))
+  }
+}
+
+// SubtestStreamReset checks that Reset ends the other side's reads.
+func SubtestStreamReset(t *testing.T, f func() xnet.Transport, laddr ma.Multiaddr) {
+  tpt := f()
+  defer tpt.Close()
+
+  c1, c2, l := connPair(t, tpt, laddr)
+  defer l.Close()
+  defer c1.Close()
+  defer c2.Close()
+
+  s1, s2 := streamPair(t, c1, c2)
+  defer s2.Close()
+
+  if _, ok := s1.(xnet.ResetStream); !ok {
+    t.Skip("stream has no reset")
+  }
+  if err := xnet.Reset(s1); err != nil {
+    t.Fatal("Reset:", err)
+  }
+
+  // io.EOF or an error: either way, reads must not hang.
+  within(t, "read after remote Reset", func() error {
+    _, err := ioutil.ReadAll(s2)
+    return err
+  })
+  if _, err := s1.Write([]byte{1}); err == nil {
+    t.Error("write after Reset succeeded")
+  }
+}
+
+// SubtestConcurrentStreams checks that many streams on one conn carry
+// their data independently.
+func SubtestConcurrentStreams(t *testing.T, f func() xnet.Transport, laddr ma.Multiaddr) {
+  tpt := f()
+  defer tpt.Close()
+
+  c1, c2, l := connPair(t, tpt, laddr)
+  defer l.Close()
+  defer c1.Close()
+  defer c2.Close()
+
+  const n = 20
+
+  // echo every accepted stream.
+  go func() {
+    for {
+      s, err := c2.Accept()
+      if err != nil {
+        return
+      }
+      go func() {
+        defer s.Close()
+        io.Copy(s, s)
+      }()
+    }
+  }()
+
+  var wg sync.WaitGroup
+  errs := make(chan error, n)
+  for i := 0; i < n; i++ {
+    wg.Add(1)
+    go func(i int) {
+      defer wg.Done()
+      errs <- echo(c1, payload(i, 1<<12))
+    }(i)
+  }
+
+  err := within(t, "concurrent streams", func() error {
+    wg.Wait()
+    close(errs)
+    for err := range errs {
+      if err != nil {
+        return err
+      }
+    }
+    return nil
+  })
+  if err != nil {
+    t.Fatal(err)
+  }
+}
+
+// SubtestListenerCloseUnblocksAccept checks that closing a listener
+// unblocks its pending Accept, and fails later ones.
+func SubtestListenerCloseUnblocksAccept(t *testing.T, f func() xnet.Transport, laddr ma.Multiaddr) {
+  tpt := f()
+  defer tpt.Close()
+
+  l, err := tpt.Listen(laddr)
+  if err != nil {
+    t.Fatal(err)
+  }
+
+  errc := make(chan error, 1)
+  go func() {
+    c, err := l.Accept()
+    if c != nil {
+      c.Close()
+    }
+    errc <- err
+  }()
+
+  time.Sleep(50 * time.Millisecond) // let it block.
+  if err := l.Close(); err != nil {
+    t.Fatal(err)
+  }
+
+  select {
+  case err := <-errc:
+    if err == nil {
+      t.Fatal("Accept on a closed listener succeeded")
+    }
+  case <-time.After(Timeout):
+    t.Fatal("Close did not unblock Accept")
+  }
+
+  if err := within(t, "Accept after Close", func() error {
+    _, err := l.Accept()
+    return err
+  }); err == nil {
+    t.Fatal("Accept after Close succeeded")
+  }
+}
+
+// SubtestConnCloseUnblocksAccept checks that closing a conn unblocks its
+// pending stream Accept.
+func SubtestConnCloseUnblocksAccept(t *testing.T, f func() xnet.Transport, laddr ma.Multiaddr) {
+  tpt := f()
+  defer tpt.Close()
+
+  c1, c2, l := connPair(t, tpt, laddr)
+  defer l.Close()
+  defer c2.Close()
+
+  errc := make(chan error, 1)
+  go func() {
+    s, err := c1.Accept()
+    if s != nil {
+      s.Close()
+    }
+    errc <- err
+  }()
+
+  time.Sleep(50 * time.Millisecond) // let it block.
+  if err := c1.Close(); err != nil {
+    t.Fatal(err)
+  }
+
+  select {
+  case err := <-errc:
+    if err == nil {
+      t.Fatal("Accept on a closed conn succeeded")
+    }
+  case <-time.After(Timeout):
+    t.Fatal("Close did not unblock Accept")
+  }
+
+  if _, err := c1.Dial(); err == nil {
+    t.Fatal("Dial on a closed conn succeeded")
+  }
+}
+
+// SubtestConnDeadline checks that a conn's deadline fails pending and
+// later Accepts with a timeout, and that clearing it works. Conns without
+// deadlines skip it.
+func SubtestConnDeadline(t *testing.T, f func() xnet.Transport, laddr ma.Multiaddr) {
+  tpt := f()
+  defer tpt.Close()
+
+  c1, c2, l := connPair(t, tpt, laddr)
+  defer l.Close()
+  defer c1.Close()
+  defer c2.Close()
+
+  if err := xnet.SetConnDeadline(c1, time.Time{}); err == xnet.ErrNoDeadlines {
+    t.Skip("conn has no deadlines")
+  }
+
+  // a deadline set while Accept blocks.
+  errc := make(chan error, 1)
+  go func() {
+    s, err := c1.Accept()
+    if s != nil {
+      s.Close()
+    }
+    errc <- err
+  }()
+  time.Sleep(50 * time.Millisecond) // let it block.
+  start := time.Now()
+  xnet.SetConnDeadline(c1, start.Add(50*time.Millisecond))
+
+  select {
+  case err := <-errc:
+    if !errors.Is(err, os.ErrDeadlineExceeded) {
+      t.Fatal("Accept past the deadline:", err)
+    }
+  case <-time.After(Timeout):
+    t.Fatal("the deadline did not unblock Accept")
+  }
+
+  // past the deadline.
+  if _, err := c1.Accept(); !errors.Is(err, os.ErrDeadlineExceeded) {
+    t.Fatal("Accept after the deadline:", err)
+  }
+
+  // no deadline: the conn still works.
+  xnet.SetConnDeadline(c1, time.Time{})
+  s2, s1 := streamPair(t, c2, c1)
+  defer s1.Close()
+  defer s2.Close()
+  checkPipe(t, s1, s2, payload(2, 1<<10))
+}
+
+// SubtestDialer checks conns dialed through a Dialer. Transports without
+// dialers skip it.
+func SubtestDialer(t *testing.T, f func() xnet.Transport, laddr ma.Multiaddr) {
+  tpt := f()
+  defer tpt.Close()
+
+  l, err := tpt.Listen(laddr)
+  if err != nil {
+    t.Fatal(err)
+  }
+  defer l.Close()
+
+  d, err := tpt.Dialer(laddr)
+  if err != nil || d == nil {
+    t.Skip("transport has no dialers:", err)
+  }
+  defer d.Close()
+
+  if d.Multiaddr() == nil {
+    t.Error("dialer has no multiaddr")
+  }
+
+  c1, c2 := dialAccept(t, l, d.Dial)
+  defer c1.Close()
+  defer c2.Close()
+
+  s1, s2 := streamPair(t, c1, c2)
+  defer s1.Close()
+  defer s2.Close()
+  checkPipe(t, s1, s2, payload(1, 1<<10))
+}
+
+// SubtestErrors checks that operations on closed or missing things fail.
+func SubtestErrors(t *testing.T, f func() xnet.Transport, laddr ma.Multiaddr) {
+  tpt := f()
+  defer tpt.Close()
+
+  l, err := tpt.Listen(laddr)
+  if err != nil {
+    t.Fatal(err)
+  }
+  lma := l.Multiaddr()
+
+  // can't listen twice on the same address.
+  if l2, err := tpt.Listen(lma); err == nil {
+    l2.Close()
+    l.Close()
+    t.Fatal("listened twice on", lma)
+  }
+
+  l.Close()
+
+  // nobody is listening anymore.
+  err = within(t, "dialing a closed listener", func() error {
+    c, err := tpt.Dial(lma)
+    if err == nil {
+      c.Close()
+    }
+    return err
+  })
+  if err == nil {
+    t.Fatal("dialing a closed listener succeeded")
+  }
+}
+
+// connPair listens on laddr, and returns a dialed and an accepted conn.
+func connPair(t *testing.T, tpt xnet.Transport, laddr ma.Multiaddr) (xnet.Conn, xnet.Conn, xnet.Listener) {
+  l, err := tpt.Listen(laddr)
+  if err != nil {
+    t.Fatal(err)
+  }
+  c1, c2 := dialAccept(t, l, tpt.Dial)
+  return c1, c2, l
+}
+
+// dialAccept dials l with dial, and returns both ends.
+func dialAccept(t *testing.T, l xnet.Listener, dial func(ma.Multiaddr) (xnet.Conn, error)) (xnet.Conn, xnet.Conn) {
+  accepted := make(chan xnet.Conn, 1)
+  errc := make(chan error, 1)
+  go func() {
+    c, err := l.Accept()
+    if err != nil {
+      errc <- err
+      return
+    }
+    accepted <- c
+  }()
+
+  c1, err := dial(l.Multiaddr())
+  if err != nil {
+    l.Close()
+    t.Fatal("dial:", err)
+  }
+
+  select {
+  case c2 := <-accepted:
+    return c1, c2
+  case err := <-errc:
+    c1.Close()
+    l.Close()
+    t.Fatal("accept:", err)
+  case <-time.After(Timeout):
+    c1.Close()
+    l.Close()
+    t.Fatal("accept timed out")
+  }
+  return nil, nil
+}
+
+// streamPair opens a stream on c1, and accepts it on c2. Streams may only
+// show up on the other side once written to, so it writes one byte.
+func streamPair(t *testing.T, c1, c2 xnet.Conn) (xnet.Stream, xnet.Stream) {
+  s1, err := c1.Dial()
+  if err != nil {
+    t.Fatal("stream dial:", err)
+  }
+  if _, err := s1.Write([]byte{0}); err != nil {
+    t.Fatal("stream write:", err)
+  }
+
+  var s2 xnet.Stream
+  err = within(t, "stream accept", func() error {
+    var err error
+    if s2, err = c2.Accept(); err != nil {
+      return err
+    }
+    _, err = io.ReadFull(s2, make([]byte, 1))
+    return err
+  })
+  if err != nil {
+    s1.Close()
+    t.Fatal("stream accept:", err)
+  }
+  return s1, s2
+}
+
+// checkPipe writes msg to w, and checks that r reads it back.
+func checkPipe(t *testing.T, w, r io.ReadWriter, msg []byte) {
+  go w.Write(msg)
+
+  buf := make([]byte, len(msg))
+  err := within(t, "stream read", func() error {
+    _, err := io.ReadFull(r, buf)
+    return err
+  })
+  if err != nil {
+    t.Fatal(err)
+  }
+  if !bytes.Equal(buf, msg) {
+    t.Fatal("read different data than written")
+  }
+}
+
+// echo sends msg on a new stream on c, and checks it comes back.
+func echo(c xnet.Conn, msg []byte) error {
+  s, err := c.Dial()
+  if err != nil {
+    return err
+  }
+  defer s.Close()
+
+  go s.Write(msg)
+  buf := make([]byte, len(msg))
+  if _, err := io.ReadFull(s, buf); err != nil {
+    return err
+  }
+  if !bytes.Equal(buf, msg) {
+    return fmt.Errorf("echo returned different data (%d bytes)", len(msg))
+  }
+  return nil
+}
+
+// within runs f, failing the test if it takes longer than Timeout.
+func within(t *testing.T, what string, f func() error) error {
+  errc := make(chan error, 1)
+  go func() {
+    errc <- f()
+  }()
+
+  select {
+  case err := <-errc:
+    return err
+  case <-time.After(Timeout):
+    t.Fatalf("%s timed out", what)
+    return nil
+  }
+}
+
+// payload returns n bytes of data, different for every seed.
+func payload(seed, n int) []byte {
+  b := make([]byte, n)
+  for i := range b {
+    b[i] = byte(seed + i*7)
+  }
+  return b
+}
@@ -16,8 +16,8 @@ type ListReqTypes struct {
 }
 
 func (m *ListReq) TypesRequested() (t ListReqTypes) {
-  for typ := range m.Types {
-    switch TType(typ) {
+  for _, typ := range m.Types {
+    switch typ {
     case TType_TTypeNil: // ignore
     case TType_TTypeTransport:
       t.Transports = true
@@ -34,6 +34,31 @@ func (m *ListReq) TypesRequested() (t ListReqTypes) {
   return t
 }
 
+// Watches returns whether the watch covers descriptors of type typ, on
+// transport tid.
+func (m *WatchReq) Watches(typ TType, tid int64) bool {
+  return (len(m.Types) == 0 || hasType(m.Types, typ)) &&
+    (len(m.TransportIds) == 0 || hasId(m.TransportIds, tid))
+}
+
+func hasType(ts []TType, t TType) bool {
+  for _, t2 := range ts {
+    if t2 == t {
+      return true
+    }
+  }
+  return false
+}
+
+func hasId(ids []int64, id int64) bool {
+  for _, id2 := range ids {
+    if id2 == id {
+      return true
+    }
+  }
+  return false
+}
+
 func Mk_ListRes_Item(id int64, typ TType, val proto.Message) (*ListRes_Item, error) {
   buf, err := proto.Marshal(val)
   if err != nil {
@@ -66,6 +91,41 @@ func ListRes_Item_Stream(s *Stream) (*ListRes_Item, error) {
   return Mk_ListRes_Item(*s.Id, TType_TTypeStream, s)
 }
 
+// Res returns the response type matching request type t.
+// NoOp is answered with a NoOp. Other types return Null.
+func (t RPC_Type) Res() RPC_Type {
+  switch t {
+  case RPC_NoOp:
+    return RPC_NoOp
+  case RPC_ListReq:
+    return RPC_ListRes
+  case RPC_CloseReq:
+    return RPC_CloseRes
+  case RPC_ListenReq:
+    return RPC_ListenRes
+  case RPC_AcceptReq:
+    return RPC_AcceptRes
+  case RPC_DialerReq:
+    return RPC_DialerRes
+  case RPC_DialReq:
+    return RPC_DialRes
+  case RPC_HelloReq:
+    return RPC_HelloRes
+  case RPC_ShutdownReq:
+    return RPC_ShutdownRes
+  case RPC_WatchReq:
+    return RPC_WatchRes
+  case RPC_StatsReq:
+    return RPC_StatsRes
+  case RPC_AuthReq:
+    return RPC_AuthRes
+  case RPC_UsageReq:
+    return RPC_UsageRes
+  default:
+    return RPC_Null
+  }
+}
+
 // validators
 
 func (m *RPC) Valid() bool {
@@ -99,11 +159,28 @@ func (m *DialerRes) Valid() bool {
   return m.Dialer.Valid()
 }
 
+func (m *WatchRes) Valid() bool {
+  if m == nil || m.Event == nil {
+    return false
+  }
+  if *m.Event == WatchRes_Watching {
+    return true
+  }
+  return m.Item.Valid()
+}
+
+func (m *Stats) Valid() bool {
+  if m == nil || m.Id == nil {
+    return false
+  }
+  return *m.Id >= MinId
+}
+
 func (m *Transport) Valid() bool {
   if m == nil || m.Id == nil || m.Transport == nil {
     return false
   }
-  if *m.Id <= MinId || *m.Transport == "" {
+  if *m.Id < MinId || *m.Transport == "" {
     return false
   }
   return true
@@ -26,6 +26,20 @@ It has these top-level messages:
 	DialerRes
 	DialReq
 	DialRes
+	Hello
+	HelloReq
+	HelloRes
+	ShutdownReq
+	WatchReq
+	WatchRes
+	Stats
+	StatsReq
+	StatsRes
+	AuthReq
+	AuthRes
+	Usage
+	UsageReq
+	UsageRes
 */
 package xtp_ctl
 
@@ -90,6 +104,83 @@ func (x *TType) UnmarshalJSON(data []byte) error {
 }
 func (TType) EnumDescriptor() ([]byte, []int) { return fileDescriptorXtpCtl, []int{0} }
 
+// Error codes, for RPC.errCode.
+type ErrCode int32
+
+const (
+	ErrCode_ErrCodeUnknown         ErrCode = 0
+	ErrCode_ErrCodeUnknownRPC      ErrCode = 1
+	ErrCode_ErrCodeProtocol        ErrCode = 2
+	ErrCode_ErrCodeNotFound        ErrCode = 3
+	ErrCode_ErrCodeInvalidMessage  ErrCode = 4
+	ErrCode_ErrCodeHelloRequired   ErrCode = 5
+	ErrCode_ErrCodeVersionMismatch ErrCode = 6
+	ErrCode_ErrCodeAddrInUse       ErrCode = 7
+	ErrCode_ErrCodeConnRefused     ErrCode = 8
+	ErrCode_ErrCodeTimeout           ErrCode = 9
+	ErrCode_ErrCodeCanceled          ErrCode = 10
+	ErrCode_ErrCodeClosed            ErrCode = 11
+	ErrCode_ErrCodeUnsupported       ErrCode = 12
+	ErrCode_ErrCodeUnauthenticated   ErrCode = 13
+	ErrCode_ErrCodePermissionDenied  ErrCode = 14
+	ErrCode_ErrCodeResourceExhausted ErrCode = 15
+)
+
+var ErrCode_name = map[int32]string{
+	0:  "ErrCodeUnknown",
+	1:  "ErrCodeUnknownRPC",
+	2:  "ErrCodeProtocol",
+	3:  "ErrCodeNotFound",
+	4:  "ErrCodeInvalidMessage",
+	5:  "ErrCodeHelloRequired",
+	6:  "ErrCodeVersionMismatch",
+	7:  "ErrCodeAddrInUse",
+	8:  "ErrCodeConnRefused",
+	9:  "ErrCodeTimeout",
+	10: "ErrCodeCanceled",
+	11: "ErrCodeClosed",
+	12: "ErrCodeUnsupported",
+	13: "ErrCodeUnauthenticated",
+	14: "ErrCodePermissionDenied",
+	15: "ErrCodeResourceExhausted",
+}
+var ErrCode_value = map[string]int32{
+	"ErrCodeUnknown":           0,
+	"ErrCodeUnknownRPC":        1,
+	"ErrCodeProtocol":          2,
+	"ErrCodeNotFound":          3,
+	"ErrCodeInvalidMessage":    4,
+	"ErrCodeHelloRequired":     5,
+	"ErrCodeVersionMismatch":   6,
+	"ErrCodeAddrInUse":         7,
+	"ErrCodeConnRefused":       8,
+	"ErrCodeTimeout":           9,
+	"ErrCodeCanceled":          10,
+	"ErrCodeClosed":            11,
+	"ErrCodeUnsupported":       12,
+	"ErrCodeUnauthenticated":   13,
+	"ErrCodePermissionDenied":  14,
+	"ErrCodeResourceExhausted": 15,
+}
+
+func (x ErrCode) Enum() *ErrCode {
+	p := new(ErrCode)
+	*p = x
+	return p
+}
+func (x ErrCode) String() string {
+	return proto.EnumName(ErrCode_name, int32(x))
+}
+func (x *ErrCode) UnmarshalJSON(data []byte) error {
+	value, err := proto.UnmarshalJSONEnum(ErrCode_value, data, "ErrCode")
+	if err != nil {
+		return err
+	}
+	*x = ErrCode(value)
+	return nil
+}
+func (ErrCode) EnumDescriptor() ([]byte, []int) { return fileDescriptorXtpCtl, []int{1} }
+
 type RPC_Type int32
 
 const (
@@ -113,6 +204,26 @@ const (
 	// Dialer.DialConn() or Conn.OpenStream()
 	RPC_DialReq RPC_Type = 12
 	RPC_DialRes RPC_Type = 13
+	// Session handshake. must be the first rpc of a session.
+	RPC_HelloReq RPC_Type = 14
+	RPC_HelloRes RPC_Type = 15
+	// Stream.CloseWrite(), Stream.CloseRead() or Stream.Reset()
+	RPC_ShutdownReq RPC_Type = 16
+	RPC_ShutdownRes RPC_Type = 17
+	// Watch descriptor lifecycle events. The server responds with a
+	// WatchRes per event, until the client closes the stream.
+	RPC_WatchReq RPC_Type = 18
+	RPC_WatchRes RPC_Type = 19
+	// Traffic statistics of descriptors
+	RPC_StatsReq RPC_Type = 20
+	RPC_StatsRes RPC_Type = 21
+	// Authenticate the client, after the Hello. Servers with a policy
+	// refuse other rpcs until then.
+	RPC_AuthReq RPC_Type = 22
+	RPC_AuthRes RPC_Type = 23
+	// What the client uses, against its limits
+	RPC_UsageReq RPC_Type = 24
+	RPC_UsageRes RPC_Type = 25
 )
 
 var RPC_Type_name = map[int32]string{
@@ -130,22 +241,46 @@ var RPC_Type_name = map[int32]string{
 	11: "DialerRes",
 	12: "DialReq",
 	13: "DialRes",
+	14: "HelloReq",
+	15: "HelloRes",
+	16: "ShutdownReq",
+	17: "ShutdownRes",
+	18: "WatchReq",
+	19: "WatchRes",
+	20: "StatsReq",
+	21: "StatsRes",
+	22: "AuthReq",
+	23: "AuthRes",
+	24: "UsageReq",
+	25: "UsageRes",
 }
 var RPC_Type_value = map[string]int32{
-	"Null":      0,
-	"NoOp":      1,
-	"ListReq":   2,
-	"ListRes":   3,
-	"CloseReq":  4,
-	"CloseRes":  5,
-	"ListenReq": 6,
-	"ListenRes": 7,
-	"AcceptReq": 8,
-	"AcceptRes": 9,
-	"DialerReq": 10,
-	"DialerRes": 11,
-	"DialReq":   12,
-	"DialRes":   13,
+	"Null":        0,
+	"NoOp":        1,
+	"ListReq":     2,
+	"ListRes":     3,
+	"CloseReq":    4,
+	"CloseRes":    5,
+	"ListenReq":   6,
+	"ListenRes":   7,
+	"AcceptReq":   8,
+	"AcceptRes":   9,
+	"DialerReq":   10,
+	"DialerRes":   11,
+	"DialReq":     12,
+	"DialRes":     13,
+	"HelloReq":    14,
+	"HelloRes":    15,
+	"ShutdownReq": 16,
+	"ShutdownRes": 17,
+	"WatchReq":    18,
+	"WatchRes":    19,
+	"StatsReq":    20,
+	"StatsRes":    21,
+	"AuthReq":     22,
+	"AuthRes":     23,
+	"UsageReq":    24,
+	"UsageRes":    25,
 }
 
 func (x RPC_Type) Enum() *RPC_Type {
@@ -170,6 +305,10 @@ type RPC struct {
 	Rpc              *RPC_Type `protobuf:"varint,1,opt,name=rpc,enum=RPC_Type" json:"rpc,omitempty"`
 	Message          []byte    `protobuf:"bytes,2,opt,name=message" json:"message,omitempty"`
 	Error            *string   `protobuf:"bytes,3,opt,name=error" json:"error,omitempty"`
+	ErrCode          *ErrCode  `protobuf:"varint,4,opt,name=errCode,enum=ErrCode" json:"errCode,omitempty"`
+	Timeout          *bool     `protobuf:"varint,5,opt,name=timeout" json:"timeout,omitempty"`
+	Temporary        *bool     `protobuf:"varint,6,opt,name=temporary" json:"temporary,omitempty"`
+	Traceparent      *string   `protobuf:"bytes,7,opt,name=traceparent" json:"traceparent,omitempty"`
 	XXX_unrecognized []byte    `json:"-"`
 }
 
@@ -199,6 +338,34 @@ func (m *RPC) GetError() string {
 	return ""
 }
 
+func (m *RPC) GetErrCode() ErrCode {
+	if m != nil && m.ErrCode != nil {
+		return *m.ErrCode
+	}
+	return ErrCode_ErrCodeUnknown
+}
+
+func (m *RPC) GetTimeout() bool {
+	if m != nil && m.Timeout != nil {
+		return *m.Timeout
+	}
+	return false
+}
+
+func (m *RPC) GetTemporary() bool {
+	if m != nil && m.Temporary != nil {
+		return *m.Temporary
+	}
+	return false
+}
+
+func (m *RPC) GetTraceparent() string {
+	if m != nil && m.Traceparent != nil {
+		return *m.Traceparent
+	}
+	return ""
+}
+
 // The types of things we use.
 type Transport struct {
 	Id               *int64  `protobuf:"varint,1,opt,name=id" json:"id,omitempty"`
@@ -385,6 +552,7 @@ type ListReq struct {
 	// include one per type we want.
 	// same TType multiple times is idempotent.
 	Types            []TType `protobuf:"varint,1,rep,name=types,enum=TType" json:"types,omitempty"`
+	Stats            *bool   `protobuf:"varint,2,opt,name=stats" json:"stats,omitempty"`
 	XXX_unrecognized []byte  `json:"-"`
 }
 
@@ -400,8 +568,16 @@ func (m *ListReq) GetTypes() []TType {
 	return nil
 }
 
+func (m *ListReq) GetStats() bool {
+	if m != nil && m.Stats != nil {
+		return *m.Stats
+	}
+	return false
+}
+
 type ListRes struct {
 	Items            []*ListRes_Item `protobuf:"bytes,1,rep,name=items" json:"items,omitempty"`
+	More             *bool           `protobuf:"varint,2,opt,name=more" json:"more,omitempty"`
 	XXX_unrecognized []byte          `json:"-"`
 }
 
@@ -417,10 +593,18 @@ func (m *ListRes) GetItems() []*ListRes_Item {
 	return nil
 }
 
+func (m *ListRes) GetMore() bool {
+	if m != nil && m.More != nil {
+		return *m.More
+	}
+	return false
+}
+
 type ListRes_Item struct {
 	Id               *int64 `protobuf:"varint,1,opt,name=id" json:"id,omitempty"`
 	Type             *TType `protobuf:"varint,2,opt,name=type,enum=TType" json:"type,omitempty"`
 	Value            []byte `protobuf:"bytes,3,opt,name=value" json:"value,omitempty"`
+	Stats            *Stats `protobuf:"bytes,4,opt,name=stats" json:"stats,omitempty"`
 	XXX_unrecognized []byte `json:"-"`
 }
 
@@ -450,6 +634,13 @@ func (m *ListRes_Item) GetValue() []byte {
 	return nil
 }
 
+func (m *ListRes_Item) GetStats() *Stats {
+	if m != nil {
+		return m.Stats
+	}
+	return nil
+}
+
 type CloseReq struct {
 	Id               *int64 `protobuf:"varint,1,opt,name=id" json:"id,omitempty"`
 	XXX_unrecognized []byte `json:"-"`
@@ -627,6 +818,632 @@ func (m *DialRes) GetStream() *Stream {
 	return nil
 }
 
+type Hello struct {
+	Version          *int64     `protobuf:"varint,1,opt,name=version" json:"version,omitempty"`
+	Impl             *string    `protobuf:"bytes,2,opt,name=impl" json:"impl,omitempty"`
+	Rpcs             []RPC_Type `protobuf:"varint,3,rep,name=rpcs,enum=RPC_Type" json:"rpcs,omitempty"`
+	MaxMessageSize   *int64     `protobuf:"varint,4,opt,name=maxMessageSize" json:"maxMessageSize,omitempty"`
+	Features         []string   `protobuf:"bytes,5,rep,name=features" json:"features,omitempty"`
+	XXX_unrecognized []byte     `json:"-"`
+}
+
+func (m *Hello) Reset()                    { *m = Hello{} }
+func (m *Hello) String() string            { return proto.CompactTextString(m) }
+func (*Hello) ProtoMessage()               {}
+func (*Hello) Descriptor() ([]byte, []int) { return fileDescriptorXtpCtl, []int{17} }
+
+func (m *Hello) GetVersion() int64 {
+	if m != nil && m.Version != nil {
+		return *m.Version
+	}
+	return 0
+}
+
+func (m *Hello) GetImpl() string {
+	if m != nil && m.Impl != nil {
+		return *m.Impl
+	}
+	return ""
+}
+
+func (m *Hello) GetRpcs() []RPC_Type {
+	if m != nil {
+		return m.Rpcs
+	}
+	return nil
+}
+
+func (m *Hello) GetMaxMessageSize() int64 {
+	if m != nil && m.MaxMessageSize != nil {
+		return *m.MaxMessageSize
+	}
+	return 0
+}
+
+func (m *Hello) GetFeatures() []string {
+	if m != nil {
+		return m.Features
+	}
+	return nil
+}
+
+type HelloReq struct {
+	Hello            *Hello `protobuf:"bytes,1,opt,name=hello" json:"hello,omitempty"`
+	Resume           []byte `protobuf:"bytes,2,opt,name=resume" json:"resume,omitempty"`
+	XXX_unrecognized []byte `json:"-"`
+}
+
+func (m *HelloReq) Reset()                    { *m = HelloReq{} }
+func (m *HelloReq) String() string            { return proto.CompactTextString(m) }
+func (*HelloReq) ProtoMessage()               {}
+func (*HelloReq) Descriptor() ([]byte, []int) { return fileDescriptorXtpCtl, []int{18} }
+
+func (m *HelloReq) GetHello() *Hello {
+	if m != nil {
+		return m.Hello
+	}
+	return nil
+}
+
+func (m *HelloReq) GetResume() []byte {
+	if m != nil {
+		return m.Resume
+	}
+	return nil
+}
+
+type HelloRes struct {
+	Hello            *Hello `protobuf:"bytes,1,opt,name=hello" json:"hello,omitempty"`
+	Session          []byte `protobuf:"bytes,2,opt,name=session" json:"session,omitempty"`
+	XXX_unrecognized []byte `json:"-"`
+}
+
+func (m *HelloRes) Reset()                    { *m = HelloRes{} }
+func (m *HelloRes) String() string            { return proto.CompactTextString(m) }
+func (*HelloRes) ProtoMessage()               {}
+func (*HelloRes) Descriptor() ([]byte, []int) { return fileDescriptorXtpCtl, []int{19} }
+
+func (m *HelloRes) GetHello() *Hello {
+	if m != nil {
+		return m.Hello
+	}
+	return nil
+}
+
+func (m *HelloRes) GetSession() []byte {
+	if m != nil {
+		return m.Session
+	}
+	return nil
+}
+
+type ShutdownReq_How int32
+
+const (
+	ShutdownReq_Write ShutdownReq_How = 1
+	ShutdownReq_Read  ShutdownReq_How = 2
+	ShutdownReq_Reset ShutdownReq_How = 3
+)
+
+var ShutdownReq_How_name = map[int32]string{
+	1: "Write",
+	2: "Read",
+	3: "Reset",
+}
+var ShutdownReq_How_value = map[string]int32{
+	"Write": 1,
+	"Read":  2,
+	"Reset": 3,
+}
+
+func (x ShutdownReq_How) Enum() *ShutdownReq_How {
+	p := new(ShutdownReq_How)
+	*p = x
+	return p
+}
+func (x ShutdownReq_How) String() string {
+	return proto.EnumName(ShutdownReq_How_name, int32(x))
+}
+func (x *ShutdownReq_How) UnmarshalJSON(data []byte) error {
+	value, err := proto.UnmarshalJSONEnum(ShutdownReq_How_value, data, "ShutdownReq_How")
+	if err != nil {
+		return err
+	}
+	*x = ShutdownReq_How(value)
+	return nil
+}
+func (ShutdownReq_How) EnumDescriptor() ([]byte, []int) { return fileDescriptorXtpCtl, []int{20, 0} }
+
+type ShutdownReq struct {
+	Id               *int64           `protobuf:"varint,1,opt,name=id" json:"id,omitempty"`
+	How              *ShutdownReq_How `protobuf:"varint,2,opt,name=how,enum=ShutdownReq_How" json:"how,omitempty"`
+	Written          *int64           `protobuf:"varint,3,opt,name=written" json:"written,omitempty"`
+	XXX_unrecognized []byte           `json:"-"`
+}
+
+func (m *ShutdownReq) Reset()                    { *m = ShutdownReq{} }
+func (m *ShutdownReq) String() string            { return proto.CompactTextString(m) }
+func (*ShutdownReq) ProtoMessage()               {}
+func (*ShutdownReq) Descriptor() ([]byte, []int) { return fileDescriptorXtpCtl, []int{20} }
+
+func (m *ShutdownReq) GetId() int64 {
+	if m != nil && m.Id != nil {
+		return *m.Id
+	}
+	return 0
+}
+
+func (m *ShutdownReq) GetHow() ShutdownReq_How {
+	if m != nil && m.How != nil {
+		return *m.How
+	}
+	return ShutdownReq_Write
+}
+
+func (m *ShutdownReq) GetWritten() int64 {
+	if m != nil && m.Written != nil {
+		return *m.Written
+	}
+	return 0
+}
+
+type WatchReq struct {
+	Types            []TType `protobuf:"varint,1,rep,name=types,enum=TType" json:"types,omitempty"`
+	TransportIds     []int64 `protobuf:"varint,2,rep,name=transportIds" json:"transportIds,omitempty"`
+	XXX_unrecognized []byte  `json:"-"`
+}
+
+func (m *WatchReq) Reset()                    { *m = WatchReq{} }
+func (m *WatchReq) String() string            { return proto.CompactTextString(m) }
+func (*WatchReq) ProtoMessage()               {}
+func (*WatchReq) Descriptor() ([]byte, []int) { return fileDescriptorXtpCtl, []int{21} }
+
+func (m *WatchReq) GetTypes() []TType {
+	if m != nil {
+		return m.Types
+	}
+	return nil
+}
+
+func (m *WatchReq) GetTransportIds() []int64 {
+	if m != nil {
+		return m.TransportIds
+	}
+	return nil
+}
+
+type WatchRes_Event int32
+
+const (
+	WatchRes_Watching WatchRes_Event = 1
+	WatchRes_Opened   WatchRes_Event = 2
+	WatchRes_Accepted WatchRes_Event = 3
+	WatchRes_Dialed   WatchRes_Event = 4
+	WatchRes_Closed   WatchRes_Event = 5
+	WatchRes_Error    WatchRes_Event = 6
+)
+
+var WatchRes_Event_name = map[int32]string{
+	1: "Watching",
+	2: "Opened",
+	3: "Accepted",
+	4: "Dialed",
+	5: "Closed",
+	6: "Error",
+}
+var WatchRes_Event_value = map[string]int32{
+	"Watching": 1,
+	"Opened":   2,
+	"Accepted": 3,
+	"Dialed":   4,
+	"Closed":   5,
+	"Error":    6,
+}
+
+func (x WatchRes_Event) Enum() *WatchRes_Event {
+	p := new(WatchRes_Event)
+	*p = x
+	return p
+}
+func (x WatchRes_Event) String() string {
+	return proto.EnumName(WatchRes_Event_name, int32(x))
+}
+func (x *WatchRes_Event) UnmarshalJSON(data []byte) error {
+	value, err := proto.UnmarshalJSONEnum(WatchRes_Event_value, data, "WatchRes_Event")
+	if err != nil {
+		return err
+	}
+	*x = WatchRes_Event(value)
+	return nil
+}
+func (WatchRes_Event) EnumDescriptor() ([]byte, []int) { return fileDescriptorXtpCtl, []int{22, 0} }
+
+type WatchRes struct {
+	Event            *WatchRes_Event `protobuf:"varint,1,opt,name=event,enum=WatchRes_Event" json:"event,omitempty"`
+	Item             *ListRes_Item   `protobuf:"bytes,2,opt,name=item" json:"item,omitempty"`
+	Error            *string         `protobuf:"bytes,3,opt,name=error" json:"error,omitempty"`
+	ErrCode          *ErrCode        `protobuf:"varint,4,opt,name=errCode,enum=ErrCode" json:"errCode,omitempty"`
+	XXX_unrecognized []byte          `json:"-"`
+}
+
+func (m *WatchRes) Reset()                    { *m = WatchRes{} }
+func (m *WatchRes) String() string            { return proto.CompactTextString(m) }
+func (*WatchRes) ProtoMessage()               {}
+func (*WatchRes) Descriptor() ([]byte, []int) { return fileDescriptorXtpCtl, []int{22} }
+
+func (m *WatchRes) GetEvent() WatchRes_Event {
+	if m != nil && m.Event != nil {
+		return *m.Event
+	}
+	return WatchRes_Watching
+}
+
+func (m *WatchRes) GetItem() *ListRes_Item {
+	if m != nil {
+		return m.Item
+	}
+	return nil
+}
+
+func (m *WatchRes) GetError() string {
+	if m != nil && m.Error != nil {
+		return *m.Error
+	}
+	return ""
+}
+
+func (m *WatchRes) GetErrCode() ErrCode {
+	if m != nil && m.ErrCode != nil {
+		return *m.ErrCode
+	}
+	return ErrCode_ErrCodeUnknown
+}
+
+type Stats struct {
+	Id               *int64 `protobuf:"varint,1,opt,name=id" json:"id,omitempty"`
+	BytesIn          *int64 `protobuf:"varint,2,opt,name=bytesIn" json:"bytesIn,omitempty"`
+	BytesOut         *int64 `protobuf:"varint,3,opt,name=bytesOut" json:"bytesOut,omitempty"`
+	Conns            *int64 `protobuf:"varint,4,opt,name=conns" json:"conns,omitempty"`
+	Streams          *int64 `protobuf:"varint,5,opt,name=streams" json:"streams,omitempty"`
+	Errors           *int64 `protobuf:"varint,6,opt,name=errors" json:"errors,omitempty"`
+	OpenedAt         *int64 `protobuf:"varint,7,opt,name=openedAt" json:"openedAt,omitempty"`
+	LastActivity     *int64 `protobuf:"varint,8,opt,name=lastActivity" json:"lastActivity,omitempty"`
+	XXX_unrecognized []byte `json:"-"`
+}
+
+func (m *Stats) Reset()                    { *m = Stats{} }
+func (m *Stats) String() string            { return proto.CompactTextString(m) }
+func (*Stats) ProtoMessage()               {}
+func (*Stats) Descriptor() ([]byte, []int) { return fileDescriptorXtpCtl, []int{23} }
+
+func (m *Stats) GetId() int64 {
+	if m != nil && m.Id != nil {
+		return *m.Id
+	}
+	return 0
+}
+
+func (m *Stats) GetBytesIn() int64 {
+	if m != nil && m.BytesIn != nil {
+		return *m.BytesIn
+	}
+	return 0
+}
+
+func (m *Stats) GetBytesOut() int64 {
+	if m != nil && m.BytesOut != nil {
+		return *m.BytesOut
+	}
+	return 0
+}
+
+func (m *Stats) GetConns() int64 {
+	if m != nil && m.Conns != nil {
+		return *m.Conns
+	}
+	return 0
+}
+
+func (m *Stats) GetStreams() int64 {
+	if m != nil && m.Streams != nil {
+		return *m.Streams
+	}
+	return 0
+}
+
+func (m *Stats) GetErrors() int64 {
+	if m != nil && m.Errors != nil {
+		return *m.Errors
+	}
+	return 0
+}
+
+func (m *Stats) GetOpenedAt() int64 {
+	if m != nil && m.OpenedAt != nil {
+		return *m.OpenedAt
+	}
+	return 0
+}
+
+func (m *Stats) GetLastActivity() int64 {
+	if m != nil && m.LastActivity != nil {
+		return *m.LastActivity
+	}
+	return 0
+}
+
+type StatsReq struct {
+	Ids              []int64 `protobuf:"varint,1,rep,name=ids" json:"ids,omitempty"`
+	XXX_unrecognized []byte  `json:"-"`
+}
+
+func (m *StatsReq) Reset()                    { *m = StatsReq{} }
+func (m *StatsReq) String() string            { return proto.CompactTextString(m) }
+func (*StatsReq) ProtoMessage()               {}
+func (*StatsReq) Descriptor() ([]byte, []int) { return fileDescriptorXtpCtl, []int{24} }
+
+func (m *StatsReq) GetIds() []int64 {
+	if m != nil {
+		return m.Ids
+	}
+	return nil
+}
+
+type StatsRes struct {
+	Stats            []*Stats `protobuf:"bytes,1,rep,name=stats" json:"stats,omitempty"`
+	More             *bool    `protobuf:"varint,2,opt,name=more" json:"more,omitempty"`
+	XXX_unrecognized []byte   `json:"-"`
+}
+
+func (m *StatsRes) Reset()                    { *m = StatsRes{} }
+func (m *StatsRes) String() string            { return proto.CompactTextString(m) }
+func (*StatsRes) ProtoMessage()               {}
+func (*StatsRes) Descriptor() ([]byte, []int) { return fileDescriptorXtpCtl, []int{25} }
+
+func (m *StatsRes) GetStats() []*Stats {
+	if m != nil {
+		return m.Stats
+	}
+	return nil
+}
+
+func (m *StatsRes) GetMore() bool {
+	if m != nil && m.More != nil {
+		return *m.More
+	}
+	return false
+}
+
+type AuthReq_Method int32
+
+const (
+	AuthReq_Token     AuthReq_Method = 1
+	AuthReq_Challenge AuthReq_Method = 2
+	AuthReq_Ed25519   AuthReq_Method = 3
+)
+
+var AuthReq_Method_name = map[int32]string{
+	1: "Token",
+	2: "Challenge",
+	3: "Ed25519",
+}
+var AuthReq_Method_value = map[string]int32{
+	"Token":     1,
+	"Challenge": 2,
+	"Ed25519":   3,
+}
+
+func (x AuthReq_Method) Enum() *AuthReq_Method {
+	p := new(AuthReq_Method)
+	*p = x
+	return p
+}
+func (x AuthReq_Method) String() string {
+	return proto.EnumName(AuthReq_Method_name, int32(x))
+}
+func (x *AuthReq_Method) UnmarshalJSON(data []byte) error {
+	value, err := proto.UnmarshalJSONEnum(AuthReq_Method_value, data, "AuthReq_Method")
+	if err != nil {
+		return err
+	}
+	*x = AuthReq_Method(value)
+	return nil
+}
+func (AuthReq_Method) EnumDescriptor() ([]byte, []int) { return fileDescriptorXtpCtl, []int{26, 0} }
+
+// AuthReq authenticates the client with a shared token, or with an
+// ed25519 key: the client asks for a Challenge, and signs it.
+type AuthReq struct {
+	Method           *AuthReq_Method `protobuf:"varint,1,opt,name=method,enum=AuthReq_Method" json:"method,omitempty"`
+	Token            *string         `protobuf:"bytes,2,opt,name=token" json:"token,omitempty"`
+	PublicKey        []byte          `protobuf:"bytes,3,opt,name=publicKey" json:"publicKey,omitempty"`
+	Signature        []byte          `protobuf:"bytes,4,opt,name=signature" json:"signature,omitempty"`
+	XXX_unrecognized []byte          `json:"-"`
+}
+
+func (m *AuthReq) Reset()                    { *m = AuthReq{} }
+func (m *AuthReq) String() string            { return proto.CompactTextString(m) }
+func (*AuthReq) ProtoMessage()               {}
+func (*AuthReq) Descriptor() ([]byte, []int) { return fileDescriptorXtpCtl, []int{26} }
+
+func (m *AuthReq) GetMethod() AuthReq_Method {
+	if m != nil && m.Method != nil {
+		return *m.Method
+	}
+	return AuthReq_Token
+}
+
+func (m *AuthReq) GetToken() string {
+	if m != nil && m.Token != nil {
+		return *m.Token
+	}
+	return ""
+}
+
+func (m *AuthReq) GetPublicKey() []byte {
+	if m != nil {
+		return m.PublicKey
+	}
+	return nil
+}
+
+func (m *AuthReq) GetSignature() []byte {
+	if m != nil {
+		return m.Signature
+	}
+	return nil
+}
+
+type AuthRes struct {
+	Identity         *string `protobuf:"bytes,1,opt,name=identity" json:"identity,omitempty"`
+	Challenge        []byte  `protobuf:"bytes,2,opt,name=challenge" json:"challenge,omitempty"`
+	XXX_unrecognized []byte  `json:"-"`
+}
+
+func (m *AuthRes) Reset()                    { *m = AuthRes{} }
+func (m *AuthRes) String() string            { return proto.CompactTextString(m) }
+func (*AuthRes) ProtoMessage()               {}
+func (*AuthRes) Descriptor() ([]byte, []int) { return fileDescriptorXtpCtl, []int{27} }
+
+func (m *AuthRes) GetIdentity() string {
+	if m != nil && m.Identity != nil {
+		return *m.Identity
+	}
+	return ""
+}
+
+func (m *AuthRes) GetChallenge() []byte {
+	if m != nil {
+		return m.Challenge
+	}
+	return nil
+}
+
+// Usage is what a client (or the whole server) uses, or its limits. In
+// limits, 0 is no limit.
+type Usage struct {
+	Listeners        *int64 `protobuf:"varint,1,opt,name=listeners" json:"listeners,omitempty"`
+	Dialers          *int64 `protobuf:"varint,2,opt,name=dialers" json:"dialers,omitempty"`
+	Conns            *int64 `protobuf:"varint,3,opt,name=conns" json:"conns,omitempty"`
+	Streams          *int64 `protobuf:"varint,4,opt,name=streams" json:"streams,omitempty"`
+	PendingAccepts   *int64 `protobuf:"varint,5,opt,name=pendingAccepts" json:"pendingAccepts,omitempty"`
+	InFlight         *int64 `protobuf:"varint,6,opt,name=inFlight" json:"inFlight,omitempty"`
+	Bytes            *int64 `protobuf:"varint,7,opt,name=bytes" json:"bytes,omitempty"`
+	Bandwidth        *int64 `protobuf:"varint,8,opt,name=bandwidth" json:"bandwidth,omitempty"`
+	XXX_unrecognized []byte `json:"-"`
+}
+
+func (m *Usage) Reset()                    { *m = Usage{} }
+func (m *Usage) String() string            { return proto.CompactTextString(m) }
+func (*Usage) ProtoMessage()               {}
+func (*Usage) Descriptor() ([]byte, []int) { return fileDescriptorXtpCtl, []int{28} }
+
+func (m *Usage) GetListeners() int64 {
+	if m != nil && m.Listeners != nil {
+		return *m.Listeners
+	}
+	return 0
+}
+
+func (m *Usage) GetDialers() int64 {
+	if m != nil && m.Dialers != nil {
+		return *m.Dialers
+	}
+	return 0
+}
+
+func (m *Usage) GetConns() int64 {
+	if m != nil && m.Conns != nil {
+		return *m.Conns
+	}
+	return 0
+}
+
+func (m *Usage) GetStreams() int64 {
+	if m != nil && m.Streams != nil {
+		return *m.Streams
+	}
+	return 0
+}
+
+func (m *Usage) GetPendingAccepts() int64 {
+	if m != nil && m.PendingAccepts != nil {
+		return *m.PendingAccepts
+	}
+	return 0
+}
+
+func (m *Usage) GetInFlight() int64 {
+	if m != nil && m.InFlight != nil {
+		return *m.InFlight
+	}
+	return 0
+}
+
+func (m *Usage) GetBytes() int64 {
+	if m != nil && m.Bytes != nil {
+		return *m.Bytes
+	}
+	return 0
+}
+
+func (m *Usage) GetBandwidth() int64 {
+	if m != nil && m.Bandwidth != nil {
+		return *m.Bandwidth
+	}
+	return 0
+}
+
+type UsageReq struct {
+	XXX_unrecognized []byte `json:"-"`
+}
+
+func (m *UsageReq) Reset()                    { *m = UsageReq{} }
+func (m *UsageReq) String() string            { return proto.CompactTextString(m) }
+func (*UsageReq) ProtoMessage()               {}
+func (*UsageReq) Descriptor() ([]byte, []int) { return fileDescriptorXtpCtl, []int{29} }
+
+type UsageRes struct {
+	Usage            *Usage `protobuf:"bytes,1,opt,name=usage" json:"usage,omitempty"`
+	Limits           *Usage `protobuf:"bytes,2,opt,name=limits" json:"limits,omitempty"`
+	ServerUsage      *Usage `protobuf:"bytes,3,opt,name=serverUsage" json:"serverUsage,omitempty"`
+	ServerLimits     *Usage `protobuf:"bytes,4,opt,name=serverLimits" json:"serverLimits,omitempty"`
+	XXX_unrecognized []byte `json:"-"`
+}
+
+func (m *UsageRes) Reset()                    { *m = UsageRes{} }
+func (m *UsageRes) String() string            { return proto.CompactTextString(m) }
+func (*UsageRes) ProtoMessage()               {}
+func (*UsageRes) Descriptor() ([]byte, []int) { return fileDescriptorXtpCtl, []int{30} }
+
+func (m *UsageRes) GetUsage() *Usage {
+	if m != nil {
+		return m.Usage
+	}
+	return nil
+}
+
+func (m *UsageRes) GetLimits() *Usage {
+	if m != nil {
+		return m.Limits
+	}
+	return nil
+}
+
+func (m *UsageRes) GetServerUsage() *Usage {
+	if m != nil {
+		return m.ServerUsage
+	}
+	return nil
+}
+
+func (m *UsageRes) GetServerLimits() *Usage {
+	if m != nil {
+		return m.ServerLimits
+	}
+	return nil
+}
+
 func init() {
 	proto.RegisterType((*RPC)(nil), "RPC")
 	proto.RegisterType((*Transport)(nil), "Transport")
@@ -646,52 +1463,133 @@ func init() {
 	proto.RegisterType((*DialerRes)(nil), "DialerRes")
 	proto.RegisterType((*DialReq)(nil), "DialReq")
 	proto.RegisterType((*DialRes)(nil), "DialRes")
+	proto.RegisterType((*Hello)(nil), "Hello")
+	proto.RegisterType((*HelloReq)(nil), "HelloReq")
+	proto.RegisterType((*HelloRes)(nil), "HelloRes")
+	proto.RegisterType((*ShutdownReq)(nil), "ShutdownReq")
+	proto.RegisterType((*WatchReq)(nil), "WatchReq")
+	proto.RegisterType((*WatchRes)(nil), "WatchRes")
+	proto.RegisterType((*Stats)(nil), "Stats")
+	proto.RegisterType((*StatsReq)(nil), "StatsReq")
+	proto.RegisterType((*StatsRes)(nil), "StatsRes")
+	proto.RegisterType((*AuthReq)(nil), "AuthReq")
+	proto.RegisterType((*AuthRes)(nil), "AuthRes")
+	proto.RegisterType((*Usage)(nil), "Usage")
+	proto.RegisterType((*UsageReq)(nil), "UsageReq")
+	proto.RegisterType((*UsageRes)(nil), "UsageRes")
 	proto.RegisterEnum("TType", TType_name, TType_value)
+	proto.RegisterEnum("ErrCode", ErrCode_name, ErrCode_value)
 	proto.RegisterEnum("RPC_Type", RPC_Type_name, RPC_Type_value)
+	proto.RegisterEnum("ShutdownReq_How", ShutdownReq_How_name, ShutdownReq_How_value)
+	proto.RegisterEnum("WatchRes_Event", WatchRes_Event_name, WatchRes_Event_value)
+	proto.RegisterEnum("AuthReq_Method", AuthReq_Method_name, AuthReq_Method_value)
 }
 
 func init() { proto.RegisterFile("xtp-ctl.proto", fileDescriptorXtpCtl) }
 
 var fileDescriptorXtpCtl = []byte{
-	// 627 bytes of a gzipped FileDescriptorProto
-	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x09, 0x6e, 0x88, 0x02, 0xff, 0xac, 0x54, 0xcd, 0x6e, 0xd4, 0x3c,
-	0x14, 0xfd, 0xf2, 0x37, 0x33, 0xb9, 0x99, 0x99, 0xfa, 0xb3, 0x10, 0x1a, 0xda, 0x4a, 0x0c, 0x46,
-	0xd0, 0x11, 0xa2, 0x59, 0x44, 0x6c, 0x40, 0x6c, 0xd0, 0x80, 0xa0, 0x12, 0xb4, 0x95, 0xe9, 0x02,
-	0xb1, 0x8b, 0x12, 0x0b, 0x45, 0xca, 0x1f, 0xb1, 0x8b, 0xda, 0x57, 0xe0, 0x0d, 0xd8, 0xf3, 0x28,
-	0x3c, 0x18, 0xb2, 0x9d, 0x49, 0x4c, 0xc2, 0xa2, 0x02, 0x76, 0x3e, 0xe7, 0x5c, 0x5f, 0x1f, 0xdb,
-	0xf7, 0x5e, 0x58, 0x5c, 0x89, 0xfa, 0x38, 0x11, 0x79, 0x58, 0x37, 0x95, 0xa8, 0xc8, 0x37, 0x1b,
-	0x1c, 0x7a, 0xbe, 0xc5, 0x07, 0xe0, 0x34, 0x75, 0xb2, 0xb2, 0xd6, 0xd6, 0x66, 0x19, 0xf9, 0x21,
-	0x3d, 0xdf, 0x86, 0x17, 0xd7, 0x35, 0xa3, 0x92, 0xc5, 0x2b, 0x98, 0x16, 0x8c, 0xf3, 0xf8, 0x13,
-	0x5b, 0xd9, 0x6b, 0x6b, 0x33, 0xa7, 0x3b, 0x88, 0x6f, 0x81, 0xc7, 0x9a, 0xa6, 0x6a, 0x56, 0xce,
-	0xda, 0xda, 0xf8, 0x54, 0x03, 0xf2, 0xc3, 0x02, 0x57, 0xee, 0xc6, 0x33, 0x70, 0x4f, 0x2f, 0xf3,
-	0x1c, 0xfd, 0xa7, 0x56, 0xd5, 0x59, 0x8d, 0x2c, 0x1c, 0xc0, 0xf4, 0x6d, 0xc6, 0x05, 0x65, 0x9f,
-	0x91, 0xdd, 0x03, 0x8e, 0x1c, 0x3c, 0x87, 0xd9, 0x36, 0xaf, 0x38, 0x93, 0x92, 0x6b, 0x20, 0x8e,
-	0x3c, 0xbc, 0x00, 0x5f, 0x06, 0xb2, 0x52, 0x8a, 0x13, 0x13, 0x72, 0x34, 0x95, 0xf0, 0x45, 0x92,
-	0xb0, 0x5a, 0x65, 0x9d, 0x99, 0x90, 0x23, 0x5f, 0xc2, 0x97, 0x59, 0x9c, 0xb3, 0x46, 0xaa, 0x60,
-	0x42, 0x8e, 0x02, 0x69, 0x41, 0x42, 0xa9, 0xcd, 0x7b, 0xc0, 0xd1, 0x82, 0x3c, 0x05, 0xff, 0xa2,
-	0x89, 0x4b, 0x5e, 0x57, 0x8d, 0xc0, 0x4b, 0xb0, 0xb3, 0x54, 0xbd, 0x8f, 0x43, 0xed, 0x2c, 0xc5,
-	0x87, 0xe0, 0x8b, 0x9d, 0xa8, 0x5e, 0xc5, 0xa7, 0x3d, 0x41, 0x3e, 0xc2, 0x4c, 0xfb, 0x63, 0xcd,
-	0x68, 0xe7, 0x1a, 0x82, 0x2e, 0xf0, 0x24, 0x55, 0x7b, 0x1d, 0x6a, 0x52, 0x32, 0x77, 0x71, 0x99,
-	0x8b, 0x2c, 0x4e, 0x53, 0xfd, 0xb2, 0x73, 0xda, 0x13, 0xe4, 0x03, 0x4c, 0xb4, 0xff, 0x7f, 0x9e,
-	0xf9, 0xab, 0x05, 0xee, 0xb6, 0x2a, 0xcb, 0x3f, 0x48, 0xfc, 0x10, 0x96, 0x79, 0x95, 0xc4, 0xf9,
-	0xbb, 0x41, 0xf6, 0x01, 0x8b, 0x37, 0xb0, 0xd7, 0xb0, 0xa2, 0x12, 0xac, 0x0f, 0x74, 0x55, 0xe0,
-	0x90, 0x26, 0xdf, 0x2d, 0x98, 0xbc, 0x17, 0x0d, 0x8b, 0x8b, 0x91, 0x9d, 0xdb, 0x30, 0x49, 0xaa,
-	0xb2, 0xec, 0x9c, 0xb4, 0x68, 0x68, 0xd3, 0xb9, 0x89, 0x4d, 0xf7, 0xa6, 0x36, 0xbd, 0xdf, 0xdb,
-	0x3c, 0xea, 0xca, 0x19, 0x1f, 0x82, 0x27, 0xae, 0x6b, 0xc6, 0x57, 0xd6, 0xda, 0xd9, 0x2c, 0xa3,
-	0x49, 0x78, 0xa1, 0x5a, 0x48, 0x93, 0xe4, 0xaa, 0x2b, 0x75, 0x7c, 0x1f, 0xbc, 0x4c, 0xb0, 0x42,
-	0x07, 0x06, 0xd1, 0x22, 0x6c, 0x85, 0xf0, 0x44, 0xb0, 0x82, 0x6a, 0x6d, 0xff, 0x0d, 0xb8, 0x12,
-	0x8e, 0x2e, 0xbf, 0x0f, 0xae, 0x4c, 0xa8, 0xae, 0xde, 0x1f, 0xa2, 0x38, 0xd9, 0x8e, 0x5f, 0xe2,
-	0xfc, 0x92, 0xb5, 0x8f, 0xaf, 0x01, 0xd9, 0xef, 0xfb, 0x6a, 0x98, 0x8d, 0x3c, 0x33, 0xfa, 0x0a,
-	0x1f, 0xc3, 0x3c, 0x6f, 0xab, 0xf6, 0xac, 0x16, 0x5c, 0x85, 0x05, 0x91, 0x1f, 0xee, 0x4a, 0x99,
-	0xfe, 0x22, 0x93, 0xc8, 0x68, 0x42, 0xfc, 0x00, 0x66, 0x3b, 0x71, 0xbc, 0xaf, 0x93, 0xc8, 0x81,
-	0xd1, 0xa9, 0x23, 0x33, 0xaf, 0x8d, 0xbe, 0xc5, 0x77, 0xc0, 0x95, 0xdf, 0xda, 0x26, 0xf3, 0x42,
-	0x59, 0x98, 0x54, 0x51, 0xf8, 0x2e, 0x4c, 0xb8, 0xaa, 0x0c, 0xf5, 0x08, 0x41, 0x34, 0x0d, 0x75,
-	0xa1, 0xd0, 0x96, 0x26, 0x4f, 0x8c, 0x8e, 0xc7, 0x47, 0x00, 0xa9, 0x02, 0xc6, 0x9d, 0xa6, 0x61,
-	0xab, 0x1b, 0x12, 0x79, 0x6c, 0x0c, 0x06, 0x79, 0x86, 0x96, 0x86, 0x3b, 0x5a, 0x9a, 0x3c, 0xef,
-	0xe6, 0xc6, 0xe8, 0x8b, 0xee, 0xc1, 0x4c, 0xfa, 0x54, 0xe7, 0xd9, 0xa6, 0xfd, 0x8e, 0x26, 0xaf,
-	0xba, 0x41, 0xf3, 0x37, 0x17, 0x7d, 0x54, 0x80, 0xa7, 0xfe, 0x5f, 0x4e, 0x4b, 0xb5, 0x38, 0xcd,
-	0xe4, 0xb4, 0xc5, 0xb0, 0x54, 0xa8, 0x1b, 0x5f, 0xc8, 0xc2, 0xff, 0xc3, 0x42, 0x71, 0xbb, 0x4f,
-	0x41, 0x36, 0xde, 0x83, 0x40, 0x51, 0xfa, 0x66, 0xc8, 0x91, 0xa3, 0x51, 0x11, 0xd2, 0x02, 0x72,
-	0x3b, 0x5d, 0x1f, 0x8a, 0xbc, 0x9f, 0x01, 0x00, 0x00, 0xff, 0xff, 0x15, 0xef, 0xe0, 0x8e, 0x3d,
-	0x06, 0x00, 0x00,
+	// 1645 bytes of a gzipped FileDescriptorProto
+	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x09, 0x6e, 0x88, 0x02, 0xff, 0xac, 0x57, 0xcd, 0x92, 0x24, 0x37,
+	0x11, 0x76, 0x75, 0x55, 0xf5, 0x4f, 0x76, 0xcf, 0x8c, 0x56, 0xde, 0x5d, 0xd7, 0xce, 0x2e, 0x30,
+	0x16, 0xb1, 0x76, 0xc7, 0x06, 0x2e, 0x82, 0x09, 0x7c, 0x30, 0x61, 0x22, 0x18, 0xda, 0x63, 0x76,
+	0x82, 0xfd, 0x0b, 0xcd, 0x0c, 0x26, 0xb8, 0xd5, 0x56, 0x69, 0xa7, 0x2b, 0x5c, 0x7f, 0x94, 0x54,
+	0x33, 0x3b, 0x1c, 0x39, 0x11, 0x3c, 0x01, 0x27, 0x4e, 0x9c, 0xb8, 0xf3, 0x04, 0x3c, 0x03, 0x2f,
+	0xc0, 0x85, 0x2b, 0x8f, 0x40, 0xa4, 0xa4, 0xfa, 0xe9, 0x6e, 0x13, 0x76, 0x18, 0x6e, 0xf5, 0x7d,
+	0x9f, 0x94, 0x4a, 0x49, 0xa9, 0xcc, 0x2c, 0xd8, 0x7b, 0xab, 0xaa, 0x8f, 0x62, 0x95, 0x85, 0x55,
+	0x5d, 0xaa, 0x92, 0xfd, 0xdd, 0x03, 0x97, 0xbf, 0x5a, 0xd1, 0x87, 0xe0, 0xd6, 0x55, 0x1c, 0x38,
+	0x47, 0xce, 0x72, 0xff, 0x78, 0x16, 0xf2, 0x57, 0xab, 0xf0, 0xe2, 0xb6, 0x12, 0x1c, 0x59, 0x1a,
+	0xc0, 0x24, 0x17, 0x52, 0x46, 0x57, 0x22, 0x18, 0x1d, 0x39, 0xcb, 0x05, 0x6f, 0x21, 0xbd, 0x0b,
+	0xbe, 0xa8, 0xeb, 0xb2, 0x0e, 0xdc, 0x23, 0x67, 0x39, 0xe3, 0x06, 0x50, 0x06, 0x13, 0x51, 0xd7,
+	0xab, 0x32, 0x11, 0x81, 0xa7, 0x0d, 0x4e, 0xc3, 0x53, 0x83, 0x79, 0x2b, 0xa0, 0x4d, 0x95, 0xe6,
+	0xa2, 0x6c, 0x54, 0xe0, 0x1f, 0x39, 0xcb, 0x29, 0x6f, 0x21, 0x7d, 0x04, 0x33, 0x25, 0xf2, 0xaa,
+	0xac, 0xa3, 0xfa, 0x36, 0x18, 0x6b, 0xad, 0x27, 0xe8, 0x11, 0xcc, 0x55, 0x1d, 0xc5, 0xa2, 0x8a,
+	0x6a, 0x51, 0xa8, 0x60, 0xa2, 0xd7, 0x1d, 0x52, 0xec, 0xdf, 0x23, 0xf0, 0xd0, 0x77, 0x3a, 0x05,
+	0xef, 0x45, 0x93, 0x65, 0xe4, 0x1d, 0xfd, 0x55, 0xbe, 0xac, 0x88, 0x43, 0xe7, 0x30, 0x79, 0x96,
+	0x4a, 0xc5, 0xc5, 0x6f, 0xc9, 0xa8, 0x07, 0x92, 0xb8, 0x74, 0x01, 0xd3, 0x55, 0x56, 0x4a, 0x81,
+	0x92, 0x37, 0x40, 0x92, 0xf8, 0x74, 0x0f, 0x66, 0x38, 0x50, 0x14, 0x28, 0x8e, 0x87, 0x50, 0x92,
+	0x09, 0xc2, 0x93, 0x38, 0x16, 0x95, 0xb6, 0x3a, 0x1d, 0x42, 0x49, 0x66, 0x08, 0x3f, 0x4b, 0xa3,
+	0x4c, 0xd4, 0xa8, 0xc2, 0x10, 0x4a, 0x32, 0x47, 0x17, 0x10, 0xa2, 0xb6, 0xe8, 0x81, 0x24, 0x7b,
+	0xe8, 0xc1, 0x53, 0x91, 0x65, 0x25, 0x4a, 0xfb, 0x03, 0x24, 0xc9, 0x01, 0x3d, 0x80, 0xf9, 0xf9,
+	0xba, 0x51, 0x49, 0x79, 0xa3, 0x3d, 0x22, 0x9b, 0x84, 0x24, 0x77, 0x70, 0xfc, 0x17, 0x91, 0x8a,
+	0xd7, 0x28, 0xd3, 0x01, 0x92, 0xe4, 0x5d, 0x44, 0xe7, 0x2a, 0x52, 0x12, 0xb5, 0xbb, 0x03, 0x24,
+	0xc9, 0x3d, 0x74, 0xe1, 0xa4, 0x51, 0x7a, 0xda, 0xfd, 0x1e, 0x48, 0xf2, 0x1e, 0x8e, 0xbb, 0xc4,
+	0x3b, 0x47, 0x29, 0x18, 0x20, 0x49, 0x1e, 0xb0, 0x4f, 0x60, 0x76, 0x51, 0x47, 0x85, 0xac, 0xca,
+	0x5a, 0xd1, 0x7d, 0x18, 0xa5, 0x89, 0x8e, 0x24, 0x97, 0x8f, 0xd2, 0x44, 0xdf, 0x67, 0x2b, 0xea,
+	0xf8, 0x99, 0xf1, 0x9e, 0x60, 0xbf, 0x81, 0xa9, 0x39, 0x4b, 0x51, 0xef, 0xcc, 0x34, 0x77, 0x6d,
+	0x06, 0x9e, 0x25, 0x7a, 0xae, 0xcb, 0x87, 0x14, 0xda, 0xce, 0x9b, 0x4c, 0xa5, 0x51, 0x92, 0x98,
+	0x18, 0x5c, 0xf0, 0x9e, 0x60, 0xbf, 0x86, 0xb1, 0x39, 0xeb, 0xff, 0xbb, 0xe5, 0x3f, 0x3a, 0xe0,
+	0xad, 0xca, 0xa2, 0xf8, 0x16, 0x86, 0x3f, 0x80, 0xfd, 0xac, 0x8c, 0xa3, 0xec, 0xf9, 0x96, 0xf5,
+	0x2d, 0x96, 0x2e, 0xe1, 0xa0, 0x16, 0x79, 0xa9, 0x44, 0x3f, 0xd0, 0xd3, 0x03, 0xb7, 0x69, 0xf6,
+	0x17, 0x07, 0xc6, 0xe7, 0xaa, 0x16, 0x51, 0xbe, 0xe3, 0xce, 0x7d, 0x18, 0xc7, 0x65, 0x51, 0x74,
+	0x9e, 0x58, 0xb4, 0xed, 0xa6, 0xfb, 0x4d, 0xdc, 0xf4, 0xbe, 0xa9, 0x9b, 0xfe, 0x57, 0xbb, 0xf9,
+	0xd3, 0xee, 0xe9, 0xd1, 0x47, 0xe0, 0xab, 0xdb, 0x4a, 0xc8, 0xc0, 0x39, 0x72, 0x97, 0xfb, 0xc7,
+	0xe3, 0xf0, 0x42, 0x27, 0x1b, 0x43, 0x62, 0x52, 0x91, 0x18, 0x91, 0xda, 0xe7, 0x29, 0x37, 0x80,
+	0xfd, 0xd5, 0xe9, 0x5e, 0x2b, 0xfd, 0x3e, 0xf8, 0xa9, 0x12, 0xb9, 0x99, 0x3f, 0x3f, 0xde, 0x0b,
+	0xad, 0x10, 0x9e, 0x29, 0x91, 0x73, 0xa3, 0x51, 0x0a, 0x5e, 0x5e, 0xd6, 0xc2, 0x5a, 0xd1, 0xdf,
+	0x87, 0x6f, 0xc0, 0xc3, 0x21, 0x3b, 0xe7, 0x74, 0x08, 0x1e, 0xae, 0xad, 0xc7, 0xf6, 0xfe, 0x68,
+	0x0e, 0xdd, 0xb9, 0x8e, 0xb2, 0x46, 0xd8, 0x7b, 0x32, 0x00, 0xb7, 0x60, 0x9c, 0xc4, 0x63, 0x99,
+	0x1f, 0x8f, 0x43, 0xf3, 0x88, 0xac, 0xb3, 0x87, 0x7d, 0x32, 0xd9, 0x5e, 0x8b, 0xfd, 0x64, 0x90,
+	0x4c, 0xe8, 0x47, 0xb0, 0xc8, 0x6c, 0xf8, 0xbf, 0xac, 0x94, 0xd4, 0xc3, 0xe6, 0xc7, 0xb3, 0xb0,
+	0x7d, 0x13, 0x7c, 0x43, 0x66, 0xc7, 0xfd, 0x5c, 0x49, 0x1f, 0xc3, 0xb4, 0x15, 0x77, 0xe7, 0x75,
+	0x12, 0x7b, 0x38, 0x48, 0x4f, 0x3b, 0xce, 0xfc, 0xa2, 0x17, 0x25, 0x7d, 0x00, 0x1e, 0xc6, 0x87,
+	0x35, 0xe6, 0x87, 0x18, 0xe1, 0x5c, 0x53, 0xf4, 0x7b, 0x30, 0x96, 0x3a, 0xc4, 0xf4, 0x11, 0xcd,
+	0x8f, 0x27, 0xa1, 0x89, 0x38, 0x6e, 0x69, 0xf6, 0xe3, 0x41, 0x9a, 0xa3, 0x1f, 0x02, 0x24, 0x1a,
+	0x0c, 0xf6, 0x34, 0x09, 0xad, 0x3e, 0x90, 0xd8, 0x0f, 0xfa, 0x59, 0x12, 0xd7, 0x30, 0xd2, 0xf6,
+	0x0c, 0x4b, 0xb3, 0x4f, 0xbb, 0x64, 0xb9, 0x73, 0x81, 0xef, 0xc3, 0x14, 0xfd, 0xd4, 0xeb, 0x8d,
+	0x86, 0xee, 0x77, 0x34, 0x3b, 0x6d, 0x67, 0xff, 0x6f, 0x1b, 0xfd, 0x93, 0x03, 0xbe, 0x4e, 0xc5,
+	0x58, 0xc2, 0xae, 0x45, 0x2d, 0xd3, 0xb2, 0xb0, 0x8e, 0xb4, 0x10, 0x43, 0x2f, 0xcd, 0xab, 0xcc,
+	0x66, 0x3b, 0xfd, 0x4d, 0xbf, 0x03, 0x5e, 0x5d, 0xc5, 0x32, 0x70, 0x8f, 0xdc, 0xcd, 0x12, 0xab,
+	0x69, 0x7c, 0x6f, 0x79, 0xf4, 0xf6, 0xb9, 0xa9, 0xab, 0xe7, 0xe9, 0xef, 0x4c, 0xe9, 0x74, 0xf9,
+	0x16, 0x4b, 0x0f, 0x61, 0xfa, 0x46, 0x44, 0xaa, 0xa9, 0x85, 0x0c, 0xfc, 0x23, 0x77, 0x39, 0xe3,
+	0x1d, 0x66, 0x3f, 0xeb, 0x4b, 0x06, 0xc6, 0xe7, 0x1a, 0xbf, 0xed, 0x1e, 0xc7, 0xa1, 0x51, 0x0c,
+	0x89, 0x79, 0xa1, 0x16, 0xb2, 0xc9, 0xdb, 0x82, 0x6e, 0x11, 0xfb, 0x79, 0x5f, 0x66, 0xbe, 0xc6,
+	0x42, 0x00, 0x13, 0x29, 0xa4, 0xde, 0xbc, 0xed, 0x09, 0x2c, 0x64, 0xbf, 0x77, 0x36, 0xaa, 0xd3,
+	0xce, 0x55, 0x31, 0x70, 0xd7, 0xe5, 0x8d, 0x7d, 0x6a, 0x24, 0x1c, 0x0c, 0x0d, 0x9f, 0x96, 0x37,
+	0x1c, 0x45, 0xb4, 0x7e, 0x53, 0xa7, 0x4a, 0x89, 0xc2, 0xe6, 0xa6, 0x16, 0xb2, 0xc7, 0xe0, 0x3e,
+	0x2d, 0x6f, 0xe8, 0x0c, 0xfc, 0x2f, 0xea, 0x54, 0x09, 0xe2, 0x60, 0x71, 0xe7, 0x22, 0x4a, 0xc8,
+	0x08, 0x49, 0x2e, 0xa4, 0x50, 0xc4, 0x65, 0xcf, 0xfa, 0xfa, 0xf7, 0x35, 0xd9, 0x86, 0xc1, 0x62,
+	0x90, 0xf7, 0x30, 0x7a, 0xdc, 0xa5, 0xcb, 0x37, 0x38, 0xf6, 0x4f, 0xa7, 0x2f, 0xa0, 0xf4, 0x31,
+	0xf8, 0xe2, 0x1a, 0x7b, 0x0f, 0xd3, 0x2c, 0x1d, 0x84, 0xad, 0x12, 0x9e, 0x22, 0xcd, 0x8d, 0x4a,
+	0xdf, 0x07, 0x0f, 0xf3, 0x90, 0x0d, 0xa3, 0xad, 0x14, 0xa5, 0xa5, 0x6f, 0xdf, 0x3d, 0x31, 0x0e,
+	0xbe, 0x5e, 0xac, 0xab, 0xec, 0x69, 0x71, 0x45, 0x1c, 0x0a, 0x30, 0x7e, 0x59, 0x89, 0x42, 0xe0,
+	0x61, 0x2c, 0x60, 0x6a, 0x5e, 0xb6, 0x48, 0x88, 0x8b, 0x8a, 0x7e, 0x4c, 0x09, 0xf1, 0xf0, 0x5b,
+	0x27, 0xa7, 0x84, 0xf8, 0x78, 0x64, 0xa7, 0xb8, 0x2a, 0x19, 0xb3, 0x7f, 0x38, 0xe0, 0xeb, 0x24,
+	0xb6, 0x73, 0x63, 0x01, 0x4c, 0x5e, 0xdf, 0x2a, 0x21, 0xcf, 0x0a, 0x5b, 0x46, 0x5a, 0x88, 0xd1,
+	0xa8, 0x3f, 0x5f, 0x36, 0xca, 0x5e, 0x54, 0x87, 0x71, 0x77, 0xf8, 0xa2, 0xa4, 0x0d, 0x64, 0x03,
+	0x74, 0xdc, 0xe8, 0x87, 0x24, 0x75, 0x9d, 0x70, 0x79, 0x0b, 0x31, 0x26, 0xf5, 0x01, 0x48, 0xdd,
+	0xf4, 0xb9, 0xdc, 0x22, 0x5c, 0xa3, 0xd4, 0x9b, 0x3a, 0x31, 0xed, 0x9e, 0xcb, 0x3b, 0x8c, 0x97,
+	0x97, 0x45, 0x52, 0x9d, 0xc4, 0x2a, 0xbd, 0x4e, 0xd5, 0x6d, 0x30, 0xd5, 0xfa, 0x06, 0xc7, 0x1e,
+	0xf5, 0xed, 0x0e, 0x25, 0xe0, 0xa6, 0x89, 0x09, 0x04, 0x97, 0xe3, 0x27, 0xfb, 0xb4, 0x53, 0x65,
+	0x9f, 0xd3, 0x4d, 0x59, 0xd9, 0xcc, 0xe9, 0x5f, 0x55, 0x4f, 0xd8, 0xdf, 0x9c, 0xae, 0x5f, 0xa2,
+	0x1f, 0xc2, 0x38, 0x17, 0x6a, 0x5d, 0x26, 0x5d, 0x60, 0x58, 0x25, 0x7c, 0xae, 0x69, 0x6e, 0x65,
+	0x3c, 0x18, 0x55, 0x7e, 0x29, 0x0a, 0x9b, 0x1e, 0x0c, 0xc0, 0x86, 0xa3, 0x6a, 0x5e, 0x67, 0x69,
+	0xfc, 0x4b, 0x71, 0xdb, 0x36, 0x1c, 0x1d, 0x81, 0xaa, 0x4c, 0xaf, 0x0a, 0xfd, 0xd0, 0x6d, 0x25,
+	0xee, 0x09, 0xf6, 0x43, 0x18, 0x9b, 0x35, 0xf0, 0x3e, 0x2f, 0xd0, 0x1c, 0x71, 0xb0, 0xd3, 0x5c,
+	0xad, 0xa3, 0x2c, 0x13, 0xc5, 0x95, 0x30, 0xcd, 0xee, 0x69, 0x72, 0xfc, 0xf1, 0xc7, 0x3f, 0xfa,
+	0x84, 0xb8, 0x6c, 0xd5, 0x75, 0x76, 0x78, 0xbc, 0x69, 0x22, 0x0a, 0x85, 0xc7, 0xe7, 0x68, 0x87,
+	0x3a, 0x8c, 0xab, 0xc6, 0xad, 0x09, 0xfb, 0xcc, 0x7b, 0x82, 0xfd, 0xcb, 0x01, 0x5f, 0x37, 0x81,
+	0x38, 0xae, 0x2d, 0x37, 0xd2, 0xc6, 0x4d, 0x4f, 0xe0, 0x95, 0x9b, 0x04, 0x2e, 0xdb, 0xf0, 0xb1,
+	0xb0, 0x0f, 0x11, 0xf7, 0xbf, 0x84, 0x88, 0xb7, 0x19, 0x22, 0x1f, 0xc0, 0x7e, 0x25, 0x8a, 0x24,
+	0x2d, 0xae, 0x4c, 0x68, 0xb7, 0x31, 0xb4, 0xc5, 0xea, 0x3d, 0x15, 0x9f, 0x67, 0xe9, 0xd5, 0x5a,
+	0xd9, 0x60, 0xea, 0x30, 0xae, 0xa9, 0x43, 0xd4, 0xc6, 0x92, 0x01, 0xb8, 0x83, 0xd7, 0x51, 0x91,
+	0xdc, 0xa4, 0x89, 0x5a, 0xdb, 0x28, 0xea, 0x09, 0x06, 0x7d, 0xef, 0xcb, 0xfe, 0xec, 0x74, 0x40,
+	0x47, 0x4c, 0x83, 0xdf, 0x5d, 0x8e, 0x34, 0x8a, 0x21, 0xe9, 0x77, 0x61, 0x9c, 0xa5, 0x79, 0xda,
+	0x95, 0xa4, 0x56, 0xb6, 0x2c, 0x5d, 0xc2, 0x5c, 0x8a, 0xfa, 0x5a, 0xd4, 0x9a, 0x0e, 0xdc, 0x8d,
+	0x41, 0x43, 0x89, 0x3e, 0x81, 0x85, 0x81, 0xcf, 0x8c, 0x3d, 0x6f, 0x63, 0xe8, 0x86, 0xf6, 0x24,
+	0x07, 0x5f, 0x27, 0x38, 0xcc, 0x00, 0xfa, 0xe3, 0x45, 0x8a, 0xff, 0x40, 0x14, 0xf6, 0x35, 0xea,
+	0x1a, 0x75, 0xe2, 0xd0, 0x3b, 0xb0, 0xa7, 0xb9, 0xb6, 0x6b, 0x20, 0x23, 0xfc, 0x93, 0xd0, 0x94,
+	0x29, 0xbd, 0xc4, 0xc5, 0x30, 0xd2, 0x04, 0xd6, 0x48, 0xe2, 0x75, 0xba, 0xa9, 0x8a, 0xc4, 0x7f,
+	0xf2, 0x07, 0x17, 0x26, 0x36, 0x3f, 0xe1, 0x1a, 0xf6, 0xf3, 0xb2, 0xf8, 0xb2, 0x28, 0x6f, 0x0a,
+	0xf2, 0x0e, 0xbd, 0x07, 0x77, 0x36, 0x39, 0xfe, 0x6a, 0x45, 0x1c, 0xfa, 0x2e, 0x1c, 0x58, 0xfa,
+	0x15, 0xfe, 0x88, 0xc6, 0x65, 0x46, 0x46, 0x03, 0xf2, 0x45, 0xa9, 0x3e, 0x2f, 0x9b, 0x02, 0x53,
+	0xd7, 0x03, 0xb8, 0x67, 0xc9, 0xb3, 0xe2, 0x3a, 0xca, 0xd2, 0xc4, 0x96, 0x43, 0xe2, 0xd1, 0x00,
+	0xee, 0x5a, 0xa9, 0xad, 0x7b, 0x4d, 0x5a, 0xeb, 0xbc, 0x76, 0x08, 0xf7, 0xad, 0xf2, 0x2b, 0x53,
+	0x93, 0x9f, 0xa7, 0x32, 0xc7, 0x44, 0x49, 0xc6, 0xf4, 0x2e, 0x10, 0xab, 0x9d, 0x24, 0x49, 0x7d,
+	0x56, 0x5c, 0x4a, 0x41, 0x26, 0xf4, 0x3e, 0x50, 0xcb, 0xea, 0x6e, 0x40, 0xbc, 0x69, 0x30, 0x43,
+	0x4e, 0x07, 0x7b, 0xba, 0x30, 0x3f, 0xa8, 0x64, 0x36, 0xf0, 0x73, 0x15, 0x15, 0xb1, 0xc0, 0xb4,
+	0x0a, 0x78, 0x98, 0x2d, 0x69, 0xb2, 0xeb, 0x7c, 0x60, 0xf3, 0xb2, 0x90, 0x4d, 0x85, 0xa7, 0x2e,
+	0x12, 0xb2, 0x18, 0x78, 0x77, 0x59, 0x44, 0x8d, 0x5a, 0xe3, 0x73, 0x8b, 0x23, 0xd4, 0xf6, 0xe8,
+	0x43, 0x78, 0xaf, 0x3d, 0x18, 0x51, 0xe7, 0xa9, 0xae, 0xa9, 0x9f, 0x89, 0x22, 0x15, 0x09, 0xd9,
+	0xa7, 0x8f, 0x20, 0xb0, 0x22, 0x17, 0xb2, 0x6c, 0xea, 0x58, 0x9c, 0xbe, 0x5d, 0x47, 0x8d, 0xc4,
+	0xa9, 0x07, 0xff, 0x19, 0x00, 0x09, 0xc3, 0xd5, 0xf5, 0xdc, 0x0f, 0x00, 0x00,
 }
@@ -1,9 +1,10 @@
 package xtpctlrpc
 
 import (
+  "encoding/binary"
   "errors"
-  "io"
   "fmt"
+  "io"
 
   ggio "gx/ipfs/QmZ4Qi3GaRbjcx28Sme5eMH7RQjGkt8wHxt2a65oLaeFEV/gogo-protobuf/io"
   proto "gx/ipfs/QmZ4Qi3GaRbjcx28Sme5eMH7RQjGkt8wHxt2a65oLaeFEV/gogo-protobuf/proto"
@@ -11,33 +12,110 @@ import (
   pb "github.com/libp2p/go-xtp-ctl/pb"
 )
 
-var (
-  ErrUnknownRPC = errors.New("unknown rpc")
-  ErrProtocol   = errors.New("incorrect protocol behavior")
-  ErrNotFound   = errors.New("descriptor not found")
-  ErrInvalidMessage = errors.New("invalid message")
-)
-
 var (
   MessageSizeMax = 1 << 12
 )
 
+// ErrMessageTooLarge is returned when writing an rpc bigger than
+// MessageSizeMax, or than the peer takes.
+var ErrMessageTooLarge = errors.New("rpc message too large")
+
+// PeerLimiter is a stream that knows the largest rpc its peer takes (the
+// MaxMessageSize of the peer's Hello). Bigger rpcs are not written to it.
+type PeerLimiter interface {
+  PeerMessageSizeMax() int
+}
+
+// writeSizeMax returns the largest rpc that may be written to s: the
+// smaller of ours and the peer's limits.
+func writeSizeMax(s IoStream) int {
+  if cs, ok := s.(*ctxStream); ok {
+    s = cs.IoStream
+  }
+  max := MessageSizeMax
+  if pl, ok := s.(PeerLimiter); ok {
+    if n := pl.PeerMessageSizeMax(); n > 0 && n < max {
+      max = n
+    }
+  }
+  return max
+}
+
 func WriteRPC(s IoStream, rpc *pb.RPC) error {
+  if n, max := proto.Size(rpc), writeSizeMax(s); n > max {
+    return fmt.Errorf("%w: %d bytes, limit %d", ErrMessageTooLarge, n, max)
+  }
   w := ggio.NewDelimitedWriter(s)
   return w.WriteMsg(rpc)
 }
 
+// ReadRPC reads exactly one delimited rpc message from s. It never reads
+// ahead (unlike a buffered reader), as streams may carry raw data right
+// after an rpc (see DialRes and AcceptRes).
 func ReadRPC(s IoStream, rpc *pb.RPC) error {
-  r := ggio.NewDelimitedReader(s, MessageSizeMax)
-  return r.ReadMsg(rpc)
+  l, err := binary.ReadUvarint(byteReader{s})
+  if err != nil {
+    return err
+  }
+  if l > uint64(MessageSizeMax) {
+    return io.ErrShortBuffer
+  }
+
+  buf := make([]byte, l)
+  if _, err := io.ReadFull(s, buf); err != nil {
+    return err
+  }
+  return proto.Unmarshal(buf, rpc)
+}
+
+// byteReader reads one byte at a time, without buffering.
+type byteReader struct {
+  io.Reader
+}
+
+func (r byteReader) ReadByte() (byte, error) {
+  var b [1]byte
+  _, err := io.ReadFull(r.Reader, b[:])
+  return b[0], err
+}
+
+// pageOverhead is what an rpc carrying a page of items takes besides the
+// items: its type, traceparent, and the page's own fields.
+const pageOverhead = 128
+
+// writePages writes n items as responses of type typ, each with as many
+// items as fit in an rpc to s. size returns the size of item i, and page
+// the response with items i to j, saying whether more follow. It writes
+// one (empty) page if there are no items.
+func writePages(s IoStream, typ pb.RPC_Type, n int, size func(i int) int, page func(i, j int, more bool) proto.Message) error {
+  max := writeSizeMax(s) - pageOverhead
+  for i := 0; ; {
+    j, total := i, 0
+    for j < n {
+      // a repeated field's element: tag, length, and the item.
+      sz := 1 + proto.SizeVarint(uint64(size(j))) + size(j)
+      if j > i && total+sz > max {
+        break
+      }
+      total += sz
+      j++
+    }
+    if err := WriteRPCMsg(s, typ, page(i, j, j < n), nil); err != nil {
+      return err
+    }
+    if j >= n {
+      return nil
+    }
+    i = j
+  }
 }
 
 func WriteRPCMsg(s IoStream, typ pb.RPC_Type, m proto.Message, err error) error {
   rpc := pb.RPC{Rpc: &typ}
   if err != nil {
-    estr := fmt.Sprint(err)
-    rpc.Error = &estr
+    setRPCError(&rpc, err)
   }
+  setTraceparent(&rpc, s)
   if m != nil {
     b, err := proto.Marshal(m)
     if err != nil {
@@ -58,12 +136,16 @@ func ReadRPCMsg(s IoStream, typ pb.RPC_Type, m proto.Message) error {
   if !rpc.Valid() {
     return ErrInvalidMessage
   }
-  if typ != pb.RPC_Null && typ != *rpc.Rpc {
+  // errors for rpcs the peer did not understand come back as Null.
+  if typ != pb.RPC_Null && typ != *rpc.Rpc && *rpc.Rpc != pb.RPC_Null {
     return ErrInvalidMessage
   }
 
-  if rpc.Error != nil && len(*rpc.Error) > 0 {
-    return errors.New(*rpc.Error)
+  if err := rpcError(&rpc); err != nil {
+    return err
+  }
+  if typ != pb.RPC_Null && typ != *rpc.Rpc {
+    return ErrInvalidMessage
   }
 
   // ok.
@@ -86,8 +168,9 @@ func ReadRPCMsg(s IoStream, typ pb.RPC_Type, m proto.Message) error {
   return nil
 }
 
+// ErrRPCRes sends err back as the response to req.
 func ErrRPCRes(s IoStream, req *pb.RPC, err error) error {
-  return WriteRPCMsg(s, *req.Rpc, nil, err)
+  return WriteRPCMsg(s, req.GetRpc().Res(), nil, err)
 }
 
 type IoStream interface {
@@ -0,0 +1,47 @@
+package xtpctlrpc
+
+import (
+  "bytes"
+  "errors"
+  "strings"
+  "testing"
+
+  pb "github.com/libp2p/go-xtp-ctl/pb"
+)
+
+// bufStream is an IoStream over a buffer, with the peer's limit.
+type bufStream struct {
+  bytes.Buffer
+  peerMax int
+}
+
+func (s *bufStream) Close() error            { return nil }
+func (s *bufStream) PeerMessageSizeMax() int { return s.peerMax }
+
+func TestWriteSizeMax(t *testing.T) {
+  big := strings.Repeat("x", 200)
+  hello := &pb.Hello{Impl: &big}
+
+  s := &bufStream{peerMax: 100}
+  if err := WriteRPCMsg(s, pb.RPC_HelloRes, &pb.HelloRes{Hello: hello}, nil); !errors.Is(err, ErrMessageTooLarge) {
+    t.Fatal("over the peer's limit:", err)
+  }
+  if s.Len() != 0 {
+    t.Fatal("wrote", s.Len(), "bytes of a message too large")
+  }
+
+  s.peerMax = 0 // unknown: ours applies.
+  if err := WriteRPCMsg(s, pb.RPC_HelloRes, &pb.HelloRes{Hello: hello}, nil); err != nil {
+    t.Fatal(err)
+  }
+  res := &pb.HelloRes{}
+  if err := ReadRPCMsg(s, pb.RPC_HelloRes, res); err != nil || res.GetHello().GetImpl() != big {
+    t.Fatal("round trip:", err)
+  }
+
+  huge := strings.Repeat("x", MessageSizeMax)
+  s.peerMax = 2 * MessageSizeMax // more than ours: ours applies.
+  if err := WriteRPCMsg(s, pb.RPC_HelloRes, &pb.HelloRes{Hello: &pb.Hello{Impl: &huge}}, nil); !errors.Is(err, ErrMessageTooLarge) {
+    t.Fatal("over our limit:", err)
+  }
+}
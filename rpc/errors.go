@@ -0,0 +1,162 @@
+package xtpctlrpc
+
+import (
+  "context"
+  "errors"
+  "io"
+  "net"
+  "syscall"
+
+  pb "github.com/libp2p/go-xtp-ctl/pb"
+)
+
+var (
+  ErrUnknownRPC      = errors.New("unknown rpc")
+  ErrProtocol        = errors.New("incorrect protocol behavior")
+  ErrNotFound        = errors.New("descriptor not found")
+  ErrInvalidMessage  = errors.New("invalid message")
+  ErrHelloRequired   = errors.New("hello required before other rpcs")
+  ErrVersionMismatch = errors.New("xtp-ctl protocol version mismatch")
+  ErrAddrInUse       = errors.New("address in use")
+  ErrConnRefused     = errors.New("connection refused")
+  ErrTimeout         = errors.New("timeout")
+  ErrCanceled        = errors.New("canceled")
+  ErrClosed          = errors.New("closed")
+  ErrUnsupported     = errors.New("not supported")
+
+  ErrUnauthenticated   = errors.New("not authenticated")
+  ErrPermissionDenied  = errors.New("permission denied")
+  ErrResourceExhausted = errors.New("resource exhausted")
+)
+
+// codeErrs maps the error codes to their sentinel errors.
+var codeErrs = []struct {
+  code pb.ErrCode
+  err  error
+}{
+  {pb.ErrCode_ErrCodeUnknownRPC, ErrUnknownRPC},
+  {pb.ErrCode_ErrCodeProtocol, ErrProtocol},
+  {pb.ErrCode_ErrCodeNotFound, ErrNotFound},
+  {pb.ErrCode_ErrCodeInvalidMessage, ErrInvalidMessage},
+  {pb.ErrCode_ErrCodeHelloRequired, ErrHelloRequired},
+  {pb.ErrCode_ErrCodeVersionMismatch, ErrVersionMismatch},
+  {pb.ErrCode_ErrCodeAddrInUse, ErrAddrInUse},
+  {pb.ErrCode_ErrCodeConnRefused, ErrConnRefused},
+  {pb.ErrCode_ErrCodeTimeout, ErrTimeout},
+  {pb.ErrCode_ErrCodeCanceled, ErrCanceled},
+  {pb.ErrCode_ErrCodeClosed, ErrClosed},
+  {pb.ErrCode_ErrCodeUnsupported, ErrUnsupported},
+  {pb.ErrCode_ErrCodeUnauthenticated, ErrUnauthenticated},
+  {pb.ErrCode_ErrCodePermissionDenied, ErrPermissionDenied},
+  {pb.ErrCode_ErrCodeResourceExhausted, ErrResourceExhausted},
+}
+
+// Error is an error sent by the peer in an rpc. It matches the sentinel
+// error of its code with errors.Is, and is a net.Error.
+type Error struct {
+  Code pb.ErrCode
+  Msg  string
+
+  timeout   bool
+  temporary bool
+}
+
+func (e *Error) Error() string {
+  return e.Msg
+}
+
+func (e *Error) Timeout() bool {
+  return e.timeout
+}
+
+func (e *Error) Temporary() bool {
+  return e.temporary
+}
+
+// Is reports whether target is the sentinel error of e's code. Timeouts
+// and refused connections also match the standard library errors.
+func (e *Error) Is(target error) bool {
+  if target == CodeErr(e.Code) {
+    return true
+  }
+  switch target {
+  case ErrTimeout, context.DeadlineExceeded:
+    return e.timeout
+  case context.Canceled:
+    return e.Code == pb.ErrCode_ErrCodeCanceled
+  case syscall.ECONNREFUSED:
+    return e.Code == pb.ErrCode_ErrCodeConnRefused
+  case syscall.EADDRINUSE:
+    return e.Code == pb.ErrCode_ErrCodeAddrInUse
+  }
+  return false
+}
+
+var _ net.Error = (*Error)(nil)
+
+// CodeErr returns the sentinel error for code, or nil if there is none.
+func CodeErr(code pb.ErrCode) error {
+  for _, ce := range codeErrs {
+    if ce.code == code {
+      return ce.err
+    }
+  }
+  return nil
+}
+
+// ErrCode returns the code that best describes err.
+func ErrCode(err error) pb.ErrCode {
+  for _, ce := range codeErrs {
+    if errors.Is(err, ce.err) {
+      return ce.code
+    }
+  }
+
+  var ne net.Error
+  switch {
+  case errors.Is(err, syscall.EADDRINUSE):
+    return pb.ErrCode_ErrCodeAddrInUse
+  case errors.Is(err, syscall.ECONNREFUSED):
+    return pb.ErrCode_ErrCodeConnRefused
+  case errors.Is(err, context.Canceled):
+    return pb.ErrCode_ErrCodeCanceled
+  case errors.Is(err, context.DeadlineExceeded):
+    return pb.ErrCode_ErrCodeTimeout
+  case errors.Is(err, io.EOF), errors.Is(err, io.ErrClosedPipe), errors.Is(err, net.ErrClosed):
+    return pb.ErrCode_ErrCodeClosed
+  case errors.As(err, &ne) && ne.Timeout():
+    return pb.ErrCode_ErrCodeTimeout
+  }
+  return pb.ErrCode_ErrCodeUnknown
+}
+
+// setRPCError puts err into the rpc envelope.
+func setRPCError(rpc *pb.RPC, err error) {
+  estr := err.Error()
+  code := ErrCode(err)
+  rpc.Error = &estr
+  rpc.ErrCode = &code
+
+  var ne net.Error
+  if errors.As(err, &ne) {
+    timeout, temporary := ne.Timeout(), ne.Temporary()
+    rpc.Timeout = &timeout
+    rpc.Temporary = &temporary
+  } else if code == pb.ErrCode_ErrCodeTimeout {
+    timeout := true
+    rpc.Timeout = &timeout
+  }
+}
+
+// rpcError returns the error carried in the rpc envelope, if any.
+func rpcError(rpc *pb.RPC) error {
+  if rpc.GetError() == "" {
+    return nil
+  }
+  return &Error{
+    Code:      rpc.GetErrCode(),
+    Msg:       rpc.GetError(),
+    timeout:   rpc.GetTimeout() || rpc.GetErrCode() == pb.ErrCode_ErrCodeTimeout,
+    temporary: rpc.GetTemporary(),
+  }
+}
@@ -0,0 +1,109 @@
+package xtpctlrpc
+
+import (
+  "context"
+  "errors"
+  "fmt"
+  "net"
+  "os"
+  "syscall"
+  "testing"
+
+  pb "github.com/libp2p/go-xtp-ctl/pb"
+)
+
+// netErr is a net.Error of the standard library's kind.
+type netErr struct {
+  timeout, temporary bool
+}
+
+func (e netErr) Error() string   { return fmt.Sprintf("net error: timeout %v, temporary %v", e.timeout, e.temporary) }
+func (e netErr) Timeout() bool   { return e.timeout }
+func (e netErr) Temporary() bool { return e.temporary }
+
+// sendErr sends err as the error of a ListenReq, and returns what the
+// peer reads of it.
+func sendErr(t *testing.T, err error) error {
+  t.Helper()
+  s := &bufStream{}
+  if err := ErrRPCRes(s, &pb.RPC{Rpc: pb.RPC_ListenReq.Enum()}, err); err != nil {
+    t.Fatal(err)
+  }
+  got := ReadRPCMsg(s, pb.RPC_ListenRes, &pb.ListenRes{})
+  if got == nil {
+    t.Fatal("the error did not survive the wire")
+  }
+  return got
+}
+
+func TestErrorCodesRoundTrip(t *testing.T) {
+  // every code, but Unknown, has a sentinel.
+  for v, name := range pb.ErrCode_name {
+    if code := pb.ErrCode(v); code != pb.ErrCode_ErrCodeUnknown && CodeErr(code) == nil {
+      t.Error("no sentinel error for", name)
+    }
+  }
+
+  for _, ce := range codeErrs {
+    err := fmt.Errorf("listen /memory/a: %w", ce.err)
+    // as sent by the server, and forwarded once more by a client.
+    got := sendErr(t, sendErr(t, err))
+
+    var rerr *Error
+    if !errors.As(got, &rerr) || rerr.Code != ce.code || got.Error() != err.Error() {
+      t.Errorf("%s: got %#v", ce.code, got)
+      continue
+    }
+    if !errors.Is(got, ce.err) || ErrCode(got) != ce.code {
+      t.Errorf("%s: does not match %q", ce.code, ce.err)
+    }
+    timeout := ce.code == pb.ErrCode_ErrCodeTimeout
+    if rerr.Timeout() != timeout || errors.Is(got, context.DeadlineExceeded) != timeout || rerr.Temporary() {
+      t.Errorf("%s: timeout %v, temporary %v", ce.code, rerr.Timeout(), rerr.Temporary())
+    }
+  }
+}
+
+func TestErrorsRoundTrip(t *testing.T) {
+  cases := []struct {
+    name               string
+    err                error
+    code               pb.ErrCode
+    is                 []error // what the received error must match
+    timeout, temporary bool
+  }{
+    {"refused", &net.OpError{Op: "dial", Err: syscall.ECONNREFUSED}, pb.ErrCode_ErrCodeConnRefused, []error{ErrConnRefused, syscall.ECONNREFUSED}, false, false},
+    {"in use", &net.OpError{Op: "listen", Err: syscall.EADDRINUSE}, pb.ErrCode_ErrCodeAddrInUse, []error{ErrAddrInUse, syscall.EADDRINUSE}, false, false},
+    {"canceled", context.Canceled, pb.ErrCode_ErrCodeCanceled, []error{ErrCanceled, context.Canceled}, false, false},
+    // the standard library's deadlines are temporary timeouts.
+    {"deadline", context.DeadlineExceeded, pb.ErrCode_ErrCodeTimeout, []error{ErrTimeout, context.DeadlineExceeded}, true, true},
+    {"io deadline", os.ErrDeadlineExceeded, pb.ErrCode_ErrCodeTimeout, []error{ErrTimeout}, true, true},
+    {"closed", net.ErrClosed, pb.ErrCode_ErrCodeClosed, []error{ErrClosed}, false, false},
+    {"timeout", netErr{timeout: true}, pb.ErrCode_ErrCodeTimeout, []error{ErrTimeout}, true, false},
+    {"temporary timeout", netErr{timeout: true, temporary: true}, pb.ErrCode_ErrCodeTimeout, []error{ErrTimeout}, true, true},
+    {"temporary", netErr{temporary: true}, pb.ErrCode_ErrCodeUnknown, nil, false, true},
+    {"unknown", errors.New("something else"), pb.ErrCode_ErrCodeUnknown, nil, false, false},
+  }
+
+  for _, tc := range cases {
+    t.Run(tc.name, func(t *testing.T) {
+      got := sendErr(t, sendErr(t, tc.err))
+
+      if ErrCode(got) != tc.code || got.Error() != tc.err.Error() {
+        t.Fatalf("got %s %q, expected %s %q", ErrCode(got), got, tc.code, tc.err)
+      }
+      for _, target := range tc.is {
+        if !errors.Is(got, target) {
+          t.Errorf("does not match %q", target)
+        }
+      }
+      if !tc.timeout && errors.Is(got, ErrTimeout) {
+        t.Error("matches ErrTimeout")
+      }
+      var ne net.Error
+      if !errors.As(got, &ne) || ne.Timeout() != tc.timeout || ne.Temporary() != tc.temporary {
+        t.Errorf("got timeout %v, temporary %v", ne.Timeout(), ne.Temporary())
+      }
+    })
+  }
+}
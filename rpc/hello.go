@@ -0,0 +1,104 @@
+package xtpctlrpc
+
+import (
+  "fmt"
+
+  pb "github.com/libp2p/go-xtp-ctl/pb"
+  xtptrace "github.com/libp2p/go-xtp-ctl/trace"
+)
+
+var (
+  // ProtocolVersion is the version of the xtp-ctl protocol spoken here.
+  // Peers with a different version refuse the session.
+  ProtocolVersion int64 = 1
+
+  // ImplName names this implementation in Hello messages.
+  ImplName = "go-xtp-ctl"
+)
+
+// NewHello returns a Hello for this implementation, advertising the given
+// rpcs and features.
+func NewHello(rpcs []pb.RPC_Type, features []string) *pb.Hello {
+  impl := ImplName
+  version := ProtocolVersion
+  max := int64(MessageSizeMax)
+  return &pb.Hello{
+    Version:        &version,
+    Impl:           &impl,
+    Rpcs:           rpcs,
+    MaxMessageSize: &max,
+    Features:       features,
+  }
+}
+
+// CheckHello checks that the peer's Hello is compatible with us.
+func CheckHello(h *pb.Hello) error {
+  if h == nil || h.Version == nil {
+    return ErrInvalidMessage
+  }
+  if v := h.GetVersion(); v != ProtocolVersion {
+    return fmt.Errorf("%w: local %d, remote %d (%s)",
+      ErrVersionMismatch, ProtocolVersion, v, h.GetImpl())
+  }
+  return nil
+}
+
+// Supports returns whether the peer that sent h handles rpc t.
+func Supports(h *pb.Hello, t pb.RPC_Type) bool {
+  for _, t2 := range h.GetRpcs() {
+    if t2 == t {
+      return true
+    }
+  }
+  return false
+}
+
+// HasFeature returns whether the peer that sent h has feature f.
+func HasFeature(h *pb.Hello, f string) bool {
+  for _, f2 := range h.GetFeatures() {
+    if f2 == f {
+      return true
+    }
+  }
+  return false
+}
+
+// HelloReq sends our Hello, and returns the peer's.
+func HelloReq(s IoStream, h *pb.Hello) (*pb.Hello, error) {
+  res, err := ResumeReq(s, h, nil)
+  if err != nil {
+    return nil, err
+  }
+  return res.Hello, nil
+}
+
+// ResumeReq is HelloReq, resuming the ended session with token resume,
+// unless it is nil. It returns the whole response: its Session is the
+// token to resume this session with, if the server keeps ended sessions.
+func ResumeReq(s IoStream, h *pb.Hello, resume []byte) (_ *pb.HelloRes, err error) {
+  s, span := startReq(s, pb.RPC_HelloReq)
+  defer func() { xtptrace.End(span, err) }()
+
+  // send the request
+  err = WriteRPCMsg(s, pb.RPC_HelloReq, &pb.HelloReq{Hello: h, Resume: resume}, nil)
+  if err != nil {
+    return nil, err
+  }
+
+  // now get the response
+  res := &pb.HelloRes{}
+  if err := ReadRPCMsg(s, pb.RPC_HelloRes, res); err != nil {
+    return nil, err
+  }
+  if err := CheckHello(res.Hello); err != nil {
+    return nil, err
+  }
+  return res, nil
+}
+
+// HelloRes answers a HelloReq with our Hello, and the token to resume the
+// session with, if any.
+func HelloRes(s IoStream, h *pb.Hello, session []byte, err error) error {
+  // send the response
+  return WriteRPCMsg(s, pb.RPC_HelloRes, &pb.HelloRes{Hello: h, Session: session}, err)
+}
@@ -3,37 +3,77 @@ package xtpctlrpc
 import (
   ma "github.com/multiformats/go-multiaddr"
   pb "github.com/libp2p/go-xtp-ctl/pb"
+  xtptrace "github.com/libp2p/go-xtp-ctl/trace"
+
+  proto "gx/ipfs/QmZ4Qi3GaRbjcx28Sme5eMH7RQjGkt8wHxt2a65oLaeFEV/gogo-protobuf/proto"
 )
 
+// NoOpReq sends a NoOp, and waits for it to come back.
+func NoOpReq(s IoStream) (err error) {
+  s, span := startReq(s, pb.RPC_NoOp)
+  defer func() { xtptrace.End(span, err) }()
+
+  if err := WriteRPCMsg(s, pb.RPC_NoOp, nil, nil); err != nil {
+    return err
+  }
+  return ReadRPCMsg(s, pb.RPC_NoOp, nil)
+}
+
 func ListReq(s IoStream, types []pb.TType) ([]*pb.ListRes_Item, error) {
+  return listReq(s, &pb.ListReq{Types: types})
+}
+
+// ListStatsReq is ListReq, with the stats of each item.
+func ListStatsReq(s IoStream, types []pb.TType) ([]*pb.ListRes_Item, error) {
+  stats := true
+  return listReq(s, &pb.ListReq{Types: types, Stats: &stats})
+}
+
+func listReq(s IoStream, req *pb.ListReq) (_ []*pb.ListRes_Item, err error) {
+  s, span := startReq(s, pb.RPC_ListReq)
+  defer func() { xtptrace.End(span, err) }()
+
   // send the request
-  err := WriteRPCMsg(s, pb.RPC_ListReq, &pb.ListReq{Types: types}, nil)
+  err = WriteRPCMsg(s, pb.RPC_ListReq, req, nil)
   if err != nil {
     return nil, err
   }
 
-  // now get the response
-  res := pb.ListRes{}
-  if err := ReadRPCMsg(s, pb.RPC_ListRes, &res); err != nil {
-    return nil, err
-  }
+  // now get the response, in pages.
   var is []*pb.ListRes_Item
-  for _, i := range res.Items {
-    if i.Valid() {
-      is = append(is, i)
+  for more := true; more; {
+    res := pb.ListRes{}
+    if err := ReadRPCMsg(s, pb.RPC_ListRes, &res); err != nil {
+      return nil, err
+    }
+    for _, i := range res.Items {
+      if i.Valid() {
+        is = append(is, i)
+      }
     }
+    more = res.GetMore()
   }
   return is, nil
 }
 
+// ListRes sends items, in as many ListRes as it takes to keep each
+// within the rpc size limit.
 func ListRes(s IoStream, items []*pb.ListRes_Item, err error) error {
-  // send the response
-  return WriteRPCMsg(s, pb.RPC_ListRes, &pb.ListRes{Items: items}, err)
+  if err != nil {
+    return WriteRPCMsg(s, pb.RPC_ListRes, nil, err)
+  }
+  size := func(i int) int { return proto.Size(items[i]) }
+  return writePages(s, pb.RPC_ListRes, len(items), size, func(i, j int, more bool) proto.Message {
+    return &pb.ListRes{Items: items[i:j], More: &more}
+  })
 }
 
-func CloseReq(s IoStream, id int64) error {
+func CloseReq(s IoStream, id int64) (err error) {
+  s, span := startReq(s, pb.RPC_CloseReq)
+  defer func() { xtptrace.End(span, err) }()
+
   // send the request
-  err := WriteRPCMsg(s, pb.RPC_CloseReq, &pb.CloseReq{Id: &id}, nil)
+  err = WriteRPCMsg(s, pb.RPC_CloseReq, &pb.CloseReq{Id: &id}, nil)
   if err != nil {
     return err
   }
@@ -42,7 +82,10 @@ func CloseReq(s IoStream, id int64) error {
   return ReadRPCMsg(s, pb.RPC_CloseRes, nil)
 }
 
-func ListenReq(s IoStream, tid int64, laddr ma.Multiaddr) (*pb.Listener, error) {
+func ListenReq(s IoStream, tid int64, laddr ma.Multiaddr) (_ *pb.Listener, err error) {
+  s, span := startReq(s, pb.RPC_ListenReq)
+  defer func() { xtptrace.End(span, err) }()
+
   // send the request
   req := &pb.ListenReq{
     ListenerOpts: &pb.Listener{
@@ -50,7 +93,7 @@ func ListenReq(s IoStream, tid int64, laddr ma.Multiaddr) (*pb.Listener, error)
       Multiaddr:   laddr.Bytes(),
     },
   }
-  err := WriteRPCMsg(s, pb.RPC_ListReq, req, nil)
+  err = WriteRPCMsg(s, pb.RPC_ListenReq, req, nil)
   if err != nil {
     return nil, err
   }
@@ -67,9 +110,15 @@ func ListenRes(s IoStream, l *pb.Listener, err error) error {
   return WriteRPCMsg(s, pb.RPC_ListenRes, &pb.ListenRes{Listener: l}, err)
 }
 
-func AcceptReq(s IoStream, id int64) (*pb.AcceptRes, error) {
+// AcceptReq accepts a conn from a listener, or a stream from a conn.
+// It blocks until the server responds. To abort it, close s: the server
+// then aborts the accept.
+func AcceptReq(s IoStream, id int64) (_ *pb.AcceptRes, err error) {
+  s, span := startReq(s, pb.RPC_AcceptReq)
+  defer func() { xtptrace.End(span, err) }()
+
   // send the request
-  err := WriteRPCMsg(s, pb.RPC_AcceptReq, &pb.AcceptReq{Id: &id}, nil)
+  err = WriteRPCMsg(s, pb.RPC_AcceptReq, &pb.AcceptReq{Id: &id}, nil)
   if err != nil {
     return nil, err
   }
@@ -87,7 +136,10 @@ func AcceptRes(s IoStream, conn *pb.Conn, st *pb.Stream, err error) error {
 }
 
 // todo: connOpts
-func DialerReq(s IoStream, tid int64, laddr ma.Multiaddr) (*pb.Dialer, error) {
+func DialerReq(s IoStream, tid int64, laddr ma.Multiaddr) (_ *pb.Dialer, err error) {
+  s, span := startReq(s, pb.RPC_DialerReq)
+  defer func() { xtptrace.End(span, err) }()
+
   // send the request
   req := &pb.DialerReq{
     DialerOpts: &pb.Dialer{
@@ -95,7 +147,7 @@ func DialerReq(s IoStream, tid int64, laddr ma.Multiaddr) (*pb.Dialer, error) {
       Multiaddr:   laddr.Bytes(),
     },
   }
-  err := WriteRPCMsg(s, pb.RPC_DialerReq, req, nil)
+  err = WriteRPCMsg(s, pb.RPC_DialerReq, req, nil)
   if err != nil {
     return nil, err
   }
@@ -112,15 +164,19 @@ func DialerRes(s IoStream, d *pb.Dialer, err error) error {
   return WriteRPCMsg(s, pb.RPC_DialerRes, &pb.DialerRes{Dialer: d}, err)
 }
 
-func DialReq(s IoStream, id int64, raddr ma.Multiaddr) (*pb.DialRes, error) {
+// DialReq dials raddr from a transport or dialer, or opens a new
+// stream on a conn (raddr is nil then). Like AcceptReq, closing s
+// aborts it.
+func DialReq(s IoStream, id int64, raddr ma.Multiaddr) (_ *pb.DialRes, err error) {
+  s, span := startReq(s, pb.RPC_DialReq)
+  defer func() { xtptrace.End(span, err) }()
+
   // send the request
-  req := &pb.DialReq{
-    Id: &id,
-    ConnOpts: &pb.Conn{
-      RemoteMultiaddr: raddr.Bytes(),
-    },
+  req := &pb.DialReq{Id: &id}
+  if raddr != nil {
+    req.ConnOpts = &pb.Conn{RemoteMultiaddr: raddr.Bytes()}
   }
-  err := WriteRPCMsg(s, pb.RPC_DialReq, req, nil)
+  err = WriteRPCMsg(s, pb.RPC_DialReq, req, nil)
   if err != nil {
     return nil, err
   }
@@ -134,5 +190,153 @@ func DialReq(s IoStream, id int64, raddr ma.Multiaddr) (*pb.DialRes, error) {
 }
 
 func DialRes(s IoStream, conn *pb.Conn, st *pb.Stream, err error) error {
-  return WriteRPCMsg(s, pb.RPC_DialReq, &pb.DialRes{Conn: conn, Stream: st}, err)
+  return WriteRPCMsg(s, pb.RPC_DialRes, &pb.DialRes{Conn: conn, Stream: st}, err)
+}
+
+// ShutdownReq shuts down stream id: see pb.ShutdownReq_How. Streams are
+// half-closed for writing in-band, by half-closing the xtp-ctl stream
+// that carries their data.
+func ShutdownReq(s IoStream, id int64, how pb.ShutdownReq_How) (err error) {
+  s, span := startReq(s, pb.RPC_ShutdownReq)
+  defer func() { xtptrace.End(span, err) }()
+
+  // send the request
+  req := &pb.ShutdownReq{Id: &id, How: &how}
+  if err := WriteRPCMsg(s, pb.RPC_ShutdownReq, req, nil); err != nil {
+    return err
+  }
+
+  // now get the response
+  return ReadRPCMsg(s, pb.RPC_ShutdownRes, nil)
+}
+
+// WatchReq starts watching the descriptors of the given types, on the
+// given transports (empty means all). Once it returns, the events come
+// in with NextWatchRes, until s is closed.
+func WatchReq(s IoStream, types []pb.TType, tids []int64) (err error) {
+  s, span := startReq(s, pb.RPC_WatchReq)
+  defer func() { xtptrace.End(span, err) }()
+
+  // send the request
+  req := &pb.WatchReq{Types: types, TransportIds: tids}
+  if err := WriteRPCMsg(s, pb.RPC_WatchReq, req, nil); err != nil {
+    return err
+  }
+
+  // now wait for the watch to start
+  res, err := NextWatchRes(s)
+  if err != nil {
+    return err
+  }
+  if res.GetEvent() != pb.WatchRes_Watching {
+    return ErrInvalidMessage
+  }
+  return nil
+}
+
+// NextWatchRes reads the next event of a watch.
+func NextWatchRes(s IoStream) (*pb.WatchRes, error) {
+  res := pb.WatchRes{}
+  if err := ReadRPCMsg(s, pb.RPC_WatchRes, &res); err != nil {
+    return nil, err
+  }
+  if res.Event == nil {
+    return nil, ErrInvalidMessage
+  }
+  return &res, nil
+}
+
+func WatchRes(s IoStream, res *pb.WatchRes, err error) error {
+  return WriteRPCMsg(s, pb.RPC_WatchRes, res, err)
+}
+
+// StatsReq gets the stats of descriptors ids, or of all descriptors if
+// there are none.
+func StatsReq(s IoStream, ids []int64) (_ []*pb.Stats, err error) {
+  s, span := startReq(s, pb.RPC_StatsReq)
+  defer func() { xtptrace.End(span, err) }()
+
+  // send the request
+  if err := WriteRPCMsg(s, pb.RPC_StatsReq, &pb.StatsReq{Ids: ids}, nil); err != nil {
+    return nil, err
+  }
+
+  // now get the response, in pages.
+  var ss []*pb.Stats
+  for more := true; more; {
+    res := pb.StatsRes{}
+    if err := ReadRPCMsg(s, pb.RPC_StatsRes, &res); err != nil {
+      return nil, err
+    }
+    for _, st := range res.Stats {
+      if st.Valid() {
+        ss = append(ss, st)
+      }
+    }
+    more = res.GetMore()
+  }
+  return ss, nil
+}
+
+// StatsRes sends ss, in as many StatsRes as it takes to keep each within
+// the rpc size limit.
+func StatsRes(s IoStream, ss []*pb.Stats, err error) error {
+  if err != nil {
+    return WriteRPCMsg(s, pb.RPC_StatsRes, nil, err)
+  }
+  size := func(i int) int { return proto.Size(ss[i]) }
+  return writePages(s, pb.RPC_StatsRes, len(ss), size, func(i, j int, more bool) proto.Message {
+    return &pb.StatsRes{Stats: ss[i:j], More: &more}
+  })
+}
+
+// AuthReq sends an auth request, and returns the response.
+func AuthReq(s IoStream, req *pb.AuthReq) (_ *pb.AuthRes, err error) {
+  s, span := startReq(s, pb.RPC_AuthReq)
+  defer func() { xtptrace.End(span, err) }()
+
+  // send the request
+  if err := WriteRPCMsg(s, pb.RPC_AuthReq, req, nil); err != nil {
+    return nil, err
+  }
+
+  // now get the response
+  res := &pb.AuthRes{}
+  if err := ReadRPCMsg(s, pb.RPC_AuthRes, res); err != nil {
+    return nil, err
+  }
+  return res, nil
+}
+
+func AuthRes(s IoStream, res *pb.AuthRes, err error) error {
+  return WriteRPCMsg(s, pb.RPC_AuthRes, res, err)
+}
+
+// UsageReq gets what the client uses, against its limits.
+func UsageReq(s IoStream) (_ *pb.UsageRes, err error) {
+  s, span := startReq(s, pb.RPC_UsageReq)
+  defer func() { xtptrace.End(span, err) }()
+
+  // send the request
+  if err := WriteRPCMsg(s, pb.RPC_UsageReq, &pb.UsageReq{}, nil); err != nil {
+    return nil, err
+  }
+
+  // now get the response
+  res := &pb.UsageRes{}
+  if err := ReadRPCMsg(s, pb.RPC_UsageRes, res); err != nil {
+    return nil, err
+  }
+  return res, nil
+}
+
+func UsageRes(s IoStream, res *pb.UsageRes, err error) error {
+  return WriteRPCMsg(s, pb.RPC_UsageRes, res, err)
+}
+
+// AuthMessage returns what clients sign to authenticate with an ed25519
+// key: the server's challenge, prefixed so the signature can't be used
+// for anything else.
+func AuthMessage(challenge []byte) []byte {
+  return append([]byte("xtp-ctl auth\x00"), challenge...)
 }
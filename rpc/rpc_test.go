@@ -0,0 +1,59 @@
+package xtpctlrpc
+
+import (
+  "bytes"
+  "io"
+  "testing"
+
+  pb "github.com/libp2p/go-xtp-ctl/pb"
+  ma "github.com/multiformats/go-multiaddr"
+)
+
+// recStream records what is written to it. Reads get io.EOF.
+type recStream struct {
+  w bytes.Buffer
+}
+
+func (s *recStream) Read(buf []byte) (int, error)  { return 0, io.EOF }
+func (s *recStream) Write(buf []byte) (int, error) { return s.w.Write(buf) }
+func (s *recStream) Close() error                  { return nil }
+
+// sent returns the type of the rpc written to s.
+func (s *recStream) sent(t *testing.T) pb.RPC_Type {
+  t.Helper()
+  b := &bufStream{}
+  b.Write(s.w.Bytes())
+  rpc := &pb.RPC{}
+  if err := ReadRPC(b, rpc); err != nil {
+    t.Fatal(err)
+  }
+  return rpc.GetRpc()
+}
+
+func TestRequestWireTypes(t *testing.T) {
+  s := &recStream{}
+  ListenReq(s, 1, ma.StringCast("/ip4/127.0.0.1/tcp/0"))
+  if typ := s.sent(t); typ != pb.RPC_ListenReq {
+    t.Error("ListenReq sent as", typ)
+  }
+
+  s = &recStream{}
+  DialReq(s, 1, ma.StringCast("/ip4/127.0.0.1/tcp/1"))
+  if typ := s.sent(t); typ != pb.RPC_DialReq {
+    t.Error("DialReq sent as", typ)
+  }
+}
+
+func TestResponseWireTypes(t *testing.T) {
+  s := &recStream{}
+  DialRes(s, &pb.Conn{}, nil, nil)
+  if typ := s.sent(t); typ != pb.RPC_DialRes {
+    t.Error("DialRes sent as", typ)
+  }
+
+  s = &recStream{}
+  ListenRes(s, &pb.Listener{}, nil)
+  if typ := s.sent(t); typ != pb.RPC_ListenRes {
+    t.Error("ListenRes sent as", typ)
+  }
+}
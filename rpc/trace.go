@@ -0,0 +1,79 @@
+package xtpctlrpc
+
+import (
+  "context"
+
+  pb "github.com/libp2p/go-xtp-ctl/pb"
+  xtptrace "github.com/libp2p/go-xtp-ctl/trace"
+
+  "go.opentelemetry.io/otel/propagation"
+  "go.opentelemetry.io/otel/trace"
+)
+
+// WithContext returns s carrying ctx. Requests sent on it with the
+// helpers of this package are traced as children of the span in ctx.
+func WithContext(ctx context.Context, s IoStream) IoStream {
+  if cs, ok := s.(*ctxStream); ok {
+    s = cs.IoStream
+  }
+  return &ctxStream{s, ctx}
+}
+
+type ctxStream struct {
+  IoStream
+  ctx context.Context
+}
+
+func streamContext(s IoStream) context.Context {
+  if cs, ok := s.(*ctxStream); ok {
+    return cs.ctx
+  }
+  return context.Background()
+}
+
+// startReq starts the span of a request of type typ, sent on s. The
+// returned stream carries the span, for WriteRPCMsg to send along.
+func startReq(s IoStream, typ pb.RPC_Type) (IoStream, trace.Span) {
+  ctx, span := xtptrace.Start(streamContext(s), "xrpc."+typ.String())
+  if !span.SpanContext().IsValid() {
+    return s, span // not tracing.
+  }
+  return WithContext(ctx, s), span
+}
+
+// traceContext carries W3C trace context in rpcs. Only its traceparent:
+// the envelope has no room for tracestate.
+var traceContext = propagation.TraceContext{}
+
+// rpcCarrier is a propagation.TextMapCarrier over an rpc envelope.
+type rpcCarrier struct {
+  rpc *pb.RPC
+}
+
+func (c rpcCarrier) Get(key string) string {
+  if key == "traceparent" {
+    return c.rpc.GetTraceparent()
+  }
+  return ""
+}
+
+func (c rpcCarrier) Set(key, value string) {
+  if key == "traceparent" {
+    c.rpc.Traceparent = &value
+  }
+}
+
+func (c rpcCarrier) Keys() []string {
+  return []string{"traceparent"}
+}
+
+// setTraceparent puts the trace context of s, if any, in the rpc envelope.
+func setTraceparent(rpc *pb.RPC, s IoStream) {
+  traceContext.Inject(streamContext(s), rpcCarrier{rpc})
+}
+
+// ExtractTrace returns ctx with the trace context sent with rpc, if any,
+// as the remote parent of the spans started with it.
+func ExtractTrace(ctx context.Context, rpc *pb.RPC) context.Context {
+  return traceContext.Extract(ctx, rpcCarrier{rpc})
+}
@@ -0,0 +1,42 @@
+package xtpctlrpc
+
+import (
+  "context"
+  "strings"
+  "testing"
+
+  pb "github.com/libp2p/go-xtp-ctl/pb"
+
+  "go.opentelemetry.io/otel/trace"
+)
+
+// TestTraceparent checks that the trace context, flags included, gets
+// through the rpc envelope.
+func TestTraceparent(t *testing.T) {
+  for _, flags := range []trace.TraceFlags{0, trace.FlagsSampled} {
+    sc := trace.NewSpanContext(trace.SpanContextConfig{
+      TraceID:    trace.TraceID{1, 2, 3},
+      SpanID:     trace.SpanID{4, 5, 6},
+      TraceFlags: flags,
+    })
+    s := WithContext(trace.ContextWithSpanContext(context.Background(), sc), &recStream{})
+
+    rpc := &pb.RPC{}
+    setTraceparent(rpc, s)
+    if want := "-" + flags.String(); !strings.HasSuffix(rpc.GetTraceparent(), want) {
+      t.Fatalf("traceparent %q, expected flags %s", rpc.GetTraceparent(), flags)
+    }
+
+    got := trace.SpanContextFromContext(ExtractTrace(context.Background(), rpc))
+    if !got.IsRemote() || !got.Equal(sc.WithRemote(true)) {
+      t.Fatalf("extracted %v, expected %v", got, sc)
+    }
+  }
+
+  // no trace context, no traceparent.
+  rpc := &pb.RPC{}
+  setTraceparent(rpc, &recStream{})
+  if rpc.Traceparent != nil {
+    t.Fatal("traceparent without a trace:", rpc.GetTraceparent())
+  }
+}
@@ -0,0 +1,188 @@
+package xtpserver
+
+import (
+  "crypto/ed25519"
+  "crypto/rand"
+  "fmt"
+
+  xnet "github.com/libp2p/go-xtp-ctl/net"
+  pb "github.com/libp2p/go-xtp-ctl/pb"
+  xrpc "github.com/libp2p/go-xtp-ctl/rpc"
+  ma "github.com/multiformats/go-multiaddr"
+)
+
+// featureAuth is in the server's Hello when it has a Policy: clients
+// should authenticate.
+const featureAuth = "auth"
+
+// challengeSize is the size of the challenges ed25519 keys sign.
+const challengeSize = 32
+
+// auth is who a client authenticated as, and what it may do.
+type auth struct {
+  identity  string // "" until authenticated
+  grant     *Grant
+  challenge []byte // the last challenge sent, until used
+}
+
+// policy returns the server's policy, if any.
+func (sc *ServerClient) policy() *Policy {
+  if sc.Server == nil {
+    return nil
+  }
+  return sc.Server.Policy
+}
+
+// Identity returns who the client authenticated as, or "".
+func (sc *ServerClient) Identity() string {
+  sc.alk.Lock()
+  defer sc.alk.Unlock()
+  return sc.auth.identity
+}
+
+// initAuth gives a new client the anonymous grant, or authenticates it
+// with its TLS certificate.
+func (sc *ServerClient) initAuth() {
+  p := sc.policy()
+  if p == nil {
+    return
+  }
+  sc.auth.grant = p.grant("")
+
+  if !p.TLSIdentity {
+    return
+  }
+  cert, ok := xnet.PeerCertificate(sc.Conn)
+  if !ok {
+    return
+  }
+  if id := cert.Subject.CommonName; id != "" {
+    sc.setIdentity(id, "tls")
+  }
+}
+
+func (sc *ServerClient) setIdentity(id, method string) {
+  sc.alk.Lock()
+  sc.auth.identity = id
+  sc.auth.grant = sc.policy().grant(id)
+  sc.alk.Unlock()
+  sc.log.Info("client authenticated", "identity", id, "method", method)
+}
+
+// grant returns what the client may do, and whether it is limited at all.
+func (sc *ServerClient) grant() (*Grant, bool) {
+  if sc.policy() == nil {
+    return nil, false
+  }
+  sc.alk.Lock()
+  defer sc.alk.Unlock()
+  return sc.auth.grant, true
+}
+
+// authorizeRPC checks that the client may send request t.
+func (sc *ServerClient) authorizeRPC(t pb.RPC_Type) error {
+  g, limited := sc.grant()
+  if !limited || t == pb.RPC_HelloReq || t == pb.RPC_AuthReq {
+    return nil
+  }
+  if g == nil {
+    return xrpc.ErrUnauthenticated
+  }
+  if !g.AllowsRPC(t) {
+    return denied("rpc %s", t)
+  }
+  return nil
+}
+
+// allowsTransport returns whether the client may use t.
+func (sc *ServerClient) allowsTransport(t *transport) bool {
+  g, limited := sc.grant()
+  return !limited || g.AllowsTransport(t.rawT.Code())
+}
+
+// authorizeTransport checks that the client may use t.
+func (sc *ServerClient) authorizeTransport(t *transport) error {
+  if !sc.allowsTransport(t) {
+    return denied("transport %s", t.rawT.Code())
+  }
+  return nil
+}
+
+// authorizeListen checks that the client may listen on laddr (or bind a
+// dialer to it) with t.
+func (sc *ServerClient) authorizeListen(t *transport, laddr ma.Multiaddr) error {
+  if err := sc.authorizeTransport(t); err != nil {
+    return err
+  }
+  g, limited := sc.grant()
+  if limited && !g.AllowsListen(laddr) {
+    return denied("listen on %s", laddr)
+  }
+  return nil
+}
+
+// authorizeDial checks that the client may dial raddr with t.
+func (sc *ServerClient) authorizeDial(t *transport, raddr ma.Multiaddr) error {
+  if err := sc.authorizeTransport(t); err != nil {
+    return err
+  }
+  g, limited := sc.grant()
+  if limited && !g.AllowsDial(raddr) {
+    return denied("dial %s", raddr)
+  }
+  return nil
+}
+
+func handleAuthReq(sc *ServerClient, s *rpcCall, req *pb.AuthReq) error {
+  p := sc.policy()
+  if p == nil {
+    return xrpc.AuthRes(s, &pb.AuthRes{}, nil) // everyone is welcome.
+  }
+
+  var id string
+  switch req.GetMethod() {
+  case pb.AuthReq_Token:
+    var ok bool
+    if id, ok = p.tokenIdentity(req.GetToken()); !ok {
+      return fmt.Errorf("%w: invalid token", xrpc.ErrUnauthenticated)
+    }
+
+  case pb.AuthReq_Challenge:
+    c := make([]byte, challengeSize)
+    if _, err := rand.Read(c); err != nil {
+      return err
+    }
+    sc.alk.Lock()
+    sc.auth.challenge = c
+    sc.alk.Unlock()
+    return xrpc.AuthRes(s, &pb.AuthRes{Challenge: c}, nil)
+
+  case pb.AuthReq_Ed25519:
+    // challenges are good for one try.
+    sc.alk.Lock()
+    c := sc.auth.challenge
+    sc.auth.challenge = nil
+    sc.alk.Unlock()
+    if c == nil {
+      return fmt.Errorf("%w: no challenge", xrpc.ErrProtocol)
+    }
+
+    pub := ed25519.PublicKey(req.GetPublicKey())
+    if len(pub) != ed25519.PublicKeySize {
+      return xrpc.ErrInvalidMessage
+    }
+    var ok bool
+    if id, ok = p.keyIdentity(pub); !ok {
+      return fmt.Errorf("%w: unknown key", xrpc.ErrUnauthenticated)
+    }
+    if !ed25519.Verify(pub, xrpc.AuthMessage(c), req.GetSignature()) {
+      return fmt.Errorf("%w: invalid signature", xrpc.ErrUnauthenticated)
+    }
+
+  default:
+    return xrpc.ErrInvalidMessage
+  }
+
+  sc.setIdentity(id, req.GetMethod().String())
+  return xrpc.AuthRes(s, &pb.AuthRes{Identity: &id}, nil)
+}
@@ -0,0 +1,274 @@
+package xtpserver_test
+
+import (
+  "crypto/ed25519"
+  "crypto/tls"
+  "crypto/x509"
+  "encoding/base64"
+  "errors"
+  "testing"
+
+  xclient "github.com/libp2p/go-xtp-ctl/client"
+  ximpls "github.com/libp2p/go-xtp-ctl/impls"
+  xnet "github.com/libp2p/go-xtp-ctl/net"
+  pb "github.com/libp2p/go-xtp-ctl/pb"
+  xrpc "github.com/libp2p/go-xtp-ctl/rpc"
+  xserver "github.com/libp2p/go-xtp-ctl/server"
+  "github.com/libp2p/go-xtp-ctl/xtptest"
+  ma "github.com/multiformats/go-multiaddr"
+)
+
+// testPolicy lets "admin" do anything, and "ci" listen and dial on
+// /memory/ok only. Other identities get to list, anonymous clients
+// nothing.
+func testPolicy(t *testing.T, admin ed25519.PublicKey) *xserver.Policy {
+  p := &xserver.Policy{
+    Tokens: map[string]string{"ci-token": "ci", "admin-token": "admin", "guest-token": "guest"},
+    Keys:   map[string]string{base64.StdEncoding.EncodeToString(admin): "admin"},
+    Clients: map[string]*xserver.Grant{
+      "admin": {Transports: []string{"*"}, Listen: []string{"*"}, Dial: []string{"*"}, RPCs: []string{"*"}},
+      "ci": {
+        Transports: []string{"/memory"},
+        Listen:     []string{"/memory/ok"},
+        Dial:       []string{"/memory/ok"},
+        RPCs:       []string{"ListReq", "ListenReq", "DialReq", "CloseReq", "StatsReq", "WatchReq"},
+      },
+    },
+    Default: &xserver.Grant{RPCs: []string{"ListReq"}},
+  }
+  if err := p.Compile(); err != nil {
+    t.Fatal(err)
+  }
+  return p
+}
+
+// startPolicy starts a server with policy p, offering /tcp and /memory.
+func startPolicy(t *testing.T, p *xserver.Policy) ma.Multiaddr {
+  xports := []xnet.Transport{&ximpls.TCPTransport{}, &ximpls.MemoryTransport{}}
+  s, err := xserver.NewServer(xtptest.TCPAddr, xports)
+  if err != nil {
+    t.Fatal(err)
+  }
+  s.Policy = p
+  go s.Serve()
+  t.Cleanup(func() { s.Close() })
+  return s.Listener.Multiaddr()
+}
+
+func connect(t *testing.T, saddr ma.Multiaddr, cred *xclient.Credentials) *xclient.Client {
+  c, err := xclient.NewAuthClient(saddr, nil, cred)
+  if err != nil {
+    t.Fatal("connecting:", err)
+  }
+  t.Cleanup(func() { c.Close() })
+  return c
+}
+
+func TestAuthenticate(t *testing.T) {
+  pub, priv, _ := ed25519.GenerateKey(nil)
+  _, stranger, _ := ed25519.GenerateKey(nil)
+  saddr := startPolicy(t, testPolicy(t, pub))
+
+  cases := []struct {
+    name     string
+    cred     *xclient.Credentials
+    identity string
+    err      error
+  }{
+    {"token", &xclient.Credentials{Token: "ci-token"}, "ci", nil},
+    {"key", &xclient.Credentials{Key: priv}, "admin", nil},
+    {"default grant", &xclient.Credentials{Token: "guest-token"}, "guest", nil},
+    {"anonymous", nil, "", xrpc.ErrUnauthenticated},
+    {"bad token", &xclient.Credentials{Token: "nope"}, "", xrpc.ErrUnauthenticated},
+    {"unknown key", &xclient.Credentials{Key: stranger}, "", xrpc.ErrUnauthenticated},
+  }
+  for _, c := range cases {
+    client, err := xclient.NewAuthClient(saddr, nil, c.cred)
+    if !errors.Is(err, c.err) {
+      t.Errorf("%s: connecting: %v, expected %v", c.name, err, c.err)
+    }
+    if client != nil {
+      if client.Identity != c.identity {
+        t.Errorf("%s: identity %q, expected %q", c.name, client.Identity, c.identity)
+      }
+      client.Close()
+    }
+  }
+}
+
+func TestAuthorize(t *testing.T) {
+  pub, _, _ := ed25519.GenerateKey(nil)
+  saddr := startPolicy(t, testPolicy(t, pub))
+
+  ci := connect(t, saddr, &xclient.Credentials{Token: "ci-token"})
+  if ci.Transport("/tcp") != nil {
+    t.Error("ci sees /tcp")
+  }
+  mem := ci.Transport("/memory")
+  if mem == nil {
+    t.Fatal("ci does not see /memory")
+  }
+
+  l, err := mem.Listen(ma.StringCast("/memory/ok"))
+  if err != nil {
+    t.Fatal("allowed listen:", err)
+  }
+  defer l.Close()
+  go func() {
+    if c, err := l.Accept(); err == nil {
+      c.Close()
+    }
+  }()
+  c, err := mem.Dial(ma.StringCast("/memory/ok"))
+  if err != nil {
+    t.Fatal("allowed dial:", err)
+  }
+  c.Close()
+
+  if _, err := mem.Listen(ma.StringCast("/memory/other")); !errors.Is(err, xrpc.ErrPermissionDenied) {
+    t.Error("listen out of the grant:", err)
+  }
+  if _, err := mem.Dial(ma.StringCast("/memory/other")); !errors.Is(err, xrpc.ErrPermissionDenied) {
+    t.Error("dial out of the grant:", err)
+  }
+  if _, err := mem.Dialer(ma.StringCast("/memory/ok")); !errors.Is(err, xrpc.ErrPermissionDenied) {
+    t.Error("rpc out of the grant:", err)
+  }
+
+  // the default grant lists, and nothing else.
+  guest := connect(t, saddr, &xclient.Credentials{Token: "guest-token"})
+  if len(guest.Xports) != 0 {
+    t.Error("guest sees transports:", len(guest.Xports))
+  }
+  s, err := guest.Stream()
+  if err != nil {
+    t.Fatal(err)
+  }
+  defer s.Close()
+  if err := xrpc.NoOpReq(s); !errors.Is(err, xrpc.ErrPermissionDenied) {
+    t.Error("guest NoOp:", err)
+  }
+}
+
+// TestPolicyHidesTransports checks that stats and watches, like lists,
+// leave out the transports the client may not use: here, descriptors
+// opened before it authenticated again, with less rights.
+func TestPolicyHidesTransports(t *testing.T) {
+  pub, _, _ := ed25519.GenerateKey(nil)
+  saddr := startPolicy(t, testPolicy(t, pub))
+
+  c := connect(t, saddr, &xclient.Credentials{Token: "admin-token"})
+  tcpId := c.Transport("/tcp").(interface{ Id() int64 }).Id()
+  tl, err := c.Transport("/tcp").Listen(ma.StringCast("/ip4/127.0.0.1/tcp/0"))
+  if err != nil {
+    t.Fatal(err)
+  }
+  ml, err := c.Transport("/memory").Listen(ma.StringCast("/memory/ok"))
+  if err != nil {
+    t.Fatal(err)
+  }
+  tlId := tl.(interface{ Id() int64 }).Id()
+  mlId := ml.(interface{ Id() int64 }).Id()
+
+  if err := c.Authenticate(&xclient.Credentials{Token: "ci-token"}); err != nil {
+    t.Fatal(err)
+  }
+
+  ss, err := c.Stats()
+  if err != nil {
+    t.Fatal(err)
+  }
+  for _, st := range ss {
+    if st.GetId() == tcpId || st.GetId() == tlId {
+      t.Errorf("stats of hidden descriptor %d", st.GetId())
+    }
+  }
+  for _, id := range []int64{tcpId, tlId} {
+    if _, err := c.Stats(id); !errors.Is(err, xrpc.ErrNotFound) {
+      t.Errorf("stats of hidden descriptor %d: %v", id, err)
+    }
+  }
+
+  w, err := c.Watch(nil)
+  if err != nil {
+    t.Fatal(err)
+  }
+  defer w.Close()
+  s, err := c.Stream()
+  if err != nil {
+    t.Fatal(err)
+  }
+  defer s.Close()
+  for _, id := range []int64{tlId, mlId} {
+    if err := xrpc.CloseReq(s, id); err != nil {
+      t.Fatal(err)
+    }
+  }
+
+  // the /tcp listener closed first, unseen.
+  res, err := w.Next()
+  if err != nil {
+    t.Fatal(err)
+  }
+  if res.GetEvent() != pb.WatchRes_Closed || res.GetItem().GetId() != mlId {
+    t.Fatalf("first event %s of %d, expected the close of %d", res.GetEvent(), res.GetItem().GetId(), mlId)
+  }
+}
+
+// TestTLSIdentity authenticates clients with their TLS certificates:
+// verified by a CA, or pinned.
+func TestTLSIdentity(t *testing.T) {
+  ca, clientCA := newTestCA(t), newTestCA(t)
+  admin, ci := clientCA.issue(t, "admin"), clientCA.issue(t, "ci")
+  ciLeaf, err := x509.ParseCertificate(ci.Certificate[0])
+  if err != nil {
+    t.Fatal(err)
+  }
+
+  cases := []struct {
+    name     string
+    server   *xnet.Security
+    cert     tls.Certificate
+    identity string
+  }{
+    {"ca", &xnet.Security{TLS: &tls.Config{ClientCAs: clientCA.pool, ClientAuth: tls.RequireAndVerifyClientCert}}, admin, "admin"},
+    {"pin", &xnet.Security{PinnedKeys: [][]byte{xnet.KeyPin(ciLeaf)}}, ci, "ci"},
+  }
+  for _, c := range cases {
+    t.Run(c.name, func(t *testing.T) {
+      pub, _, _ := ed25519.GenerateKey(nil)
+      p := testPolicy(t, pub)
+      p.TLSIdentity = true
+      if c.server.TLS == nil {
+        c.server.TLS = &tls.Config{}
+      }
+      c.server.TLS.Certificates = []tls.Certificate{ca.issue(t, "server")}
+      s, err := xserver.NewSecureServer(xtptest.TCPAddr, []xnet.Transport{&ximpls.MemoryTransport{}}, c.server)
+      if err != nil {
+        t.Fatal(err)
+      }
+      s.Policy = p
+      go s.Serve()
+      t.Cleanup(func() { s.Close() })
+
+      client, err := xclient.NewSecureClient(s.Listener.Multiaddr(), &xnet.Security{TLS: &tls.Config{
+        RootCAs:      ca.pool,
+        Certificates: []tls.Certificate{c.cert},
+      }})
+      if err != nil {
+        t.Fatal(err)
+      }
+      defer client.Close()
+      if _, err := client.Transport("/memory").Listen(ma.StringCast("/memory/ok")); err != nil {
+        t.Fatal("listening with the identity's grant:", err)
+      }
+
+      s.Lock()
+      sc := s.Clients[0]
+      s.Unlock()
+      if id := sc.Identity(); id != c.identity {
+        t.Fatalf("identity %q, expected %q", id, c.identity)
+      }
+    })
+  }
+}
@@ -0,0 +1,37 @@
+package xtpserver
+
+import (
+  "testing"
+  "time"
+)
+
+func TestBucketRate(t *testing.T) {
+  const rate = 1000 // bytes per second
+  b := &bucket{}
+
+  // it starts full, with a second's worth.
+  if w := b.take(rate, rate); w != 0 {
+    t.Fatal("a full bucket waits", w)
+  }
+  // then each byte takes a thousandth of a second.
+  if w := b.take(500, rate); !near(w, 500*time.Millisecond) {
+    t.Fatal("500 bytes over: waits", w)
+  }
+
+  // it fills up at rate, up to a second's worth.
+  b.last = b.last.Add(-time.Second)
+  if w := b.take(250, rate); w != 0 {
+    t.Fatal("a bucket refilled with 500 bytes waits", w)
+  }
+  b.last = b.last.Add(-time.Hour)
+  if w := b.take(rate, rate); w != 0 {
+    t.Fatal("a full bucket waits", w)
+  }
+  if w := b.take(1, rate); !near(w, time.Millisecond) {
+    t.Fatal("an idle bucket holds more than a second's worth: waits", w)
+  }
+}
+
+func near(d, want time.Duration) bool {
+  return d >= want-10*time.Millisecond && d <= want+10*time.Millisecond
+}
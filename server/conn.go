@@ -1,26 +1,35 @@
 package xtpserver
 
 import (
+  "context"
   "sync"
 
   xnet "github.com/libp2p/go-xtp-ctl/net"
+  pb "github.com/libp2p/go-xtp-ctl/pb"
+  xtptrace "github.com/libp2p/go-xtp-ctl/trace"
+
+  "go.opentelemetry.io/otel/attribute"
 )
 
 type conn struct {
   sync.RWMutex
 
   id      int64
-  rawC    xnet.Conn
+  rawC    xnet.ConnContext
   streams map[int64]*stream
   xport   *transport
+  stats   *stats
 }
 
-func newConn(id int64, t *transport, c xnet.Conn) *conn {
+// newConn wraps c. Its stats add up in up: those of the listener, dialer
+// or transport that opened it.
+func newConn(id int64, t *transport, c xnet.Conn, up *stats) *conn {
   return &conn{
     id:      id,
-    rawC:    c,
+    rawC:    xnet.ConnWithContext(c),
     xport:   t,
     streams: make(map[int64]*stream),
+    stats:   newStats(up),
   }
 }
 
@@ -32,8 +41,12 @@ func (c *conn) addStream(s *stream) {
 
 func (c *conn) rmStream(s *stream) {
   c.Lock()
+  _, found := c.streams[s.id]
   delete(c.streams, s.id)
   c.Unlock()
+  if found {
+    c.xport.sc.closed(s)
+  }
 }
 
 func (c *conn) Close() error {
@@ -41,6 +54,7 @@ func (c *conn) Close() error {
   for id, s := range c.streams {
     delete(c.streams, id)
     s.Close()
+    c.xport.sc.closed(s)
   }
   c.Unlock()
   return c.rawC.Close()
@@ -54,26 +68,46 @@ func (c *conn) Find(id int64) *stream {
   return s
 }
 
-func (c *conn) Dial() (*stream, error) {
-  s, err := c.rawC.Dial()
+func (c *conn) Dial(ctx context.Context) (_ *stream, err error) {
+  ctx, span := xtptrace.Start(ctx, "conn.Dial", attribute.Int64("id", c.id))
+  defer func() { xtptrace.End(span, err) }()
+
+  if err := c.xport.sc.reserve(resStream); err != nil {
+    return nil, err
+  }
+  s, err := c.rawC.DialContext(ctx)
   if err != nil {
+    c.xport.sc.release(resStream)
+    c.xport.sc.opFailed(c, err)
     return nil, err
   }
   id := c.xport.sc.NextId()
 
   s2 := newStream(id, c, s)
   c.addStream(s2)
+  c.stats.addStream()
+  c.xport.sc.emit(pb.WatchRes_Dialed, s2, nil)
   return s2, nil
 }
 
-func (c *conn) Accept() (*stream, error) {
-  s, err := c.rawC.Accept()
+func (c *conn) Accept(ctx context.Context) (_ *stream, err error) {
+  ctx, span := xtptrace.Start(ctx, "conn.Accept", attribute.Int64("id", c.id))
+  defer func() { xtptrace.End(span, err) }()
+
+  s, err := c.rawC.AcceptContext(ctx)
   if err != nil {
+    c.xport.sc.opFailed(c, err)
+    return nil, err
+  }
+  if err := c.xport.sc.reserve(resStream); err != nil {
+    s.Close()
     return nil, err
   }
   id := c.xport.sc.NextId()
 
   s2 := newStream(id, c, s)
   c.addStream(s2)
+  c.stats.addStream()
+  c.xport.sc.emit(pb.WatchRes_Accepted, s2, nil)
   return s2, nil
 }
@@ -0,0 +1,205 @@
+package xtpserver
+
+import (
+  "context"
+  "errors"
+  "sync"
+  "time"
+
+  xnet "github.com/libp2p/go-xtp-ctl/net"
+  pb "github.com/libp2p/go-xtp-ctl/pb"
+  xrpc "github.com/libp2p/go-xtp-ctl/rpc"
+  xtptrace "github.com/libp2p/go-xtp-ctl/trace"
+
+  "go.opentelemetry.io/otel/trace"
+)
+
+// ctlStream is an xtp-ctl stream, as the server sees it. While a blocking
+// rpc (accept or dial) runs, it watches the stream with a background
+// read: clients abort rpcs by closing the stream. Whatever that read
+// gets is handed to the next Read, so no data is lost.
+//
+// It also tracks the descriptors opened through the stream, so they can
+// be closed when the stream goes away. The rpc being answered has its
+// own state, in an rpcCall.
+type ctlStream struct {
+  xnet.Stream
+  sc *ServerClient
+
+  lk      sync.Mutex
+  pending chan struct{} // non-nil while a watch read is in flight or unconsumed
+  buf     []byte
+  err     error
+
+  owned []int64 // descriptor ids opened through this stream
+}
+
+func newCtlStream(sc *ServerClient, s xnet.Stream) *ctlStream {
+  return &ctlStream{Stream: s, sc: sc}
+}
+
+// PeerMessageSizeMax returns the largest rpc the client takes, once it
+// said hello.
+func (s *ctlStream) PeerMessageSizeMax() int {
+  return int(s.sc.Hello().GetMaxMessageSize())
+}
+
+// watch starts a background read, unless one is pending already. The
+// returned channel is closed when the read returns: the client either
+// sent something, or closed the stream.
+func (s *ctlStream) watch() <-chan struct{} {
+  s.lk.Lock()
+  defer s.lk.Unlock()
+
+  if s.pending != nil {
+    return s.pending
+  }
+
+  done := make(chan struct{})
+  s.pending = done
+  go func() {
+    buf := make([]byte, 4096)
+    n, err := s.Stream.Read(buf)
+
+    s.lk.Lock()
+    s.buf = buf[:n]
+    s.err = err
+    s.lk.Unlock()
+    close(done)
+  }()
+  return done
+}
+
+// watchContext returns a context of parent, cancelled when the client
+// sends anything (it should not while it waits for a response), or closes
+// the stream. Callers must call cancel when done.
+func (s *ctlStream) watchContext(parent context.Context) (context.Context, context.CancelFunc) {
+  ctx, cancel := context.WithCancel(parent)
+  done := s.watch()
+  go func() {
+    select {
+    case <-done:
+      cancel()
+    case <-ctx.Done():
+    }
+  }()
+  return ctx, cancel
+}
+
+func (s *ctlStream) Read(buf []byte) (int, error) {
+  s.lk.Lock()
+  pending := s.pending
+  s.lk.Unlock()
+
+  if pending == nil {
+    return s.Stream.Read(buf)
+  }
+  <-pending
+
+  // hand out what the watch read got.
+  s.lk.Lock()
+  defer s.lk.Unlock()
+
+  n := copy(buf, s.buf)
+  s.buf = s.buf[n:]
+  if len(s.buf) > 0 {
+    return n, nil
+  }
+
+  s.pending = nil
+  if n > 0 {
+    return n, nil // the stream repeats any error on the next Read.
+  }
+  return 0, s.err
+}
+
+// CloseWrite half-closes the underlying stream, if it can. The client
+// reads io.EOF, and can still write.
+func (s *ctlStream) CloseWrite() error {
+  return xnet.CloseWrite(s.Stream)
+}
+
+// own records that descriptor id was opened through this stream.
+func (s *ctlStream) own(id int64) {
+  s.lk.Lock()
+  s.owned = append(s.owned, id)
+  s.lk.Unlock()
+}
+
+// closeOwned closes all descriptors opened through this stream.
+func (s *ctlStream) closeOwned(sc *ServerClient) {
+  s.lk.Lock()
+  owned := s.owned
+  s.owned = nil
+  s.lk.Unlock()
+
+  for _, id := range owned {
+    if err := sc.CloseId(id); err != nil && !errors.Is(err, xrpc.ErrNotFound) { // closed already
+      sc.log.Error("closing descriptor", "id", id, "err", err)
+    }
+  }
+}
+
+// rpcCall is the rpc being answered on a ctlStream: what its log, metrics,
+// trace and in-flight slot need. rpcHandler owns it, and hands it to the
+// rpc's handler as the stream to answer on.
+type rpcCall struct {
+  *ctlStream
+
+  typ     pb.RPC_Type
+  start   time.Time
+  ctx     context.Context // carries the rpc's span, if traced
+  metrics *Metrics
+  release func() // frees the rpc's in-flight slot, if held
+
+  aboutId int64   // the descriptor the rpc is about, if any. see about
+  opened  []int64 // the descriptors it opened
+  done    bool    // answered
+}
+
+// startRPC starts timing and tracing request req on s. The span is a
+// child of the client's, if it sent its traceparent. The caller ends it.
+func startRPC(s *ctlStream, m *Metrics, req *pb.RPC) (*rpcCall, trace.Span) {
+  ctx := xrpc.ExtractTrace(context.Background(), req)
+  ctx, span := xtptrace.Start(ctx, "xtpserver."+req.GetRpc().String())
+  return &rpcCall{ctlStream: s, typ: req.GetRpc(), start: time.Now(), ctx: ctx, metrics: m}, span
+}
+
+// answered observes the rpc's duration, and frees its in-flight slot, if
+// not yet. rpcHandler calls it when the handler returns. Handlers of rpcs
+// that go on after their response (streams spliced, watches) call it
+// once they sent the response.
+func (c *rpcCall) answered() {
+  if c.done {
+    return
+  }
+  c.done = true
+  if c.metrics != nil {
+    c.metrics.observeRPC(c.typ, time.Since(c.start))
+  }
+  if c.release != nil {
+    c.release()
+  }
+}
+
+// about records the descriptor id the rpc is about (for listen and
+// dialer requests, the transport), for its log.
+func (c *rpcCall) about(id int64) {
+  c.aboutId = id
+}
+
+// own records that the rpc opened descriptor id, through its stream.
+func (c *rpcCall) own(id int64) {
+  c.opened = append(c.opened, id)
+  c.ctlStream.own(id)
+}
+
+// context returns the rpc's context.
+func (c *rpcCall) context() context.Context {
+  return c.ctx
+}
+
+// watchContext is the stream's watchContext, of the rpc's context.
+func (c *rpcCall) watchContext() (context.Context, context.CancelFunc) {
+  return c.ctlStream.watchContext(c.ctx)
+}
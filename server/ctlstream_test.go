@@ -0,0 +1,102 @@
+package xtpserver_test
+
+import (
+  "context"
+  "errors"
+  "testing"
+  "time"
+
+  ximpls "github.com/libp2p/go-xtp-ctl/impls"
+  xnet "github.com/libp2p/go-xtp-ctl/net"
+  "github.com/libp2p/go-xtp-ctl/xtptest"
+  ma "github.com/multiformats/go-multiaddr"
+)
+
+// stuckTransport is /memory, but its dials hang until aborted.
+type stuckTransport struct {
+  ximpls.MemoryTransport
+  dialing chan struct{}
+  aborted chan error
+}
+
+func (t *stuckTransport) DialContext(ctx context.Context, raddr ma.Multiaddr) (xnet.Conn, error) {
+  t.dialing <- struct{}{}
+  <-ctx.Done()
+  t.aborted <- ctx.Err()
+  return nil, ctx.Err()
+}
+
+func TestCancelDial(t *testing.T) {
+  tpt := &stuckTransport{dialing: make(chan struct{}, 1), aborted: make(chan error, 1)}
+  h := xtptest.NewMemory(t, tpt)
+
+  ctx, cancel := context.WithCancel(context.Background())
+  dialed := make(chan error, 1)
+  go func() {
+    _, err := h.Client.Transport("/memory").(xnet.TransportContext).DialContext(ctx, ma.StringCast("/memory/a"))
+    dialed <- err
+  }()
+  <-tpt.dialing
+  cancel()
+
+  if err := <-dialed; !errors.Is(err, context.Canceled) {
+    t.Fatal("expected the dial to be cancelled, got", err)
+  }
+  select {
+  case <-tpt.aborted:
+  case <-time.After(5 * time.Second):
+    t.Fatal("the server did not abort the dial")
+  }
+  eventually(t, "the dial's conn and in-flight slot to be freed", func() bool {
+    u := serverUsage(t, h.Client)
+    return u.GetConns() == 0 && u.GetInFlight() == 1
+  })
+}
+
+func TestCancelAccept(t *testing.T) {
+  h := xtptest.NewMemory(t, &ximpls.MemoryTransport{})
+  l, err := h.Client.Transport("/memory").Listen(ma.StringCast("/memory/a"))
+  if err != nil {
+    t.Fatal(err)
+  }
+
+  go l.Accept() // accepts the conn the streams are accepted on.
+  c, err := h.NewClient().Transport("/memory").Dial(l.Multiaddr())
+  if err != nil {
+    t.Fatal(err)
+  }
+  defer c.Close()
+
+  cancelled := func(accept func(ctx context.Context) error) {
+    t.Helper()
+    ctx, cancel := context.WithCancel(context.Background())
+    accepted := make(chan error, 1)
+    go func() { accepted <- accept(ctx) }()
+    eventually(t, "the accept to be pending", func() bool {
+      return serverUsage(t, h.Client).GetPendingAccepts() == 1
+    })
+    cancel()
+
+    if err := <-accepted; !errors.Is(err, context.Canceled) {
+      t.Fatal("expected the accept to be cancelled, got", err)
+    }
+    eventually(t, "the accept to be aborted", func() bool {
+      u := serverUsage(t, h.Client)
+      return u.GetPendingAccepts() == 0 && u.GetInFlight() == 1
+    })
+  }
+
+  // a conn's stream.
+  cancelled(func(ctx context.Context) error {
+    _, err := c.(xnet.ConnContext).AcceptContext(ctx)
+    return err
+  })
+  // a listener's conn. no conn is left open.
+  cancelled(func(ctx context.Context) error {
+    _, err := l.(xnet.ListenerContext).AcceptContext(ctx)
+    return err
+  })
+  if u := serverUsage(t, h.Client); u.GetConns() != 2 || u.GetStreams() != 0 {
+    t.Fatal("descriptors left open:", u)
+  }
+}
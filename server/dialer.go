@@ -1,29 +1,46 @@
 package xtpserver
 
 import (
+  "context"
+
   xnet "github.com/libp2p/go-xtp-ctl/net"
+  pb "github.com/libp2p/go-xtp-ctl/pb"
+  xtptrace "github.com/libp2p/go-xtp-ctl/trace"
+
+  "go.opentelemetry.io/otel/attribute"
   ma "github.com/multiformats/go-multiaddr"
 )
 
 type dialer struct {
   id    int64
-  rawD  xnet.Dialer
+  rawD  xnet.DialerContext
   xport *transport
+  stats *stats
 }
 
 func newDialer(id int64, t *transport, d xnet.Dialer) *dialer {
-  return &dialer{id, d, t}
+  return &dialer{id, xnet.DialerWithContext(d), t, newStats(t.stats)}
 }
 
 
-func (d *dialer) Dial(raddr ma.Multiaddr) (*conn, error) {
-  c, err := d.rawD.Dial(raddr)
+func (d *dialer) Dial(ctx context.Context, raddr ma.Multiaddr) (_ *conn, err error) {
+  ctx, span := xtptrace.Start(ctx, "dialer.Dial", attribute.Int64("id", d.id))
+  defer func() { xtptrace.End(span, err) }()
+
+  if err := d.xport.sc.reserve(resConn); err != nil {
+    return nil, err
+  }
+  c, err := d.rawD.DialContext(ctx, raddr)
   if err != nil {
+    d.xport.sc.release(resConn)
+    d.xport.sc.opFailed(d, err)
     return nil, err
   }
   id := d.xport.sc.NextId()
 
-  c2 := newConn(id, d.xport, c)
+  c2 := newConn(id, d.xport, c, d.stats)
   d.xport.addConn(c2)
+  d.stats.addConn()
+  d.xport.sc.emit(pb.WatchRes_Dialed, c2, nil)
   return c2, nil
 }
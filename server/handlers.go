@@ -3,35 +3,92 @@ package xtpserver
 import (
   "io"
   "errors"
+  "fmt"
+  "time"
 
   pb "github.com/libp2p/go-xtp-ctl/pb"
   ma "github.com/multiformats/go-multiaddr"
   xrpc "github.com/libp2p/go-xtp-ctl/rpc"
+  xtptrace "github.com/libp2p/go-xtp-ctl/trace"
 
   proto "gx/ipfs/QmZ4Qi3GaRbjcx28Sme5eMH7RQjGkt8wHxt2a65oLaeFEV/gogo-protobuf/proto"
 )
 
-func rpcHandler(sc *ServerClient, s IoStream) error {
+// errSpliced is returned by handlers that turned the xtp-ctl stream into
+// the data pipe of a remote stream. No more rpcs may be read from it.
+var errSpliced = errors.New("xtp-ctl stream spliced to a remote stream")
+
+// serverRPCs are the requests the server handles, sent in its Hello.
+var serverRPCs = []pb.RPC_Type{
+  pb.RPC_NoOp,
+  pb.RPC_ListReq,
+  pb.RPC_CloseReq,
+  pb.RPC_ListenReq,
+  pb.RPC_AcceptReq,
+  pb.RPC_DialerReq,
+  pb.RPC_DialReq,
+  pb.RPC_HelloReq,
+  pb.RPC_ShutdownReq,
+  pb.RPC_WatchReq,
+  pb.RPC_StatsReq,
+  pb.RPC_AuthReq,
+  pb.RPC_UsageReq,
+}
+
+// serverFeatures are the optional features the server supports.
+var serverFeatures []string
+
+func rpcHandler(sc *ServerClient, s *ctlStream) error {
   req := &pb.RPC{}
   if err := xrpc.ReadRPC(s, req); err != nil {
     return err
   }
+  call, span := startRPC(s, sc.metrics(), req)
 
-  err := handleReq(sc, s, req)
-  if err != nil {
+  err := sc.reserve(resInFlight)
+  if err == nil {
+    call.release = func() { sc.release(resInFlight) }
+    err = handleReq(sc, call, req)
+  }
+  call.answered()
+  sc.logRPC(req, call.aboutId, call.opened, time.Since(call.start), err)
+  switch err {
+  case nil:
+    span.End()
+    return nil
+  case errSpliced:
+    span.End()
+    return err
+  default:
+    xtptrace.End(span, err)
+    sc.metrics().rpcError(xrpc.ErrCode(err))
     return xrpc.ErrRPCRes(s, req, err)
   }
-  return nil
 }
 
-func handleReq(sc *ServerClient, s IoStream, req *pb.RPC) error {
-  if *(req.Error) != "" { // should not have an error in a request.
+func handleReq(sc *ServerClient, s *rpcCall, req *pb.RPC) error {
+  if req.Error != nil && *req.Error != "" { // should not have an error in a request.
     return xrpc.ErrProtocol
   }
 
+  // the session starts with a Hello. nothing else goes before it.
+  if *req.Rpc == pb.RPC_HelloReq {
+    req2 := &pb.HelloReq{}
+    if err := proto.Unmarshal(req.Message, req2); err != nil {
+      return err
+    }
+    return handleHelloReq(sc, s, req2)
+  }
+  if sc.Hello() == nil {
+    return xrpc.ErrHelloRequired
+  }
+  if err := sc.authorizeRPC(*req.Rpc); err != nil {
+    return err
+  }
+
   switch *req.Rpc {
   case pb.RPC_NoOp:
-    return nil // do nothing
+    return xrpc.WriteRPCMsg(s, pb.RPC_NoOp, nil, nil) // echo it back
   case pb.RPC_ListReq:
     req2 := &pb.ListReq{}
     if err := proto.Unmarshal(req.Message, req2); err != nil {
@@ -68,60 +125,125 @@ func handleReq(sc *ServerClient, s IoStream, req *pb.RPC) error {
       return err
     }
     return handleDialReq(sc, s, req2)
+  case pb.RPC_ShutdownReq:
+    req2 := &pb.ShutdownReq{}
+    if err := proto.Unmarshal(req.Message, req2); err != nil {
+      return err
+    }
+    return handleShutdownReq(sc, s, req2)
+  case pb.RPC_WatchReq:
+    req2 := &pb.WatchReq{}
+    if err := proto.Unmarshal(req.Message, req2); err != nil {
+      return err
+    }
+    return handleWatchReq(sc, s, req2)
+  case pb.RPC_StatsReq:
+    req2 := &pb.StatsReq{}
+    if err := proto.Unmarshal(req.Message, req2); err != nil {
+      return err
+    }
+    return handleStatsReq(sc, s, req2)
+  case pb.RPC_AuthReq:
+    req2 := &pb.AuthReq{}
+    if err := proto.Unmarshal(req.Message, req2); err != nil {
+      return err
+    }
+    return handleAuthReq(sc, s, req2)
+  case pb.RPC_UsageReq:
+    req2 := &pb.UsageReq{}
+    if err := proto.Unmarshal(req.Message, req2); err != nil {
+      return err
+    }
+    return handleUsageReq(sc, s, req2)
   default:
     return xrpc.ErrUnknownRPC
   }
 }
 
-func handleListReq(sc *ServerClient, s IoStream, req *pb.ListReq) error {
+func handleHelloReq(sc *ServerClient, s *rpcCall, req *pb.HelloReq) error {
+  features := append([]string(nil), serverFeatures...)
+  if sc.policy() != nil {
+    features = append(features, featureAuth)
+  }
+  h := xrpc.NewHello(serverRPCs, features)
+  if err := xrpc.CheckHello(req.Hello); err != nil {
+    // tell the client who we are anyway, so it can report the mismatch.
+    return xrpc.HelloRes(s, h, nil, err)
+  }
+  if !sc.setHello(req.Hello) {
+    return xrpc.ErrProtocol // only one hello per session.
+  }
+  if req.Resume != nil {
+    old, err := sc.resumeSession(req.Resume, req.Hello)
+    if err != nil {
+      return err
+    }
+    s.sc = old // the stream's next rpcs are old's.
+    sc = old
+  }
+  return xrpc.HelloRes(s, h, sc.sessionToken(), nil)
+}
+
+func handleListReq(sc *ServerClient, s *rpcCall, req *pb.ListReq) error {
   types := req.TypesRequested()
+  withStats := req.GetStats()
 
   var items []*pb.ListRes_Item
-  addItem := func(i *pb.ListRes_Item, err error) {
-    if err != nil {
+  addItem := func(i *pb.ListRes_Item, err error, st *stats) {
+    if err == nil {
+      if withStats {
+        i.Stats = st.PB(i.GetId())
+      }
       items = append(items, i)
     } else {
-      // internal error. TODO: log it
+      sc.log.Error("list: encoding descriptor", "err", err)
     }
   }
 
   sc.Lock()
   for _, t := range sc.transports {
+    if !sc.allowsTransport(t) {
+      continue // hidden by the client's policy.
+    }
+
     // add the transport to the list of items.
     if types.Transports {
       i, err := pb.ListRes_Item_Transport(t.PB())
-      addItem(i, err)
+      addItem(i, err, t.stats)
     }
 
     if types.Listeners || types.Dialers || types.Conns || types.Streams {
-      items = append(items, t.List(types)...)
+      items = append(items, t.List(types, withStats)...)
     }
   }
   sc.Unlock() // todo: more granular locking, to avoid holding lock while marshalling.
 
-  res := pb.ListRes{Items: items}
-  return xrpc.WriteRPCMsg(s, pb.RPC_ListRes, &res, nil)
+  return xrpc.ListRes(s, items, nil)
 }
 
-func handleCloseReq(sc *ServerClient, s IoStream, req *pb.CloseReq) error {
+func handleCloseReq(sc *ServerClient, s *rpcCall, req *pb.CloseReq) error {
   id := *req.Id
+  s.about(id)
   if id < pb.MinId {
     return xrpc.ErrInvalidMessage
   }
 
-  err := sc.CloseId(id)
-  if err != nil {
-    // log error, internal failure.
+  if err := sc.CloseId(id); errors.Is(err, xrpc.ErrNotFound) {
+    return err
+  } else if err != nil {
+    // it is gone all the same.
+    sc.log.Error("closing descriptor", "id", id, "err", err)
   }
 
   return xrpc.WriteRPCMsg(s, pb.RPC_CloseRes, nil, nil)
 }
 
-func handleListenReq(sc *ServerClient, s IoStream, req *pb.ListenReq) error {
+func handleListenReq(sc *ServerClient, s *rpcCall, req *pb.ListenReq) error {
   l := req.ListenerOpts
   if l == nil || l.Multiaddr == nil || l.TransportId == nil {
     return xrpc.ErrInvalidMessage
   }
+  s.about(*l.TransportId)
 
   // get parameters
   laddr, err := ma.NewMultiaddrBytes(l.Multiaddr)
@@ -132,58 +254,96 @@ func handleListenReq(sc *ServerClient, s IoStream, req *pb.ListenReq) error {
   tid := *l.TransportId
   t := sc.transport(tid)
   if t == nil {
-    return errors.New("transport id not found")
+    return fmt.Errorf("transport %d: %w", tid, xrpc.ErrNotFound)
+  }
+
+  if err := sc.authorizeListen(t, laddr); err != nil {
+    return err
   }
 
   // listen
-  l2, err := t.Listen(laddr)
+  l2, err := t.Listen(s.context(), laddr)
   if err != nil {
     return err
   }
+  s.own(l2.id)
 
   // send response with listener
   return xrpc.ListenRes(s, l2.PB(), nil)
 }
 
-func handleAcceptReq(sc *ServerClient, s IoStream, req *pb.AcceptReq) error {
+func handleAcceptReq(sc *ServerClient, s *rpcCall, req *pb.AcceptReq) error {
   if req.Id == nil {
     return xrpc.ErrInvalidMessage
   }
 
   // get parameters
   id := *req.Id
+  s.about(id)
 
   v := sc.Find(id)
 
+  // the client aborts the accept by closing s.
+  ctx, cancel := s.watchContext()
+  defer cancel()
+
   var c1 *pb.Conn
   var s1 *pb.Stream
+  var s2 *stream
 
   switch v := v.(type) {
   case *listener:
-    c2, err := v.Accept()
+    if err := sc.reserve(resPendingAccept); err != nil {
+      return err
+    }
+    c2, err := v.Accept(ctx)
+    sc.release(resPendingAccept)
     if err != nil {
       return err
     }
+    s.own(c2.id)
     c1 = c2.PB()
   case *conn:
-    s2, err := v.Accept()
+    if err := sc.reserve(resPendingAccept); err != nil {
+      return err
+    }
+    var err error
+    s2, err = v.Accept(ctx)
+    sc.release(resPendingAccept)
     if err != nil {
       return err
     }
     s1 = s2.PB()
+  case nil:
+    return fmt.Errorf("id %d: %w", id, xrpc.ErrNotFound)
   default:
-    return errors.New("id mismatch (not a listener or conn)")
+    return fmt.Errorf("%w: id %d is not a listener or conn", xrpc.ErrInvalidMessage, id)
   }
 
-  // send response with listener
-  return xrpc.AcceptRes(s, c1, s1, nil)
+  // send response with conn or stream
+  if err := xrpc.AcceptRes(s, c1, s1, nil); err != nil {
+    if s2 != nil {
+      s2.conn.rmStream(s2)
+      s2.Close()
+    }
+    return err
+  }
+
+  if s2 != nil {
+    // from now on, s carries the stream's data.
+    s.answered()
+    s2.splice(s.ctlStream)
+    return errSpliced
+  }
+  return nil
 }
 
-func handleDialerReq(sc *ServerClient, s IoStream, req *pb.DialerReq) error {
+func handleDialerReq(sc *ServerClient, s *rpcCall, req *pb.DialerReq) error {
   d := req.DialerOpts
   if d == nil || d.Multiaddr == nil || d.TransportId == nil {
     return xrpc.ErrInvalidMessage
   }
+  s.about(*d.TransportId)
 
   // get parameters
   laddr, err := ma.NewMultiaddrBytes(d.Multiaddr)
@@ -194,56 +354,142 @@ func handleDialerReq(sc *ServerClient, s IoStream, req *pb.DialerReq) error {
   tid := *d.TransportId
   t := sc.transport(tid)
   if t == nil {
-    return errors.New("transport id not found")
+    return fmt.Errorf("transport %d: %w", tid, xrpc.ErrNotFound)
+  }
+
+  if err := sc.authorizeListen(t, laddr); err != nil {
+    return err
   }
 
   // dial
-  d2, err := t.Dialer(laddr)
+  d2, err := t.Dialer(s.context(), laddr)
   if err != nil {
     return err
   }
+  s.own(d2.id)
 
   // send response with dialer
   return xrpc.DialerRes(s, d2.PB(), nil)
 }
 
-func handleDialReq(sc *ServerClient, s IoStream, req *pb.DialReq) error {
- if req.Id == nil {
+func handleDialReq(sc *ServerClient, s *rpcCall, req *pb.DialReq) error {
+  if req.Id == nil {
     return xrpc.ErrInvalidMessage
   }
 
   // get parameters
   id := *req.Id
-  opts := *req.ConnOpts
+  s.about(id)
 
   v := sc.Find(id)
 
+  // the client aborts the dial by closing s.
+  ctx, cancel := s.watchContext()
+  defer cancel()
+
   var c1 *pb.Conn
   var s1 *pb.Stream
+  var s2 *stream
 
   switch v := v.(type) {
+  case *transport:
+    raddr, err := dialAddr(req.ConnOpts)
+    if err != nil {
+      return err
+    }
+    if err := sc.authorizeDial(v, raddr); err != nil {
+      return err
+    }
+    c2, err := v.Dial(ctx, raddr)
+    if err != nil {
+      return err
+    }
+    s.own(c2.id)
+    c1 = c2.PB()
   case *dialer:
-    raddr, err := ma.NewMultiaddrBytes(opts.LocalMultiaddr)
+    raddr, err := dialAddr(req.ConnOpts)
     if err != nil {
       return err
     }
-    c2, err := v.Dial(raddr)
+    if err := sc.authorizeDial(v.xport, raddr); err != nil {
+      return err
+    }
+    c2, err := v.Dial(ctx, raddr)
     if err != nil {
       return err
     }
+    s.own(c2.id)
     c1 = c2.PB()
   case *conn:
-    s2, err := v.Dial()
+    var err error
+    s2, err = v.Dial(ctx)
     if err != nil {
       return err
     }
     s1 = s2.PB()
+  case nil:
+    return fmt.Errorf("id %d: %w", id, xrpc.ErrNotFound)
   default:
-    return errors.New("id mismatch (not a listener or conn)")
+    return fmt.Errorf("%w: id %d is not a transport, dialer or conn", xrpc.ErrInvalidMessage, id)
   }
 
-  // send response with listener
-  return xrpc.DialRes(s, c1, s1, nil)
+  // send response with conn or stream
+  if err := xrpc.DialRes(s, c1, s1, nil); err != nil {
+    if s2 != nil {
+      s2.conn.rmStream(s2)
+      s2.Close()
+    }
+    return err
+  }
+
+  if s2 != nil {
+    // from now on, s carries the stream's data.
+    s.answered()
+    s2.splice(s.ctlStream)
+    return errSpliced
+  }
+  return nil
+}
+
+func handleShutdownReq(sc *ServerClient, s *rpcCall, req *pb.ShutdownReq) error {
+  if req.Id == nil || req.How == nil {
+    return xrpc.ErrInvalidMessage
+  }
+
+  // get parameters
+  id := *req.Id
+  s.about(id)
+
+  st, ok := sc.Find(id).(*stream)
+  if !ok {
+    return fmt.Errorf("stream %d: %w", id, xrpc.ErrNotFound)
+  }
+
+  var err error
+  switch *req.How {
+  case pb.ShutdownReq_Write:
+    // in-band: the end of the stream's xtp-ctl stream (see splice).
+    return fmt.Errorf("%w: half-close the xtp-ctl stream instead", xrpc.ErrUnsupported)
+  case pb.ShutdownReq_Read:
+    err = st.shutdownRead()
+  case pb.ShutdownReq_Reset:
+    err = st.reset()
+  default:
+    return xrpc.ErrInvalidMessage
+  }
+  if err != nil {
+    return err
+  }
+
+  return xrpc.WriteRPCMsg(s, pb.RPC_ShutdownRes, nil, nil)
+}
+
+// dialAddr extracts the remote address to dial from DialReq conn options.
+func dialAddr(opts *pb.Conn) (ma.Multiaddr, error) {
+  if opts == nil || opts.RemoteMultiaddr == nil {
+    return nil, xrpc.ErrInvalidMessage
+  }
+  return ma.NewMultiaddrBytes(opts.RemoteMultiaddr)
 }
 
 type IoStream interface {
@@ -0,0 +1,117 @@
+package xtpserver_test
+
+import (
+  "errors"
+  "fmt"
+  "testing"
+
+  ximpls "github.com/libp2p/go-xtp-ctl/impls"
+  pb "github.com/libp2p/go-xtp-ctl/pb"
+  xrpc "github.com/libp2p/go-xtp-ctl/rpc"
+  "github.com/libp2p/go-xtp-ctl/xtptest"
+  ma "github.com/multiformats/go-multiaddr"
+
+  proto "gx/ipfs/QmZ4Qi3GaRbjcx28Sme5eMH7RQjGkt8wHxt2a65oLaeFEV/gogo-protobuf/proto"
+)
+
+func TestCloseReq(t *testing.T) {
+  h := xtptest.New(t, &ximpls.MemoryTransport{})
+  l, err := h.Client.Transport("/memory").Listen(ma.StringCast("/memory/close"))
+  if err != nil {
+    t.Fatal(err)
+  }
+  lid := h.Ids(pb.TType_TTypeListener)[0]
+
+  if err := xrpc.CloseReq(h.Stream(), lid); err != nil {
+    t.Fatal(err)
+  }
+  h.RequireNoId(lid)
+  if err := xrpc.CloseReq(h.Stream(), lid); !errors.Is(err, xrpc.ErrNotFound) {
+    t.Fatal("closing a closed id:", err)
+  }
+  if err := xrpc.CloseReq(h.Stream(), 12345); !errors.Is(err, xrpc.ErrNotFound) {
+    t.Fatal("closing an unknown id:", err)
+  }
+  l.Close()
+}
+
+// TestListenReqWireTypes checks that a ListenReq is handled as a listen
+// (not a ListReq), and answered with a ListenRes.
+func TestListenReqWireTypes(t *testing.T) {
+  h := xtptest.New(t, &ximpls.MemoryTransport{})
+  tid := h.Ids(pb.TType_TTypeTransport)[0]
+
+  res := h.RoundTrip(pb.RPC_ListenReq, &pb.ListenReq{ListenerOpts: &pb.Listener{
+    TransportId: &tid,
+    Multiaddr:   ma.StringCast("/memory/wire-listen").Bytes(),
+  }})
+  if res.GetRpc() != pb.RPC_ListenRes || res.GetError() != "" {
+    t.Fatalf("ListenReq answered with %s: %q", res.GetRpc(), res.GetError())
+  }
+  lres := &pb.ListenRes{}
+  if err := proto.Unmarshal(res.Message, lres); err != nil {
+    t.Fatal(err)
+  }
+  h.RequireId(pb.TType_TTypeListener, lres.GetListener().GetId())
+}
+
+// TestDialResWireType checks that a DialReq is answered with a DialRes.
+func TestDialResWireType(t *testing.T) {
+  h := xtptest.New(t, &ximpls.MemoryTransport{})
+  tid := h.Ids(pb.TType_TTypeTransport)[0]
+  raddr := ma.StringCast("/memory/wire-dial")
+  l, err := h.Client.Transport("/memory").Listen(raddr)
+  if err != nil {
+    t.Fatal(err)
+  }
+  defer l.Close()
+
+  res := h.RoundTrip(pb.RPC_DialReq, &pb.DialReq{Id: &tid, ConnOpts: &pb.Conn{RemoteMultiaddr: raddr.Bytes()}})
+  if res.GetRpc() != pb.RPC_DialRes || res.GetError() != "" {
+    t.Fatalf("DialReq answered with %s: %q", res.GetRpc(), res.GetError())
+  }
+  dres := &pb.DialRes{}
+  if err := proto.Unmarshal(res.Message, dres); err != nil {
+    t.Fatal(err)
+  }
+  h.RequireId(pb.TType_TTypeConn, dres.GetConn().GetId())
+}
+
+// TestListAndStatsPages checks that lists and stats of more descriptors
+// than fit in one rpc come in pages, all of them.
+func TestListAndStatsPages(t *testing.T) {
+  h := xtptest.New(t, &ximpls.MemoryTransport{})
+  const n = 150
+  for i := 0; i < n; i++ {
+    l, err := h.Client.Transport("/memory").Listen(ma.StringCast(fmt.Sprintf("/memory/page-%d", i)))
+    if err != nil {
+      t.Fatal(err)
+    }
+    // closed before the client: yamux sessions close many open streams
+    // slowly under -race.
+    defer l.Close()
+  }
+
+  h.RequireCount(pb.TType_TTypeListener, n)
+  s := h.Stream()
+  items, err := xrpc.ListStatsReq(s, []pb.TType{pb.TType_TTypeListener})
+  if err != nil {
+    t.Fatal(err)
+  }
+  if len(items) != n {
+    t.Fatalf("listed %d listeners with stats, expected %d", len(items), n)
+  }
+  for _, i := range items {
+    if i.Stats == nil {
+      t.Fatalf("listener %d has no stats", i.GetId())
+    }
+  }
+
+  ss, err := h.Client.Stats()
+  if err != nil {
+    t.Fatal(err)
+  }
+  if len(ss) != n+1 { // and the transport
+    t.Fatalf("got %d stats, expected %d", len(ss), n+1)
+  }
+}
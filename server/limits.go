@@ -0,0 +1,288 @@
+package xtpserver
+
+import (
+  "fmt"
+  "sync"
+  "sync/atomic"
+  "time"
+
+  pb "github.com/libp2p/go-xtp-ctl/pb"
+  xrpc "github.com/libp2p/go-xtp-ctl/rpc"
+)
+
+// Limits bound what clients use: each client (Server.ClientLimits, or
+// its Grant's), and all of them together (Server.Limits). Zero fields
+// are unlimited. Going over a limit fails with xrpc.ErrResourceExhausted,
+// except for Bandwidth, which slows streams down.
+type Limits struct {
+  Listeners      int   `json:"listeners"`      // open listeners
+  Dialers        int   `json:"dialers"`        // open dialers
+  Conns          int   `json:"conns"`          // open conns
+  Streams        int   `json:"streams"`        // open streams
+  PendingAccepts int   `json:"pendingAccepts"` // AcceptReqs waiting at once
+  InFlight       int   `json:"inFlight"`       // rpcs not answered yet
+  Bandwidth      int64 `json:"bandwidth"`      // bytes per second proxied, both ways together
+}
+
+// resource is something Limits count.
+type resource int
+
+const (
+  resListener resource = iota
+  resDialer
+  resConn
+  resStream
+  resPendingAccept
+  resInFlight
+  numResources
+)
+
+var resourceNames = [numResources]string{"listeners", "dialers", "conns", "streams", "pending accepts", "rpcs in flight"}
+
+func (l *Limits) max(r resource) int {
+  if l == nil {
+    return 0
+  }
+  switch r {
+  case resListener:
+    return l.Listeners
+  case resDialer:
+    return l.Dialers
+  case resConn:
+    return l.Conns
+  case resStream:
+    return l.Streams
+  case resPendingAccept:
+    return l.PendingAccepts
+  case resInFlight:
+    return l.InFlight
+  default:
+    return 0
+  }
+}
+
+func (l *Limits) bandwidth() int64 {
+  if l == nil {
+    return 0
+  }
+  return l.Bandwidth
+}
+
+// PB returns l as a pb.Usage.
+func (l *Limits) PB() *pb.Usage {
+  u := &pb.Usage{}
+  if l == nil {
+    return u
+  }
+  for r := resource(0); r < numResources; r++ {
+    n := int64(l.max(r))
+    *usageField(u, r) = &n
+  }
+  bw := l.Bandwidth
+  u.Bandwidth = &bw
+  return u
+}
+
+// resourceOf returns the resource descriptor type t counts against.
+func resourceOf(t pb.TType) (resource, bool) {
+  switch t {
+  case pb.TType_TTypeListener:
+    return resListener, true
+  case pb.TType_TTypeDialer:
+    return resDialer, true
+  case pb.TType_TTypeConn:
+    return resConn, true
+  case pb.TType_TTypeStream:
+    return resStream, true
+  default:
+    return 0, false
+  }
+}
+
+// usage counts what a client, or the whole server, uses.
+type usage struct {
+  lk sync.Mutex
+  n  [numResources]int
+
+  bytes  int64 // atomic
+  bucket bucket
+}
+
+// acquire counts one more r, unless that goes over limit. who says whose
+// limit it is, for the error.
+func (u *usage) acquire(r resource, limit int, who string) error {
+  u.lk.Lock()
+  defer u.lk.Unlock()
+  if limit > 0 && u.n[r] >= limit {
+    return fmt.Errorf("%w: %s has %d %s (limit %d)", xrpc.ErrResourceExhausted, who, u.n[r], resourceNames[r], limit)
+  }
+  u.n[r]++
+  return nil
+}
+
+func (u *usage) release(r resource) {
+  u.lk.Lock()
+  if u.n[r] > 0 {
+    u.n[r]--
+  }
+  u.lk.Unlock()
+}
+
+// PB returns u as a pb.Usage.
+func (u *usage) PB() *pb.Usage {
+  out := &pb.Usage{}
+  u.lk.Lock()
+  for r := resource(0); r < numResources; r++ {
+    n := int64(u.n[r])
+    *usageField(out, r) = &n
+  }
+  u.lk.Unlock()
+  b := atomic.LoadInt64(&u.bytes)
+  out.Bytes = &b
+  return out
+}
+
+func usageField(u *pb.Usage, r resource) **int64 {
+  switch r {
+  case resListener:
+    return &u.Listeners
+  case resDialer:
+    return &u.Dialers
+  case resConn:
+    return &u.Conns
+  case resStream:
+    return &u.Streams
+  case resPendingAccept:
+    return &u.PendingAccepts
+  default:
+    return &u.InFlight
+  }
+}
+
+// bucket is a token bucket, of bytes. It holds up to a second's worth.
+type bucket struct {
+  lk     sync.Mutex
+  tokens float64
+  last   time.Time
+}
+
+// take takes n bytes out of the bucket, at rate bytes per second, and
+// returns how long to wait before using them.
+func (b *bucket) take(n int, rate int64) time.Duration {
+  b.lk.Lock()
+  defer b.lk.Unlock()
+
+  now := time.Now()
+  if !b.last.IsZero() {
+    b.tokens += now.Sub(b.last).Seconds() * float64(rate)
+  } else {
+    b.tokens = float64(rate)
+  }
+  if b.tokens > float64(rate) {
+    b.tokens = float64(rate)
+  }
+  b.last = now
+
+  b.tokens -= float64(n)
+  if b.tokens >= 0 {
+    return 0
+  }
+  return time.Duration(-b.tokens / float64(rate) * float64(time.Second))
+}
+
+// limits returns the client's limits: its grant's, or the server's
+// ClientLimits.
+func (sc *ServerClient) limits() *Limits {
+  if g, _ := sc.grant(); g != nil && g.Limits != nil {
+    return g.Limits
+  }
+  if sc.Server == nil {
+    return nil
+  }
+  return sc.Server.ClientLimits
+}
+
+// serverLimits returns the limits of all clients together.
+func (sc *ServerClient) serverLimits() *Limits {
+  if sc.Server == nil {
+    return nil
+  }
+  return sc.Server.Limits
+}
+
+// reserve counts one more r for the client, and the server, unless
+// that goes over a limit.
+func (sc *ServerClient) reserve(r resource) error {
+  if err := sc.usage.acquire(r, sc.limits().max(r), "client"); err != nil {
+    return err
+  }
+  if sc.Server != nil {
+    if err := sc.Server.usage.acquire(r, sc.serverLimits().max(r), "server"); err != nil {
+      sc.usage.release(r)
+      return err
+    }
+  }
+  return nil
+}
+
+func (sc *ServerClient) release(r resource) {
+  sc.usage.release(r)
+  if sc.Server != nil {
+    sc.Server.usage.release(r)
+  }
+}
+
+// closed releases descriptor v, and tells watchers it closed.
+func (sc *ServerClient) closed(v interface{}) {
+  typ, _ := watchKey(v)
+  if r, ok := resourceOf(typ); ok {
+    sc.release(r)
+  }
+  sc.emit(pb.WatchRes_Closed, v, nil)
+}
+
+// throttle counts n bytes proxied, and waits until the client's and the
+// server's bandwidth allow them, or done is closed.
+func (sc *ServerClient) throttle(n int, done <-chan struct{}) {
+  atomic.AddInt64(&sc.usage.bytes, int64(n))
+  var wait time.Duration
+  if bw := sc.limits().bandwidth(); bw > 0 {
+    wait = sc.usage.bucket.take(n, bw)
+  }
+  if sc.Server != nil {
+    atomic.AddInt64(&sc.Server.usage.bytes, int64(n))
+    if bw := sc.serverLimits().bandwidth(); bw > 0 {
+      if w := sc.Server.usage.bucket.take(n, bw); w > wait {
+        wait = w
+      }
+    }
+  }
+  if wait <= 0 {
+    return
+  }
+
+  t := time.NewTimer(wait)
+  defer t.Stop()
+  select {
+  case <-t.C:
+  case <-done:
+  }
+}
+
+// Usage returns what the client uses, and what all clients use, with
+// their limits.
+func (sc *ServerClient) Usage() *pb.UsageRes {
+  res := &pb.UsageRes{
+    Usage:        sc.usage.PB(),
+    Limits:       sc.limits().PB(),
+    ServerLimits: sc.serverLimits().PB(),
+  }
+  if sc.Server != nil {
+    res.ServerUsage = sc.Server.usage.PB()
+  }
+  return res
+}
+
+func handleUsageReq(sc *ServerClient, s *rpcCall, req *pb.UsageReq) error {
+  return xrpc.UsageRes(s, sc.Usage(), nil)
+}
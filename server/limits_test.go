@@ -0,0 +1,300 @@
+package xtpserver_test
+
+import (
+  "errors"
+  "io"
+  "strings"
+  "testing"
+
+  xclient "github.com/libp2p/go-xtp-ctl/client"
+  ximpls "github.com/libp2p/go-xtp-ctl/impls"
+  xnet "github.com/libp2p/go-xtp-ctl/net"
+  pb "github.com/libp2p/go-xtp-ctl/pb"
+  xrpc "github.com/libp2p/go-xtp-ctl/rpc"
+  xserver "github.com/libp2p/go-xtp-ctl/server"
+  "github.com/libp2p/go-xtp-ctl/xtptest"
+  ma "github.com/multiformats/go-multiaddr"
+)
+
+// limitedServer starts a server offering /memory, with limits for each
+// client and for all of them, and returns how to connect clients to it.
+func limitedServer(t *testing.T, client, server *xserver.Limits) func() *xclient.Client {
+  s, err := xserver.NewServer(xtptest.TCPAddr, []xnet.Transport{&ximpls.MemoryTransport{}})
+  if err != nil {
+    t.Fatal(err)
+  }
+  s.ClientLimits = client
+  s.Limits = server
+  go s.Serve()
+  t.Cleanup(func() { s.Close() })
+
+  return func() *xclient.Client {
+    c, err := xclient.NewClient(s.Listener.Multiaddr())
+    if err != nil {
+      t.Fatal(err)
+    }
+    t.Cleanup(func() { c.Close() })
+    return c
+  }
+}
+
+func usageOf(t *testing.T, c *xclient.Client) *pb.UsageRes {
+  t.Helper()
+  u, err := c.Usage()
+  if err != nil {
+    t.Fatal(err)
+  }
+  return u
+}
+
+// listenPeer has c listen on /memory/peer.
+func listenPeer(t *testing.T, c *xclient.Client) xnet.Listener {
+  t.Helper()
+  l, err := c.Transport("/memory").Listen(ma.StringCast("/memory/peer"))
+  if err != nil {
+    t.Fatal(err)
+  }
+  return l
+}
+
+func dialPeer(c *xclient.Client) (xnet.Conn, error) {
+  return c.Transport("/memory").Dial(ma.StringCast("/memory/peer"))
+}
+
+func listenMemory(c *xclient.Client, name string) (xnet.Listener, error) {
+  return c.Transport("/memory").Listen(ma.StringCast("/memory/" + name))
+}
+
+func TestLimitsExhausted(t *testing.T) {
+  cases := []struct {
+    name   string
+    limits xserver.Limits
+    // fill uses up the limit of client c, and over goes over it. peer
+    // is another client, with limits of its own.
+    fill func(t *testing.T, c, peer *xclient.Client) interface{}
+    over func(c *xclient.Client, filled interface{}) error
+  }{
+    {
+      name:   "listeners",
+      limits: xserver.Limits{Listeners: 1},
+      fill: func(t *testing.T, c, peer *xclient.Client) interface{} {
+        if _, err := listenMemory(c, "a"); err != nil {
+          t.Fatal(err)
+        }
+        return nil
+      },
+      over: func(c *xclient.Client, _ interface{}) error {
+        _, err := listenMemory(c, "b")
+        return err
+      },
+    },
+    {
+      name:   "dialers",
+      limits: xserver.Limits{Dialers: 1},
+      fill: func(t *testing.T, c, peer *xclient.Client) interface{} {
+        if _, err := c.Transport("/memory").Dialer(ma.StringCast("/memory/d1")); err != nil {
+          t.Fatal(err)
+        }
+        return nil
+      },
+      over: func(c *xclient.Client, _ interface{}) error {
+        _, err := c.Transport("/memory").Dialer(ma.StringCast("/memory/d2"))
+        return err
+      },
+    },
+    {
+      name:   "conns",
+      limits: xserver.Limits{Conns: 1},
+      fill: func(t *testing.T, c, peer *xclient.Client) interface{} {
+        listenPeer(t, peer)
+        if _, err := dialPeer(c); err != nil {
+          t.Fatal(err)
+        }
+        return nil
+      },
+      over: func(c *xclient.Client, _ interface{}) error {
+        _, err := dialPeer(c)
+        return err
+      },
+    },
+    {
+      name:   "streams",
+      limits: xserver.Limits{Streams: 1},
+      fill: func(t *testing.T, c, peer *xclient.Client) interface{} {
+        l := listenPeer(t, peer)
+        go l.Accept() // the conn carries streams once accepted.
+        cn, err := dialPeer(c)
+        if err != nil {
+          t.Fatal(err)
+        }
+        if _, err := cn.Dial(); err != nil {
+          t.Fatal(err)
+        }
+        return cn
+      },
+      over: func(c *xclient.Client, cn interface{}) error {
+        _, err := cn.(xnet.Conn).Dial()
+        return err
+      },
+    },
+    {
+      name:   "pending accepts",
+      limits: xserver.Limits{PendingAccepts: 1},
+      fill: func(t *testing.T, c, peer *xclient.Client) interface{} {
+        l, err := listenMemory(c, "a")
+        if err != nil {
+          t.Fatal(err)
+        }
+        go l.Accept()
+        eventually(t, "a pending accept", func() bool {
+          return usageOf(t, c).GetUsage().GetPendingAccepts() == 1
+        })
+        return l
+      },
+      over: func(c *xclient.Client, l interface{}) error {
+        _, err := l.(xnet.Listener).Accept()
+        return err
+      },
+    },
+    {
+      name:   "in flight",
+      limits: xserver.Limits{InFlight: 1},
+      fill: func(t *testing.T, c, peer *xclient.Client) interface{} {
+        l, err := listenMemory(c, "a")
+        if err != nil {
+          t.Fatal(err)
+        }
+        go l.Accept()
+        // peer's own Usage is in flight too.
+        eventually(t, "a pending accept", func() bool {
+          return usageOf(t, peer).GetServerUsage().GetInFlight() == 2
+        })
+        return nil
+      },
+      over: func(c *xclient.Client, _ interface{}) error {
+        _, err := c.Usage()
+        return err
+      },
+    },
+  }
+
+  for _, tc := range cases {
+    t.Run(tc.name, func(t *testing.T) {
+      limits := tc.limits
+      connect := limitedServer(t, &limits, nil)
+      c, peer := connect(), connect()
+
+      filled := tc.fill(t, c, peer)
+      err := tc.over(c, filled)
+      if !errors.Is(err, xrpc.ErrResourceExhausted) {
+        t.Fatal("over the limit: expected ErrResourceExhausted, got", err)
+      }
+    })
+  }
+}
+
+// TestServerLimits goes over a client's limit, and that of all clients
+// together.
+func TestServerLimits(t *testing.T) {
+  connect := limitedServer(t, &xserver.Limits{Listeners: 2}, &xserver.Limits{Listeners: 3})
+  c, c2 := connect(), connect()
+
+  exhausted := func(err error, who string) {
+    t.Helper()
+    if !errors.Is(err, xrpc.ErrResourceExhausted) || !strings.Contains(err.Error(), who+" has") {
+      t.Fatalf("expected the %s's limit, got %v", who, err)
+    }
+  }
+  for _, name := range []string{"a", "b"} {
+    if _, err := listenMemory(c2, name); err != nil {
+      t.Fatal(err)
+    }
+  }
+  _, err := listenMemory(c2, "c")
+  exhausted(err, "client")
+  if _, err := listenMemory(c, "d"); err != nil {
+    t.Fatal(err)
+  }
+  _, err = listenMemory(c, "e")
+  exhausted(err, "server")
+
+  u := usageOf(t, c)
+  if u.GetUsage().GetListeners() != 1 || u.GetLimits().GetListeners() != 2 ||
+    u.GetServerUsage().GetListeners() != 3 || u.GetServerLimits().GetListeners() != 3 {
+    t.Fatal("usage:", u)
+  }
+}
+
+// TestLimitsRelease checks that what clients use is counted back down:
+// when they close descriptors, when streams end, and when they go.
+func TestLimitsRelease(t *testing.T) {
+  connect := limitedServer(t, nil, nil)
+  admin := connect()
+
+  // requireUsage checks what all clients use, with admin's Usage in
+  // flight.
+  requireUsage := func(what string, listeners, dialers, conns, streams int64) {
+    t.Helper()
+    eventually(t, what, func() bool {
+      u := usageOf(t, admin).GetServerUsage()
+      return u.GetListeners() == listeners && u.GetDialers() == dialers &&
+        u.GetConns() == conns && u.GetStreams() == streams &&
+        u.GetPendingAccepts() == 0 && u.GetInFlight() == 1
+    })
+  }
+
+  c, peer := connect(), connect()
+  l := listenPeer(t, peer)
+  d, err := c.Transport("/memory").Dialer(ma.StringCast("/memory/d"))
+  if err != nil {
+    t.Fatal(err)
+  }
+  cn, err := d.Dial(l.Multiaddr())
+  if err != nil {
+    t.Fatal(err)
+  }
+  pcn, err := l.Accept()
+  if err != nil {
+    t.Fatal(err)
+  }
+  s, err := cn.Dial()
+  if err != nil {
+    t.Fatal(err)
+  }
+  s.Write([]byte("x"))
+  ps, err := pcn.Accept()
+  if err != nil {
+    t.Fatal(err)
+  }
+  requireUsage("the descriptors to open", 1, 1, 2, 2)
+
+  // streams end when both sides are done.
+  s.Close()
+  if _, err := io.ReadAll(ps); err != nil {
+    t.Fatal(err)
+  }
+  ps.Close()
+  requireUsage("the streams to end", 1, 1, 2, 0)
+
+  // CloseReqs.
+  cn.Close()
+  d.Close()
+  requireUsage("the conn and dialer to close", 1, 0, 1, 0)
+  pcn.Close()
+  l.Close()
+  requireUsage("the peer's descriptors to close", 0, 0, 0, 0)
+
+  // and clients going away.
+  l = listenPeer(t, peer)
+  go l.Accept()
+  cn, err = dialPeer(c)
+  if err != nil {
+    t.Fatal(err)
+  }
+  if _, err := cn.Dial(); err != nil {
+    t.Fatal(err)
+  }
+  c.Close()
+  peer.Close()
+  requireUsage("the clients to go", 0, 0, 0, 0)
+}
@@ -1,28 +1,45 @@
 package xtpserver
 
 import (
+  "context"
+
   xnet "github.com/libp2p/go-xtp-ctl/net"
+  pb "github.com/libp2p/go-xtp-ctl/pb"
+  xtptrace "github.com/libp2p/go-xtp-ctl/trace"
+
+  "go.opentelemetry.io/otel/attribute"
 )
 
 type listener struct {
   id    int64
-  rawL  xnet.Listener
+  rawL  xnet.ListenerContext
   xport *transport
+  stats *stats
 }
 
 func newListener(id int64, t *transport, l xnet.Listener) *listener {
-  return &listener{id, l, t}
+  return &listener{id, xnet.ListenerWithContext(l), t, newStats(t.stats)}
 }
 
-func (l *listener) Accept() (*conn, error) {
-  c, err := l.rawL.Accept()
+func (l *listener) Accept(ctx context.Context) (_ *conn, err error) {
+  ctx, span := xtptrace.Start(ctx, "listener.Accept", attribute.Int64("id", l.id))
+  defer func() { xtptrace.End(span, err) }()
+
+  c, err := l.rawL.AcceptContext(ctx)
   if err != nil {
+    l.xport.sc.opFailed(l, err)
+    return nil, err
+  }
+  if err := l.xport.sc.reserve(resConn); err != nil {
+    c.Close()
     return nil, err
   }
   id := l.xport.sc.NextId()
 
-  c2 := newConn(id, l.xport, c)
+  c2 := newConn(id, l.xport, c, l.stats)
   l.xport.addConn(c2)
+  l.stats.addConn()
+  l.xport.sc.emit(pb.WatchRes_Accepted, c2, nil)
   return c2, nil
 }
 
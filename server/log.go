@@ -0,0 +1,56 @@
+package xtpserver
+
+import (
+  "context"
+  "log/slog"
+  "time"
+
+  pb "github.com/libp2p/go-xtp-ctl/pb"
+  xrpc "github.com/libp2p/go-xtp-ctl/rpc"
+)
+
+// The server logs clients connecting and disconnecting at Info, every
+// rpc at Debug (Info if it failed), and internal failures at Error.
+
+func (s *Server) logger() *slog.Logger {
+  if s.Logger != nil {
+    return s.Logger
+  }
+  return slog.Default()
+}
+
+// logRPC logs the outcome of request req, answered in d. id is the
+// descriptor it is about, or 0, and opened are the descriptors it opened.
+func (sc *ServerClient) logRPC(req *pb.RPC, id int64, opened []int64, d time.Duration, err error) {
+  level := slog.LevelDebug
+  if err != nil && err != errSpliced {
+    level = slog.LevelInfo
+  }
+  ctx := context.Background()
+  if !sc.log.Enabled(ctx, level) {
+    return
+  }
+
+  attrs := []slog.Attr{
+    slog.String("rpc", req.GetRpc().String()),
+    slog.Duration("dur", d),
+  }
+  if id != 0 {
+    attrs = append(attrs, slog.Int64("id", id))
+  }
+  if len(opened) > 0 {
+    attrs = append(attrs, slog.Any("opened", opened))
+  }
+  switch err {
+  case nil:
+    attrs = append(attrs, slog.String("outcome", "ok"))
+  case errSpliced:
+    attrs = append(attrs, slog.String("outcome", "spliced"))
+  default:
+    attrs = append(attrs,
+      slog.String("outcome", "error"),
+      slog.String("code", xrpc.ErrCode(err).String()),
+      slog.String("err", err.Error()))
+  }
+  sc.log.LogAttrs(ctx, level, "rpc", attrs...)
+}
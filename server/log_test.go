@@ -0,0 +1,90 @@
+package xtpserver
+
+import (
+  "bytes"
+  "encoding/json"
+  "fmt"
+  "log/slog"
+  "testing"
+  "time"
+
+  pb "github.com/libp2p/go-xtp-ctl/pb"
+  xrpc "github.com/libp2p/go-xtp-ctl/rpc"
+)
+
+func TestLogRPC(t *testing.T) {
+  cases := []struct {
+    name   string
+    rpc    pb.RPC_Type
+    id     int64
+    opened []int64
+    err    error
+    level  string
+    // attrs expected, as they read in JSON. nil ones must be absent.
+    attrs map[string]interface{}
+  }{
+    {
+      name:   "opened",
+      rpc:    pb.RPC_ListenReq,
+      opened: []int64{5},
+      level:  "DEBUG",
+      attrs: map[string]interface{}{
+        "rpc": "ListenReq", "opened": []interface{}{5.0}, "outcome": "ok",
+        "id": nil, "code": nil, "err": nil,
+      },
+    },
+    {
+      name:  "failed",
+      rpc:   pb.RPC_CloseReq,
+      id:    7,
+      err:   fmt.Errorf("close 7: %w", xrpc.ErrNotFound),
+      level: "INFO",
+      attrs: map[string]interface{}{
+        "rpc": "CloseReq", "id": 7.0, "outcome": "error",
+        "code": "ErrCodeNotFound", "err": "close 7: descriptor not found",
+        "opened": nil,
+      },
+    },
+    {
+      name:  "spliced",
+      rpc:   pb.RPC_DialReq,
+      id:    3,
+      err:   errSpliced,
+      level: "DEBUG",
+      attrs: map[string]interface{}{
+        "rpc": "DialReq", "id": 3.0, "outcome": "spliced", "code": nil, "err": nil,
+      },
+    },
+  }
+
+  for _, tc := range cases {
+    t.Run(tc.name, func(t *testing.T) {
+      var buf bytes.Buffer
+      sc := &ServerClient{log: slog.New(slog.NewJSONHandler(&buf, &slog.HandlerOptions{Level: slog.LevelDebug}))}
+      sc.logRPC(&pb.RPC{Rpc: tc.rpc.Enum()}, tc.id, tc.opened, time.Millisecond, tc.err)
+
+      var rec map[string]interface{}
+      if err := json.Unmarshal(buf.Bytes(), &rec); err != nil {
+        t.Fatalf("%s: %q", err, buf.String())
+      }
+      if rec["level"] != tc.level || rec["msg"] != "rpc" || rec["dur"] != float64(time.Millisecond) {
+        t.Fatal("record:", rec)
+      }
+      for k, want := range tc.attrs {
+        got, ok := rec[k]
+        if want == nil && ok || want != nil && fmt.Sprint(got) != fmt.Sprint(want) {
+          t.Errorf("%s: got %v, expected %v", k, got, want)
+        }
+      }
+    })
+  }
+
+  // successes are not logged at Info.
+  var buf bytes.Buffer
+  sc := &ServerClient{log: slog.New(slog.NewJSONHandler(&buf, nil))}
+  sc.logRPC(&pb.RPC{Rpc: pb.RPC_ListReq.Enum()}, 0, nil, time.Millisecond, nil)
+  sc.logRPC(&pb.RPC{Rpc: pb.RPC_ListReq.Enum()}, 0, nil, time.Millisecond, errSpliced)
+  if buf.Len() != 0 {
+    t.Fatal("logged at Info:", buf.String())
+  }
+}
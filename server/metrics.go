@@ -0,0 +1,231 @@
+package xtpserver
+
+import (
+  "bufio"
+  "fmt"
+  "net/http"
+  "sort"
+  "strings"
+  "sync"
+  "sync/atomic"
+  "time"
+
+  pb "github.com/libp2p/go-xtp-ctl/pb"
+)
+
+// rpcBuckets are the upper bounds of the rpc latency histogram, in
+// seconds. The same as Prometheus' defaults.
+var rpcBuckets = []float64{.005, .01, .025, .05, .1, .25, .5, 1, 2.5, 5, 10}
+
+// Metrics are the process level counters of a Server. MetricsHandler
+// exports them, with gauges of the server's current state, in the
+// Prometheus text format. A nil *Metrics counts nothing.
+type Metrics struct {
+  bytesIn  int64 // atomic
+  bytesOut int64 // atomic
+
+  lk     sync.Mutex
+  rpcs   map[pb.RPC_Type]*rpcMetric
+  errors map[pb.ErrCode]int64
+}
+
+type rpcMetric struct {
+  count   int64
+  sum     float64 // seconds
+  buckets []int64 // counts per rpcBuckets bound, not cumulative
+}
+
+func NewMetrics() *Metrics {
+  return &Metrics{
+    rpcs:   make(map[pb.RPC_Type]*rpcMetric),
+    errors: make(map[pb.ErrCode]int64),
+  }
+}
+
+// observeRPC counts an rpc of type typ, answered after d.
+func (m *Metrics) observeRPC(typ pb.RPC_Type, d time.Duration) {
+  if m == nil {
+    return
+  }
+
+  secs := d.Seconds()
+  m.lk.Lock()
+  defer m.lk.Unlock()
+
+  r := m.rpcs[typ]
+  if r == nil {
+    r = &rpcMetric{buckets: make([]int64, len(rpcBuckets))}
+    m.rpcs[typ] = r
+  }
+  r.count++
+  r.sum += secs
+  for i, b := range rpcBuckets {
+    if secs <= b {
+      r.buckets[i]++
+      break
+    }
+  }
+}
+
+// rpcError counts an rpc that failed with code.
+func (m *Metrics) rpcError(code pb.ErrCode) {
+  if m == nil {
+    return
+  }
+  m.lk.Lock()
+  m.errors[code]++
+  m.lk.Unlock()
+}
+
+// addIn counts n bytes received from remotes, and proxied to a client.
+func (m *Metrics) addIn(n int) {
+  if m != nil {
+    atomic.AddInt64(&m.bytesIn, int64(n))
+  }
+}
+
+// addOut counts n bytes from a client, proxied to remotes.
+func (m *Metrics) addOut(n int) {
+  if m != nil {
+    atomic.AddInt64(&m.bytesOut, int64(n))
+  }
+}
+
+// MetricsHandler returns an http.Handler serving the server's metrics,
+// in the Prometheus text format. Mount it at /metrics.
+func (s *Server) MetricsHandler() http.Handler {
+  return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+    w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+    bw := bufio.NewWriter(w)
+    s.writeMetrics(bw)
+    bw.Flush()
+  })
+}
+
+func (s *Server) writeMetrics(w *bufio.Writer) {
+  s.Lock()
+  clients := append([]*ServerClient(nil), s.Clients...)
+  s.Unlock()
+
+  // gauges, of the current state.
+  byXport := make(map[string]int64)
+  descs := make(map[pb.TType]int64)
+  for _, sc := range clients {
+    byXport[clientTransport(sc)]++
+    sc.countDescriptors(descs)
+  }
+
+  writeHeader(w, "xtpctl_clients", "gauge", "Connected clients, by the transport they connected with.")
+  for _, code := range sortedKeys(byXport) {
+    fmt.Fprintf(w, "xtpctl_clients{transport=%q} %d\n", code, byXport[code])
+  }
+
+  writeHeader(w, "xtpctl_descriptors", "gauge", "Open descriptors, by type.")
+  for _, t := range []pb.TType{
+    pb.TType_TTypeTransport,
+    pb.TType_TTypeListener,
+    pb.TType_TTypeDialer,
+    pb.TType_TTypeConn,
+    pb.TType_TTypeStream,
+  } {
+    name := strings.ToLower(strings.TrimPrefix(t.String(), "TType"))
+    fmt.Fprintf(w, "xtpctl_descriptors{type=%q} %d\n", name, descs[t])
+  }
+
+  m := s.Metrics
+  if m == nil {
+    return
+  }
+
+  writeHeader(w, "xtpctl_proxied_bytes_total", "counter", "Bytes proxied between clients and remotes.")
+  fmt.Fprintf(w, "xtpctl_proxied_bytes_total{direction=\"in\"} %d\n", atomic.LoadInt64(&m.bytesIn))
+  fmt.Fprintf(w, "xtpctl_proxied_bytes_total{direction=\"out\"} %d\n", atomic.LoadInt64(&m.bytesOut))
+
+  m.lk.Lock()
+  defer m.lk.Unlock()
+
+  var types []pb.RPC_Type
+  for t := range m.rpcs {
+    types = append(types, t)
+  }
+  sort.Slice(types, func(i, j int) bool { return types[i] < types[j] })
+
+  writeHeader(w, "xtpctl_rpc_duration_seconds", "histogram", "Time to answer rpcs, by request type.")
+  for _, t := range types {
+    r := m.rpcs[t]
+    var cum int64
+    for i, b := range rpcBuckets {
+      cum += r.buckets[i]
+      fmt.Fprintf(w, "xtpctl_rpc_duration_seconds_bucket{rpc=%q,le=\"%g\"} %d\n", t, b, cum)
+    }
+    fmt.Fprintf(w, "xtpctl_rpc_duration_seconds_bucket{rpc=%q,le=\"+Inf\"} %d\n", t, r.count)
+    fmt.Fprintf(w, "xtpctl_rpc_duration_seconds_sum{rpc=%q} %g\n", t, r.sum)
+    fmt.Fprintf(w, "xtpctl_rpc_duration_seconds_count{rpc=%q} %d\n", t, r.count)
+  }
+
+  writeHeader(w, "xtpctl_rpc_errors_total", "counter", "Failed rpcs, by error code.")
+  var codes []pb.ErrCode
+  for c := range m.errors {
+    codes = append(codes, c)
+  }
+  sort.Slice(codes, func(i, j int) bool { return codes[i] < codes[j] })
+  for _, c := range codes {
+    fmt.Fprintf(w, "xtpctl_rpc_errors_total{code=%q} %d\n", strings.TrimPrefix(c.String(), "ErrCode"), m.errors[c])
+  }
+}
+
+func writeHeader(w *bufio.Writer, name, typ, help string) {
+  fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s %s\n", name, help, name, typ)
+}
+
+func sortedKeys(m map[string]int64) []string {
+  var ks []string
+  for k := range m {
+    ks = append(ks, k)
+  }
+  sort.Strings(ks)
+  return ks
+}
+
+// clientTransport returns the code of the transport the client connected
+// with: the last protocol of its address, e.g. /tcp.
+func clientTransport(sc *ServerClient) string {
+  a := sc.conn().RemoteMultiaddr()
+  if a == nil {
+    return "unknown"
+  }
+  ps := a.Protocols()
+  if len(ps) == 0 {
+    return "unknown"
+  }
+  return "/" + ps[len(ps)-1].Name
+}
+
+// metrics returns the server's metrics, if any.
+func (sc *ServerClient) metrics() *Metrics {
+  if sc.Server == nil {
+    return nil
+  }
+  return sc.Server.Metrics
+}
+
+// countDescriptors adds the client's open descriptors to counts, by type.
+func (sc *ServerClient) countDescriptors(counts map[pb.TType]int64) {
+  sc.RLock()
+  defer sc.RUnlock()
+
+  for _, t := range sc.transports {
+    counts[pb.TType_TTypeTransport]++
+
+    t.RLock()
+    counts[pb.TType_TTypeListener] += int64(len(t.listeners))
+    counts[pb.TType_TTypeDialer] += int64(len(t.dialers))
+    counts[pb.TType_TTypeConn] += int64(len(t.conns))
+    for _, c := range t.conns {
+      c.RLock()
+      counts[pb.TType_TTypeStream] += int64(len(c.streams))
+      c.RUnlock()
+    }
+    t.RUnlock()
+  }
+}
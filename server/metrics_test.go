@@ -0,0 +1,111 @@
+package xtpserver
+
+import (
+  "bufio"
+  "strings"
+  "testing"
+  "time"
+
+  xnet "github.com/libp2p/go-xtp-ctl/net"
+  pb "github.com/libp2p/go-xtp-ctl/pb"
+  ma "github.com/multiformats/go-multiaddr"
+)
+
+// remoteConn is a client's session, of which metrics only need the
+// address.
+type remoteConn struct {
+  xnet.Conn
+  addr ma.Multiaddr
+}
+
+func (c remoteConn) RemoteMultiaddr() ma.Multiaddr { return c.addr }
+
+const gaugesText = `# HELP xtpctl_clients Connected clients, by the transport they connected with.
+# TYPE xtpctl_clients gauge
+xtpctl_clients{transport="/tcp"} 1
+# HELP xtpctl_descriptors Open descriptors, by type.
+# TYPE xtpctl_descriptors gauge
+xtpctl_descriptors{type="transport"} 1
+xtpctl_descriptors{type="listener"} 2
+xtpctl_descriptors{type="dialer"} 0
+xtpctl_descriptors{type="conn"} 0
+xtpctl_descriptors{type="stream"} 0
+`
+
+const countersText = `# HELP xtpctl_proxied_bytes_total Bytes proxied between clients and remotes.
+# TYPE xtpctl_proxied_bytes_total counter
+xtpctl_proxied_bytes_total{direction="in"} 100
+xtpctl_proxied_bytes_total{direction="out"} 42
+# HELP xtpctl_rpc_duration_seconds Time to answer rpcs, by request type.
+# TYPE xtpctl_rpc_duration_seconds histogram
+xtpctl_rpc_duration_seconds_bucket{rpc="ListenReq",le="0.005"} 1
+xtpctl_rpc_duration_seconds_bucket{rpc="ListenReq",le="0.01"} 1
+xtpctl_rpc_duration_seconds_bucket{rpc="ListenReq",le="0.025"} 1
+xtpctl_rpc_duration_seconds_bucket{rpc="ListenReq",le="0.05"} 1
+xtpctl_rpc_duration_seconds_bucket{rpc="ListenReq",le="0.1"} 1
+xtpctl_rpc_duration_seconds_bucket{rpc="ListenReq",le="0.25"} 2
+xtpctl_rpc_duration_seconds_bucket{rpc="ListenReq",le="0.5"} 2
+xtpctl_rpc_duration_seconds_bucket{rpc="ListenReq",le="1"} 2
+xtpctl_rpc_duration_seconds_bucket{rpc="ListenReq",le="2.5"} 2
+xtpctl_rpc_duration_seconds_bucket{rpc="ListenReq",le="5"} 2
+xtpctl_rpc_duration_seconds_bucket{rpc="ListenReq",le="10"} 2
+xtpctl_rpc_duration_seconds_bucket{rpc="ListenReq",le="+Inf"} 2
+xtpctl_rpc_duration_seconds_sum{rpc="ListenReq"} 0.12890625
+xtpctl_rpc_duration_seconds_count{rpc="ListenReq"} 2
+xtpctl_rpc_duration_seconds_bucket{rpc="DialReq",le="0.005"} 0
+xtpctl_rpc_duration_seconds_bucket{rpc="DialReq",le="0.01"} 0
+xtpctl_rpc_duration_seconds_bucket{rpc="DialReq",le="0.025"} 0
+xtpctl_rpc_duration_seconds_bucket{rpc="DialReq",le="0.05"} 0
+xtpctl_rpc_duration_seconds_bucket{rpc="DialReq",le="0.1"} 0
+xtpctl_rpc_duration_seconds_bucket{rpc="DialReq",le="0.25"} 0
+xtpctl_rpc_duration_seconds_bucket{rpc="DialReq",le="0.5"} 0
+xtpctl_rpc_duration_seconds_bucket{rpc="DialReq",le="1"} 0
+xtpctl_rpc_duration_seconds_bucket{rpc="DialReq",le="2.5"} 0
+xtpctl_rpc_duration_seconds_bucket{rpc="DialReq",le="5"} 0
+xtpctl_rpc_duration_seconds_bucket{rpc="DialReq",le="10"} 0
+xtpctl_rpc_duration_seconds_bucket{rpc="DialReq",le="+Inf"} 1
+xtpctl_rpc_duration_seconds_sum{rpc="DialReq"} 20
+xtpctl_rpc_duration_seconds_count{rpc="DialReq"} 1
+# HELP xtpctl_rpc_errors_total Failed rpcs, by error code.
+# TYPE xtpctl_rpc_errors_total counter
+xtpctl_rpc_errors_total{code="NotFound"} 2
+xtpctl_rpc_errors_total{code="ConnRefused"} 1
+`
+
+func TestWriteMetrics(t *testing.T) {
+  s := &Server{}
+  sc := newServerClient(s, remoteConn{addr: ma.StringCast("/ip4/127.0.0.1/tcp/1")})
+  tpt := newTransport(sc.NextId(), sc, nil)
+  tpt.listeners[sc.NextId()] = &listener{}
+  tpt.listeners[sc.NextId()] = &listener{}
+  sc.addTransport(tpt)
+  s.addClient(sc)
+
+  write := func() string {
+    var b strings.Builder
+    w := bufio.NewWriter(&b)
+    s.writeMetrics(w)
+    w.Flush()
+    return b.String()
+  }
+  // without Metrics, only the gauges.
+  if out := write(); out != gaugesText {
+    t.Fatalf("got:\n%s\nexpected:\n%s", out, gaugesText)
+  }
+
+  m := NewMetrics()
+  // durations, and so the sum, are exact in binary.
+  m.observeRPC(pb.RPC_ListenReq, time.Second/256)
+  m.observeRPC(pb.RPC_ListenReq, time.Second/8)
+  m.observeRPC(pb.RPC_DialReq, 20*time.Second)
+  m.rpcError(pb.ErrCode_ErrCodeConnRefused)
+  m.rpcError(pb.ErrCode_ErrCodeNotFound)
+  m.rpcError(pb.ErrCode_ErrCodeNotFound)
+  m.addIn(100)
+  m.addOut(42)
+  s.Metrics = m
+
+  if out, want := write(), gaugesText+countersText; out != want {
+    t.Fatalf("got:\n%s\nexpected:\n%s", out, want)
+  }
+}
@@ -0,0 +1,349 @@
+package xtpserver
+
+import (
+  "crypto/ed25519"
+  "crypto/subtle"
+  "crypto/x509"
+  "encoding/base64"
+  "encoding/json"
+  "fmt"
+  "net"
+  "os"
+  "strconv"
+  "strings"
+
+  ma "github.com/multiformats/go-multiaddr"
+  pb "github.com/libp2p/go-xtp-ctl/pb"
+  xrpc "github.com/libp2p/go-xtp-ctl/rpc"
+)
+
+// Policy is who may connect to a Server, and what they may do. Clients
+// authenticate (see AuthReq) as an identity, with a token, an ed25519
+// key, or a TLS client certificate, and get the Grant of that identity.
+// A Server without a Policy lets everyone do anything.
+//
+// Policies are usually loaded from json files, with LoadPolicy:
+//
+//   {
+//     "tokens": {"s3cret": "ci"},
+//     "keys": {"<base64 ed25519 public key>": "alice"},
+//     "tlsIdentity": true,
+//     "clients": {
+//       "alice": {"transports": ["*"], "listen": ["*"], "dial": ["*"], "rpcs": ["*"]},
+//       "ci": {
+//         "transports": ["/tcp"],
+//         "listen": ["/ip4/127.0.0.1"],
+//         "dial": ["/ip4/10.0.0.0/ipcidr/8/tcp/8000-8999"],
+//         "rpcs": ["ListReq", "CloseReq", "DialReq", "AcceptReq", "ListenReq"]
+//       }
+//     }
+//   }
+//
+type Policy struct {
+  Tokens map[string]string `json:"tokens"` // shared token -> identity
+  Keys   map[string]string `json:"keys"`   // base64 ed25519 public key (raw, or DER) -> identity
+
+  // TLSIdentity authenticates clients with a verified TLS certificate as
+  // the certificate's subject common name. Certificates are verified by
+  // the server's ClientCAs, or by its PinnedKeys (see xnet.Security).
+  TLSIdentity bool `json:"tlsIdentity"`
+
+  Clients   map[string]*Grant `json:"clients"`   // identity -> grant
+  Default   *Grant            `json:"default"`   // for identities not in Clients. none if nil.
+  Anonymous *Grant            `json:"anonymous"` // for clients that did not authenticate. none if nil.
+
+  keys map[string]string // raw key -> identity
+}
+
+// Grant is what a client may do. Each list allows nothing if empty, and
+// everything if it has "*".
+type Grant struct {
+  Transports []string `json:"transports"` // transport codes, e.g. "/tcp"
+  Listen     []string `json:"listen"`     // address patterns to listen on, or bind dialers to. see addrPattern
+  Dial       []string `json:"dial"`       // address patterns to dial
+  RPCs       []string `json:"rpcs"`       // request types, e.g. "ListReq". HelloReq and AuthReq are always allowed.
+  Limits     *Limits  `json:"limits"`     // instead of the server's ClientLimits, if set
+
+  listen []addrPattern
+  dial   []addrPattern
+}
+
+// LoadPolicy reads a json Policy file.
+func LoadPolicy(path string) (*Policy, error) {
+  buf, err := os.ReadFile(path)
+  if err != nil {
+    return nil, err
+  }
+
+  p := &Policy{}
+  if err := json.Unmarshal(buf, p); err != nil {
+    return nil, fmt.Errorf("policy %s: %s", path, err)
+  }
+  if err := p.Compile(); err != nil {
+    return nil, fmt.Errorf("policy %s: %s", path, err)
+  }
+  return p, nil
+}
+
+// Compile checks p, and prepares it for use. Policies built in code must
+// be compiled before the server uses them.
+func (p *Policy) Compile() error {
+  p.keys = make(map[string]string)
+  for k, id := range p.Keys {
+    pub, err := parseKey(k)
+    if err != nil {
+      return fmt.Errorf("key of %s: %s", id, err)
+    }
+    p.keys[string(pub)] = id
+  }
+
+  grants := []*Grant{p.Default, p.Anonymous}
+  names := []string{"default", "anonymous"}
+  for id, g := range p.Clients {
+    grants = append(grants, g)
+    names = append(names, id)
+  }
+  for i, g := range grants {
+    if err := g.compile(); err != nil {
+      return fmt.Errorf("grant of %s: %s", names[i], err)
+    }
+  }
+  return nil
+}
+
+// parseKey decodes a base64 ed25519 public key, raw or DER encoded.
+func parseKey(s string) (ed25519.PublicKey, error) {
+  buf, err := base64.StdEncoding.DecodeString(s)
+  if err != nil {
+    return nil, err
+  }
+  if len(buf) == ed25519.PublicKeySize {
+    return ed25519.PublicKey(buf), nil
+  }
+  pub, err := x509.ParsePKIXPublicKey(buf)
+  if err != nil {
+    return nil, err
+  }
+  k, ok := pub.(ed25519.PublicKey)
+  if !ok {
+    return nil, fmt.Errorf("not an ed25519 key")
+  }
+  return k, nil
+}
+
+// tokenIdentity returns the identity of token, if any.
+func (p *Policy) tokenIdentity(token string) (string, bool) {
+  var found string
+  ok := false
+  for t, id := range p.Tokens {
+    // compare them all, in constant time, so timing tells nothing.
+    if subtle.ConstantTimeCompare([]byte(t), []byte(token)) == 1 {
+      found, ok = id, true
+    }
+  }
+  return found, ok
+}
+
+// keyIdentity returns the identity of key pub, if any.
+func (p *Policy) keyIdentity(pub ed25519.PublicKey) (string, bool) {
+  id, ok := p.keys[string(pub)]
+  return id, ok
+}
+
+// grant returns the grant of identity id, "" for anonymous clients.
+func (p *Policy) grant(id string) *Grant {
+  if id == "" {
+    return p.Anonymous
+  }
+  if g, ok := p.Clients[id]; ok {
+    return g
+  }
+  return p.Default
+}
+
+func (g *Grant) compile() error {
+  if g == nil {
+    return nil
+  }
+  for _, r := range g.RPCs {
+    if _, ok := pb.RPC_Type_value[r]; !ok && r != "*" {
+      return fmt.Errorf("unknown rpc: %s", r)
+    }
+  }
+
+  var err error
+  if g.listen, err = parsePatterns(g.Listen); err != nil {
+    return err
+  }
+  g.dial, err = parsePatterns(g.Dial)
+  return err
+}
+
+// AllowsRPC returns whether g allows request type t.
+func (g *Grant) AllowsRPC(t pb.RPC_Type) bool {
+  return g != nil && allows(g.RPCs, t.String())
+}
+
+// AllowsTransport returns whether g allows the transport with code.
+func (g *Grant) AllowsTransport(code string) bool {
+  return g != nil && allows(g.Transports, code)
+}
+
+// AllowsListen returns whether g allows listening on a (or binding a
+// dialer to it).
+func (g *Grant) AllowsListen(a ma.Multiaddr) bool {
+  return g != nil && matchAny(g.listen, a)
+}
+
+// AllowsDial returns whether g allows dialing a.
+func (g *Grant) AllowsDial(a ma.Multiaddr) bool {
+  return g != nil && matchAny(g.dial, a)
+}
+
+func allows(list []string, v string) bool {
+  for _, s := range list {
+    if s == "*" || s == v {
+      return true
+    }
+  }
+  return false
+}
+
+// addrPattern matches multiaddrs that start with its parts. A part's
+// value may be "*" (anything), a port range like "8000-8999", or, for
+// ip4 and ip6, a range given by a following ipcidr part:
+//
+//   /ip4/10.0.0.0/ipcidr/8/tcp/*
+//
+// The pattern "*" matches everything.
+type addrPattern []patternPart
+
+type patternPart struct {
+  proto  string
+  value  string
+  ipnet  *net.IPNet
+  lo, hi int // port range, if hi > 0
+}
+
+func parsePatterns(ss []string) ([]addrPattern, error) {
+  var ps []addrPattern
+  for _, s := range ss {
+    p, err := parsePattern(s)
+    if err != nil {
+      return nil, err
+    }
+    ps = append(ps, p)
+  }
+  return ps, nil
+}
+
+func parsePattern(s string) (addrPattern, error) {
+  if s == "*" {
+    return addrPattern{}, nil
+  }
+  if !strings.HasPrefix(s, "/") {
+    return nil, fmt.Errorf("invalid address pattern: %s", s)
+  }
+
+  var p addrPattern
+  toks := strings.Split(strings.TrimPrefix(s, "/"), "/")
+  for i := 0; i < len(toks); i++ {
+    part := patternPart{proto: toks[i]}
+    proto := ma.ProtocolWithName(part.proto)
+    if proto.Code == 0 {
+      return nil, fmt.Errorf("invalid address pattern %s: unknown protocol %s", s, part.proto)
+    }
+    if proto.Size != 0 {
+      i++
+      if i == len(toks) {
+        return nil, fmt.Errorf("invalid address pattern %s: %s needs a value", s, part.proto)
+      }
+      part.value = toks[i]
+    }
+
+    switch {
+    case part.value == "*":
+    case (proto.Code == ma.P_IP4 || proto.Code == ma.P_IP6) && i+1 < len(toks) && toks[i+1] == "ipcidr":
+      if i+2 >= len(toks) {
+        return nil, fmt.Errorf("invalid address pattern %s: ipcidr needs a value", s)
+      }
+      _, ipnet, err := net.ParseCIDR(part.value + "/" + toks[i+2])
+      if err != nil {
+        return nil, fmt.Errorf("invalid address pattern %s: %s", s, err)
+      }
+      part.ipnet = ipnet
+      i += 2
+    case strings.Contains(part.value, "-"):
+      lo, hi, ok := parseRange(part.value)
+      if !ok {
+        return nil, fmt.Errorf("invalid address pattern %s: bad range %s", s, part.value)
+      }
+      part.lo, part.hi = lo, hi
+    }
+    p = append(p, part)
+  }
+  return p, nil
+}
+
+func parseRange(s string) (int, int, bool) {
+  los, his, _ := strings.Cut(s, "-")
+  lo, err1 := strconv.Atoi(los)
+  hi, err2 := strconv.Atoi(his)
+  if err1 != nil || err2 != nil || lo > hi || hi <= 0 {
+    return 0, 0, false
+  }
+  return lo, hi, true
+}
+
+func (p addrPattern) match(a ma.Multiaddr) bool {
+  comps := ma.Split(a)
+  if len(p) > len(comps) {
+    return false
+  }
+  for i, part := range p {
+    c := comps[i]
+    proto := c.Protocols()[0]
+    if proto.Name != part.proto {
+      return false
+    }
+    if !part.matchValue(c, proto) {
+      return false
+    }
+  }
+  return true
+}
+
+func (part patternPart) matchValue(c ma.Multiaddr, proto ma.Protocol) bool {
+  if proto.Size == 0 || part.value == "*" {
+    return true
+  }
+  v, err := c.ValueForProtocol(proto.Code)
+  if err != nil {
+    return false
+  }
+
+  switch {
+  case part.ipnet != nil:
+    ip := net.ParseIP(v)
+    return ip != nil && part.ipnet.Contains(ip)
+  case part.hi > 0:
+    n, err := strconv.Atoi(v)
+    return err == nil && part.lo <= n && n <= part.hi
+  default:
+    return v == part.value
+  }
+}
+
+func matchAny(ps []addrPattern, a ma.Multiaddr) bool {
+  for _, p := range ps {
+    if p.match(a) {
+      return true
+    }
+  }
+  return false
+}
+
+// denied returns the typed error of a denied operation.
+func denied(format string, args ...interface{}) error {
+  return fmt.Errorf("%w: %s", xrpc.ErrPermissionDenied, fmt.Sprintf(format, args...))
+}
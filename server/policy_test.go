@@ -0,0 +1,67 @@
+package xtpserver
+
+import (
+  "testing"
+
+  ma "github.com/multiformats/go-multiaddr"
+)
+
+func TestAddrPatterns(t *testing.T) {
+  cases := []struct {
+    pattern string
+    addr    string
+    match   bool
+  }{
+    {"*", "/ip4/1.2.3.4/tcp/80", true},
+    {"/ip4/127.0.0.1", "/ip4/127.0.0.1/tcp/1234", true},
+    {"/ip4/127.0.0.1", "/ip4/127.0.0.2/tcp/1234", false},
+    {"/ip4/127.0.0.1/tcp/80", "/ip4/127.0.0.1", false},
+    {"/ip4/*/tcp/80", "/ip4/8.8.8.8/tcp/80", true},
+    {"/ip4/*/tcp/80", "/ip4/8.8.8.8/udp/80", false},
+    {"/ip4/*/tcp/80", "/ip6/::1/tcp/80", false},
+
+    // cidr ranges.
+    {"/ip4/10.0.0.0/ipcidr/8/tcp/*", "/ip4/10.1.2.3/tcp/22", true},
+    {"/ip4/10.0.0.0/ipcidr/8/tcp/*", "/ip4/11.0.0.1/tcp/22", false},
+    {"/ip4/192.168.1.0/ipcidr/24", "/ip4/192.168.1.255/tcp/1", true},
+    {"/ip4/192.168.1.0/ipcidr/24", "/ip4/192.168.2.0/tcp/1", false},
+    {"/ip6/fd00::/ipcidr/8/tcp/*", "/ip6/fd12::1/tcp/443", true},
+    {"/ip6/fd00::/ipcidr/8/tcp/*", "/ip6/fe80::1/tcp/443", false},
+
+    // port ranges, bounds included.
+    {"/ip4/10.0.0.0/ipcidr/8/tcp/8000-8999", "/ip4/10.0.0.1/tcp/8000", true},
+    {"/ip4/10.0.0.0/ipcidr/8/tcp/8000-8999", "/ip4/10.0.0.1/tcp/8999", true},
+    {"/ip4/10.0.0.0/ipcidr/8/tcp/8000-8999", "/ip4/10.0.0.1/tcp/7999", false},
+    {"/ip4/10.0.0.0/ipcidr/8/tcp/8000-8999", "/ip4/10.0.0.1/tcp/9000", false},
+    {"/ip4/*/udp/53-53", "/ip4/1.1.1.1/udp/53", true},
+  }
+  for _, c := range cases {
+    p, err := parsePattern(c.pattern)
+    if err != nil {
+      t.Errorf("%s: %s", c.pattern, err)
+      continue
+    }
+    if got := p.match(ma.StringCast(c.addr)); got != c.match {
+      t.Errorf("%s matching %s: %v, expected %v", c.pattern, c.addr, got, c.match)
+    }
+  }
+}
+
+func TestAddrPatternErrors(t *testing.T) {
+  bad := []string{
+    "",
+    "ip4/1.2.3.4",
+    "/nope/1",
+    "/ip4",
+    "/ip4/10.0.0.0/ipcidr",
+    "/ip4/10.0.0.0/ipcidr/33",
+    "/ip4/*/tcp/9-1",
+    "/ip4/*/tcp/a-b",
+    "/ip4/*/tcp/0-0",
+  }
+  for _, s := range bad {
+    if _, err := parsePattern(s); err == nil {
+      t.Errorf("%q parsed", s)
+    }
+  }
+}
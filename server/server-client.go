@@ -1,26 +1,313 @@
 package xtpserver
 
 import (
+  "crypto/rand"
   "sync"
   "errors"
+  "fmt"
+  "log/slog"
+  "time"
 
   xnet "github.com/libp2p/go-xtp-ctl/net"
+  pb "github.com/libp2p/go-xtp-ctl/pb"
+  xrpc "github.com/libp2p/go-xtp-ctl/rpc"
 )
 
 type ServerClient struct {
   sync.RWMutex
 
   Server *Server
-  Conn   xnet.Conn
+  Conn   xnet.Conn // the session. resuming it replaces it: see conn
+
+  log *slog.Logger
 
   transports map[int64]*transport
+  hello      *pb.Hello // the client's hello, once received
+
+  token   []byte        // to resume the session with, if it can be. see sessionToken
+  linger  *time.Timer   // closes the client, while its session is ended
+  resumed *ServerClient // whose session this one resumed, if it did
+  closing bool          // once Close started
+
+  wlk      sync.Mutex
+  watchers map[*watcher]struct{} // WatchReqs in progress
+
+  alk  sync.Mutex
+  auth auth // see Server.Policy
+
+  usage usage // against its Limits
+
+  closeOnce sync.Once
+  done      chan struct{} // closed by Close
 
   idCounter // embedded
 }
 
-// Close shuts down the ServerClient, closing everything.
+func newServerClient(s *Server, c xnet.Conn) *ServerClient {
+  sc := &ServerClient{
+    Server:     s,
+    Conn:       c,
+    log:        s.logger().With("client", fmt.Sprint(c.RemoteMultiaddr())),
+    transports: make(map[int64]*transport),
+    done:       make(chan struct{}),
+  }
+
+  // every client gets its own descriptors for the server's transports.
+  for _, t := range s.Xports {
+    sc.addTransport(newTransport(sc.NextId(), sc, t))
+  }
+  sc.initAuth()
+  if sc.gracePeriod() > 0 && canTellClosed(c) {
+    sc.token = newSessionToken()
+  }
+  return sc
+}
+
+// newSessionToken returns a random session token, or nil if there is no
+// randomness to be had.
+func newSessionToken() []byte {
+  tok := make([]byte, 16)
+  if _, err := rand.Read(tok); err != nil {
+    return nil
+  }
+  return tok
+}
+
+// Serve accepts xtp-ctl streams from the client, and handles the rpcs
+// sent on each of them. It blocks until the client's Conn fails.
+func (sc *ServerClient) Serve() error {
+  c := sc.conn()
+  for {
+    s, err := c.Accept()
+    if err != nil {
+      return err
+    }
+    go sc.current().handleStream(c, s)
+  }
+}
+
+// handleStream serves rpcs on one xtp-ctl stream of conn c, one after
+// another, until the stream is closed or broken. Then the descriptors
+// opened through the stream are closed too.
+func (sc *ServerClient) handleStream(c xnet.Conn, raw xnet.Stream) {
+  s := newCtlStream(sc, raw)
+  defer s.Close()
+  defer func() {
+    // streams end with the session. in the grace period, what they
+    // opened outlives them, until Close.
+    if !s.sc.lingers(c) {
+      s.closeOwned(s.sc)
+    }
+  }()
+
+  for {
+    // s.sc changes if the stream's hello resumes a session.
+    if err := rpcHandler(s.sc, s); err != nil {
+      return
+    }
+  }
+}
+
+// Close shuts down the ServerClient: it closes the session, and all the
+// client's descriptors (which unblocks its pending accepts), and removes
+// it from its Server. Calling it again does nothing.
 func (sc *ServerClient) Close() error {
-  panic("todo")
+  return sc.shutdown(true)
+}
+
+// shutdown is Close, leaving the session open unless closeConn: a client
+// that resumed another's session hands its conn over.
+func (sc *ServerClient) shutdown(closeConn bool) error {
+  var err error
+  sc.closeOnce.Do(func() {
+    sc.Lock()
+    sc.closing = true
+    c := sc.Conn
+    if sc.linger != nil {
+      sc.linger.Stop()
+      sc.linger = nil
+    }
+    ts := sc.transports
+    sc.transports = make(map[int64]*transport)
+    sc.Unlock()
+
+    if closeConn {
+      err = c.Close()
+    }
+    for _, t := range ts {
+      t.Close()
+      sc.closed(t)
+    }
+
+    if sc.Server != nil {
+      sc.Server.rmClient(sc)
+    }
+    close(sc.done)
+  })
+  return err
+}
+
+// Done returns a channel that is closed when the client is closed.
+func (sc *ServerClient) Done() <-chan struct{} {
+  return sc.done
+}
+
+// sessionToken returns the token to resume the client's session with, or
+// nil if it cannot be.
+func (sc *ServerClient) sessionToken() []byte {
+  sc.RLock()
+  defer sc.RUnlock()
+  return sc.token
+}
+
+// conn returns the client's session: Conn, which resume replaces.
+func (sc *ServerClient) conn() xnet.Conn {
+  sc.RLock()
+  defer sc.RUnlock()
+  return sc.Conn
+}
+
+// current returns the client that serves sc's session: sc, unless its
+// hello resumed another's session.
+func (sc *ServerClient) current() *ServerClient {
+  sc.RLock()
+  defer sc.RUnlock()
+  if sc.resumed != nil {
+    return sc.resumed
+  }
+  return sc
+}
+
+// disconnected closes the client after its session c ended. If the
+// session can be resumed, that waits until the Server's GracePeriod is
+// over, and is called off if the client resumes it by then.
+func (sc *ServerClient) disconnected(c xnet.Conn) {
+  sc.Lock()
+  if sc.Conn != c {
+    sc.Unlock()
+    return // resumed on another conn already.
+  }
+  if sc.token == nil || sc.closing {
+    sc.Unlock()
+    sc.Close()
+    return
+  }
+  grace := sc.gracePeriod()
+  var t *time.Timer
+  t = time.AfterFunc(grace, func() {
+    sc.Lock()
+    expired := sc.linger == t // not resumed (nor closed) meanwhile
+    sc.Unlock()
+    if expired {
+      sc.Close()
+    }
+  })
+  sc.linger = t
+  sc.Unlock()
+
+  sc.log.Info("keeping descriptors of disconnected client", "grace", grace)
+}
+
+// resumeSession resumes the ended session with token tok on sc's conn,
+// for a client that said hello h: the client that had the session takes
+// the conn over, and sc is closed, but for the conn. It returns that
+// client. With a Policy, the conn must be of the same identity as the
+// session (see Policy.TLSIdentity): a token alone does not carry one.
+func (sc *ServerClient) resumeSession(tok []byte, h *pb.Hello) (*ServerClient, error) {
+  var old *ServerClient
+  if sc.Server != nil {
+    old = sc.Server.sessionClient(tok)
+  }
+  if old == nil || old == sc {
+    return nil, fmt.Errorf("session: %w", xrpc.ErrNotFound)
+  }
+  if sc.policy() != nil && sc.Identity() != old.Identity() {
+    return nil, denied("resuming the session of another identity")
+  }
+  c := sc.conn()
+  if !old.resume(c, h) {
+    return nil, fmt.Errorf("session: %w", xrpc.ErrNotFound)
+  }
+
+  sc.Lock()
+  sc.resumed = old
+  sc.Unlock()
+  sc.shutdown(false)
+
+  old.log.Info("client resumed session", "conn", fmt.Sprint(c.RemoteMultiaddr()))
+  return old, nil
+}
+
+// resume moves the client's session to conn c, where the client said
+// hello h again, and keeps its descriptors. The session gets a new token:
+// each one resumes it once. The previous conn is closed, if it still was
+// up. It fails if the client is closed.
+func (sc *ServerClient) resume(c xnet.Conn, h *pb.Hello) bool {
+  sc.Lock()
+  if sc.closing {
+    sc.Unlock()
+    return false
+  }
+  prev := sc.Conn
+  sc.Conn, sc.hello = c, h
+  sc.token = newSessionToken()
+  if sc.linger != nil {
+    sc.linger.Stop()
+    sc.linger = nil
+  }
+  sc.Unlock()
+
+  if prev != c {
+    prev.Close()
+  }
+  return true
+}
+
+func (sc *ServerClient) gracePeriod() time.Duration {
+  if sc.Server == nil {
+    return 0
+  }
+  return sc.Server.GracePeriod
+}
+
+// lingers returns whether the descriptors opened on conn c outlive its
+// streams for now: the session can be resumed, and c ended, or was
+// replaced by a resumed session already.
+func (sc *ServerClient) lingers(c xnet.Conn) bool {
+  if sc.sessionToken() == nil {
+    return false
+  }
+  if sc.conn() != c {
+    return true
+  }
+  cc, ok := c.(interface{ IsClosed() bool })
+  return ok && cc.IsClosed()
+}
+
+// canTellClosed returns whether c tells when it ended. Only such sessions
+// can be resumed: their streams must know whether they end with it.
+func canTellClosed(c xnet.Conn) bool {
+  _, ok := c.(interface{ IsClosed() bool })
+  return ok
+}
+
+// Hello returns the Hello the client sent, or nil if the client has not
+// said hello yet.
+func (sc *ServerClient) Hello() *pb.Hello {
+  sc.RLock()
+  defer sc.RUnlock()
+  return sc.hello
+}
+
+// setHello records the client's hello. It fails if there already is one.
+func (sc *ServerClient) setHello(h *pb.Hello) bool {
+  sc.Lock()
+  defer sc.Unlock()
+  if sc.hello != nil {
+    return false
+  }
+  sc.hello = h
+  return true
 }
 
 func (sc *ServerClient) transport(id int64) *transport {
@@ -38,8 +325,12 @@ func (sc *ServerClient) addTransport(t *transport) {
 
 func (sc *ServerClient) rmTransport(t *transport) {
   sc.Lock()
+  _, found := sc.transports[t.id]
   delete(sc.transports, t.id)
   sc.Unlock()
+  if found {
+    sc.closed(t)
+  }
 }
 
 func (sc *ServerClient) Find(id int64) interface{} {
@@ -63,7 +354,7 @@ func (sc *ServerClient) Find(id int64) interface{} {
 func (sc *ServerClient) CloseId(id int64) error {
   v := sc.Find(id)
   if v == nil {
-    return nil // already closed?
+    return fmt.Errorf("id %d: %w", id, xrpc.ErrNotFound)
   }
 
   switch v := v.(type) {
@@ -1,29 +1,156 @@
 package xtpserver
 
 import (
+  "crypto/subtle"
+  "errors"
+  "log/slog"
+  "sync"
+  "time"
 
   xnet "github.com/libp2p/go-xtp-ctl/net"
   ma "github.com/multiformats/go-multiaddr"
 )
 
 type Server struct {
+  sync.Mutex
+
   Listener  xnet.Listener
   Xports    []xnet.Transport // to initialize with
-  Clients   []ServerClient
+  Clients   []*ServerClient
+  Metrics   *Metrics // counters to export, if set. see MetricsHandler.
+  Logger    *slog.Logger // slog.Default() if nil
+  Policy    *Policy      // access control, if set. it must be compiled.
+
+  Limits       *Limits // for all clients together, if set
+  ClientLimits *Limits // for each client, unless its Grant has some
+
+  usage usage // of all clients
+
+  // GracePeriod is how long the descriptors of a client whose session
+  // ended (listeners, conns, ...) are kept, for the client to resume the
+  // session (see xtpclient.ResumeClient), before they are closed and the
+  // client removed from Clients. 0 closes them right away.
+  GracePeriod time.Duration
+
+  // Connected, if set, is called when a client connects.
+  Connected func(sc *ServerClient)
+  // Disconnected, if set, is called when a client's session ends. It is
+  // the client that had it, if the session was a resumed one.
+  Disconnected func(sc *ServerClient, err error)
 }
 
 func NewServer(addr ma.Multiaddr, xports []xnet.Transport) (*Server, error) {
-  l, err := xnet.Listen(addr)
+  return NewSecureServer(addr, xports, nil)
+}
+
+// NewSecureServer is NewServer, with client connections secured with sec
+// (see xnet.Security). Clients that fail the handshake are dropped.
+func NewSecureServer(addr ma.Multiaddr, xports []xnet.Transport, sec *xnet.Security) (*Server, error) {
+  l, err := xnet.ListenSecure(addr, sec)
   if err != nil {
     return nil, err
   }
 
-  return &Server{Listener: l, Xports: xports}, nil
+  return &Server{Listener: l, Xports: xports, Metrics: NewMetrics()}, nil
+}
+
+// Serve accepts xtp-ctl clients on s.Listener, and serves each of them
+// in its own goroutine. It blocks until the Listener is closed, or fails
+// for good: temporary errors (like running out of file descriptors) are
+// retried, with backoff.
+func (s *Server) Serve() error {
+  var delay time.Duration // backoff of temporary accept errors
+  for {
+    c, err := s.Listener.Accept()
+    if err != nil {
+      if !isTemporary(err) {
+        return err
+      }
+      delay = acceptBackoff(delay)
+      s.logger().Warn("accept failed, retrying", "err", err, "delay", delay)
+      time.Sleep(delay)
+      continue
+    }
+    delay = 0
+
+    sc := newServerClient(s, c)
+    s.addClient(sc)
+    sc.log.Info("client connected")
+    if s.Connected != nil {
+      s.Connected(sc)
+    }
+
+    go func() {
+      err := sc.Serve()
+      sc := sc.current()
+      sc.log.Info("client disconnected", "err", err)
+      if s.Disconnected != nil {
+        s.Disconnected(sc, err)
+      }
+      sc.disconnected(c)
+    }()
+  }
+}
+
+// isTemporary returns whether err says to try again.
+func isTemporary(err error) bool {
+  var te interface{ Temporary() bool }
+  return errors.As(err, &te) && te.Temporary()
+}
+
+// acceptBackoff returns the delay after delay, from 5ms up to 1s.
+func acceptBackoff(delay time.Duration) time.Duration {
+  if delay == 0 {
+    return 5 * time.Millisecond
+  }
+  if delay *= 2; delay > time.Second {
+    delay = time.Second
+  }
+  return delay
+}
+
+func (s *Server) addClient(sc *ServerClient) {
+  s.Lock()
+  s.Clients = append(s.Clients, sc)
+  s.Unlock()
+}
+
+func (s *Server) rmClient(sc *ServerClient) {
+  s.Lock()
+  defer s.Unlock()
+  // a new slice: others may be iterating the old one.
+  var clients []*ServerClient
+  for _, c := range s.Clients {
+    if c != sc {
+      clients = append(clients, c)
+    }
+  }
+  s.Clients = clients
+}
+
+// sessionClient returns the client whose session has token tok, or nil.
+func (s *Server) sessionClient(tok []byte) *ServerClient {
+  s.Lock()
+  clients := s.Clients
+  s.Unlock()
+
+  for _, sc := range clients {
+    if t := sc.sessionToken(); t != nil && subtle.ConstantTimeCompare(t, tok) == 1 {
+      return sc
+    }
+  }
+  return nil
 }
 
 func (s *Server) Close() error {
   s.Listener.Close()
-  for _, c := range s.Clients {
+
+  s.Lock()
+  clients := s.Clients
+  s.Clients = nil
+  s.Unlock()
+
+  for _, c := range clients {
     c.Close()
   }
   for _, t := range s.Xports {
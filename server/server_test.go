@@ -0,0 +1,42 @@
+package xtpserver
+
+import (
+  "errors"
+  "net"
+  "syscall"
+  "testing"
+
+  xnet "github.com/libp2p/go-xtp-ctl/net"
+  ma "github.com/multiformats/go-multiaddr"
+)
+
+// flakyListener fails its first Accepts with errs, then for good.
+type flakyListener struct {
+  errs []error
+}
+
+func (l *flakyListener) Accept() (xnet.Conn, error) {
+  if len(l.errs) == 0 {
+    return nil, net.ErrClosed
+  }
+  err := l.errs[0]
+  l.errs = l.errs[1:]
+  return nil, err
+}
+
+func (l *flakyListener) Multiaddr() ma.Multiaddr { return ma.StringCast("/ip4/127.0.0.1/tcp/1") }
+func (l *flakyListener) Close() error            { return nil }
+
+func TestServeRetriesTemporaryErrors(t *testing.T) {
+  l := &flakyListener{errs: []error{
+    &net.OpError{Op: "accept", Net: "tcp", Err: syscall.EMFILE},
+    &net.OpError{Op: "accept", Net: "tcp", Err: syscall.ECONNABORTED},
+  }}
+  s := &Server{Listener: l}
+  if err := s.Serve(); !errors.Is(err, net.ErrClosed) {
+    t.Fatal("Serve returned", err)
+  }
+  if len(l.errs) != 0 {
+    t.Fatal("Serve returned before retrying:", l.errs)
+  }
+}
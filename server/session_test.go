@@ -0,0 +1,387 @@
+package xtpserver_test
+
+import (
+  "bytes"
+  "crypto/ecdsa"
+  "crypto/elliptic"
+  "crypto/rand"
+  "crypto/tls"
+  "crypto/x509"
+  "crypto/x509/pkix"
+  "errors"
+  "fmt"
+  "math/big"
+  "net"
+  "testing"
+  "time"
+
+  xclient "github.com/libp2p/go-xtp-ctl/client"
+  ximpls "github.com/libp2p/go-xtp-ctl/impls"
+  xnet "github.com/libp2p/go-xtp-ctl/net"
+  pb "github.com/libp2p/go-xtp-ctl/pb"
+  xrpc "github.com/libp2p/go-xtp-ctl/rpc"
+  xserver "github.com/libp2p/go-xtp-ctl/server"
+  "github.com/libp2p/go-xtp-ctl/xtptest"
+  ma "github.com/multiformats/go-multiaddr"
+  manet "github.com/multiformats/go-multiaddr-net"
+)
+
+// session is a server with a grace period, and how to connect to it.
+type session struct {
+  s     *xserver.Server
+  dial  func() (xnet.Conn, error)
+  ended chan struct{} // a client's session ended
+}
+
+// startSession starts a server with grace period grace, offering /tcp and
+// /memory, and listening for clients on ctl (/tcp or /memory).
+func startSession(t *testing.T, ctl string, grace time.Duration) *session {
+  xports := []xnet.Transport{&ximpls.TCPTransport{}, &ximpls.MemoryTransport{}}
+  ss := &session{ended: make(chan struct{}, 10)}
+
+  switch ctl {
+  case "/tcp":
+    s, err := xserver.NewServer(xtptest.TCPAddr, xports)
+    if err != nil {
+      t.Fatal(err)
+    }
+    saddr := s.Listener.Multiaddr()
+    ss.s, ss.dial = s, func() (xnet.Conn, error) { return xnet.Dial(saddr) }
+  case "/memory":
+    tpt := &ximpls.MemoryTransport{}
+    saddr := ma.StringCast(fmt.Sprintf("/memory/session-%p", t))
+    l, err := tpt.Listen(saddr)
+    if err != nil {
+      t.Fatal(err)
+    }
+    ss.s = &xserver.Server{Listener: l, Xports: xports}
+    ss.dial = func() (xnet.Conn, error) { return tpt.Dial(saddr) }
+  }
+  ss.s.GracePeriod = grace
+  ss.s.Disconnected = func(*xserver.ServerClient, error) { ss.ended <- struct{}{} }
+
+  go ss.s.Serve()
+  t.Cleanup(func() { ss.s.Close() })
+  return ss
+}
+
+// connect connects a client, resuming session tok if not nil.
+func (ss *session) connect(t *testing.T, tok []byte) (*xclient.Client, error) {
+  c, err := ss.dial()
+  if err != nil {
+    t.Fatal("connecting:", err)
+  }
+  var client *xclient.Client
+  if tok == nil {
+    client, err = xclient.NewClientConn(c)
+  } else {
+    client, err = xclient.ResumeClientConn(c, tok)
+  }
+  if err == nil {
+    t.Cleanup(func() { client.Close() })
+  }
+  return client, err
+}
+
+func (ss *session) mustConnect(t *testing.T, tok []byte) *xclient.Client {
+  t.Helper()
+  c, err := ss.connect(t, tok)
+  if err != nil {
+    t.Fatal("connecting:", err)
+  }
+  return c
+}
+
+// waitEnded waits until the server sees a client's session end.
+func (ss *session) waitEnded(t *testing.T) {
+  t.Helper()
+  select {
+  case <-ss.ended:
+  case <-time.After(5 * time.Second):
+    t.Fatal("the server did not see the session end")
+  }
+}
+
+func (ss *session) numClients() int {
+  ss.s.Lock()
+  defer ss.s.Unlock()
+  return len(ss.s.Clients)
+}
+
+func numListeners(t *testing.T, c *xclient.Client) int {
+  t.Helper()
+  s, err := c.Stream()
+  if err != nil {
+    t.Fatal(err)
+  }
+  defer s.Close()
+  items, err := xrpc.ListReq(s, []pb.TType{pb.TType_TTypeListener})
+  if err != nil {
+    t.Fatal(err)
+  }
+  return len(items)
+}
+
+func serverUsage(t *testing.T, c *xclient.Client) *pb.Usage {
+  t.Helper()
+  res, err := c.Usage()
+  if err != nil {
+    t.Fatal(err)
+  }
+  return res.GetServerUsage()
+}
+
+// eventually fails the test unless cond holds within a few seconds.
+func eventually(t *testing.T, what string, cond func() bool) {
+  t.Helper()
+  for deadline := time.Now().Add(5 * time.Second); !cond(); time.Sleep(10 * time.Millisecond) {
+    if time.Now().After(deadline) {
+      t.Fatal("timed out waiting for", what)
+    }
+  }
+}
+
+// TestKilledClient checks that without a grace period, all a killed
+// client had is closed right away: its listener's port is free, and its
+// pending accepts are unblocked.
+func TestKilledClient(t *testing.T) {
+  for _, ctl := range []string{"/tcp", "/memory"} {
+    t.Run(ctl[1:], func(t *testing.T) {
+      ss := startSession(t, ctl, 0)
+      admin := ss.mustConnect(t, nil)
+      c := ss.mustConnect(t, nil)
+      if c.Session != nil {
+        t.Fatal("session token without a grace period")
+      }
+
+      l, err := c.Transport("/tcp").Listen(xtptest.TCPAddr)
+      if err != nil {
+        t.Fatal(err)
+      }
+      laddr := l.Multiaddr()
+      go l.Accept()
+      eventually(t, "the pending accept", func() bool {
+        return serverUsage(t, admin).GetPendingAccepts() == 1
+      })
+
+      c.Conn.Close()
+      ss.waitEnded(t)
+      eventually(t, "the client to be removed", func() bool { return ss.numClients() == 1 })
+      u := serverUsage(t, admin)
+      if u.GetPendingAccepts() != 0 || u.GetListeners() != 0 {
+        t.Fatalf("the killed client's descriptors are still open: %v", u)
+      }
+
+      nl, err := manet.Listen(laddr)
+      if err != nil {
+        t.Fatal("the killed client's port is still in use:", err)
+      }
+      nl.Close()
+    })
+  }
+}
+
+// TestResumeSession checks that a client resumes its session within the
+// grace period, with the descriptors it had.
+func TestResumeSession(t *testing.T) {
+  for _, ctl := range []string{"/tcp", "/memory"} {
+    t.Run(ctl[1:], func(t *testing.T) {
+      ss := startSession(t, ctl, time.Minute)
+      c := ss.mustConnect(t, nil)
+      if c.Session == nil {
+        t.Fatal("no session token with a grace period")
+      }
+      if _, err := c.Transport("/memory").Listen(ma.StringCast(fmt.Sprintf("/memory/resume-%p", t))); err != nil {
+        t.Fatal(err)
+      }
+
+      c.Conn.Close()
+      ss.waitEnded(t)
+
+      c2 := ss.mustConnect(t, c.Session)
+      if c2.Session == nil || bytes.Equal(c2.Session, c.Session) {
+        t.Fatal("resumed session kept its token")
+      }
+      if n := numListeners(t, c2); n != 1 {
+        t.Fatalf("resumed session has %d listeners, expected 1", n)
+      }
+      if n := ss.numClients(); n != 1 {
+        t.Fatalf("server has %d clients, expected 1", n)
+      }
+
+      // tokens resume the session once.
+      if _, err := ss.connect(t, c.Session); !errors.Is(err, xrpc.ErrNotFound) {
+        t.Fatal("resuming with a used token:", err)
+      }
+      ss.waitEnded(t)
+
+      // the resumed session lingers too.
+      c2.Conn.Close()
+      ss.waitEnded(t)
+      c3 := ss.mustConnect(t, c2.Session)
+      if n := numListeners(t, c3); n != 1 {
+        t.Fatalf("resumed session has %d listeners, expected 1", n)
+      }
+    })
+  }
+}
+
+// TestResumeTakeover checks that resuming a session that has not ended
+// yet takes it over: the old conn is closed.
+func TestResumeTakeover(t *testing.T) {
+  ss := startSession(t, "/tcp", time.Minute)
+  c := ss.mustConnect(t, nil)
+  if _, err := c.Transport("/memory").Listen(ma.StringCast("/memory/takeover")); err != nil {
+    t.Fatal(err)
+  }
+
+  c2 := ss.mustConnect(t, c.Session)
+  ss.waitEnded(t)
+  if _, err := c.Usage(); err == nil {
+    t.Fatal("the taken over session still works")
+  }
+  if n := numListeners(t, c2); n != 1 {
+    t.Fatalf("resumed session has %d listeners, expected 1", n)
+  }
+}
+
+// TestGracePeriodExpires checks that a client that does not resume its
+// session in the grace period is closed, and cannot resume it anymore.
+func TestGracePeriodExpires(t *testing.T) {
+  ss := startSession(t, "/memory", 50*time.Millisecond)
+  laddr := ma.StringCast("/memory/expire")
+  c := ss.mustConnect(t, nil)
+  if _, err := c.Transport("/memory").Listen(laddr); err != nil {
+    t.Fatal(err)
+  }
+
+  c.Conn.Close()
+  ss.waitEnded(t)
+  eventually(t, "the client to be removed", func() bool { return ss.numClients() == 0 })
+
+  if _, err := ss.connect(t, c.Session); !errors.Is(err, xrpc.ErrNotFound) {
+    t.Fatal("resuming an expired session:", err)
+  }
+  c2 := ss.mustConnect(t, nil)
+  if _, err := c2.Transport("/memory").Listen(laddr); err != nil {
+    t.Fatal("the expired client's address is still in use:", err)
+  }
+}
+
+func TestResumeUnknownSession(t *testing.T) {
+  ss := startSession(t, "/tcp", time.Minute)
+  if _, err := ss.connect(t, []byte("nope")); !errors.Is(err, xrpc.ErrNotFound) {
+    t.Fatal("resuming an unknown session:", err)
+  }
+}
+
+// TestResumeIdentity checks that with a policy, only a conn of the
+// session's identity resumes it: a token alone does not carry one.
+func TestResumeIdentity(t *testing.T) {
+  ca := newTestCA(t)
+  all := &xserver.Grant{Transports: []string{"*"}, Listen: []string{"*"}, Dial: []string{"*"}, RPCs: []string{"*"}}
+  p := &xserver.Policy{TLSIdentity: true, Clients: map[string]*xserver.Grant{"alice": all, "bob": all}}
+  if err := p.Compile(); err != nil {
+    t.Fatal(err)
+  }
+  s, err := xserver.NewSecureServer(xtptest.TCPAddr, []xnet.Transport{&ximpls.MemoryTransport{}}, &xnet.Security{TLS: &tls.Config{
+    Certificates: []tls.Certificate{ca.issue(t, "server")},
+    ClientCAs:    ca.pool,
+    ClientAuth:   tls.VerifyClientCertIfGiven,
+  }})
+  if err != nil {
+    t.Fatal(err)
+  }
+  s.Policy, s.GracePeriod = p, time.Minute
+  go s.Serve()
+  t.Cleanup(func() { s.Close() })
+  saddr := s.Listener.Multiaddr()
+
+  as := func(cn string) *xnet.Security {
+    cfg := &tls.Config{RootCAs: ca.pool}
+    if cn != "" {
+      cfg.Certificates = []tls.Certificate{ca.issue(t, cn)}
+    }
+    return &xnet.Security{TLS: cfg}
+  }
+
+  c, err := xclient.NewSecureClient(saddr, as("alice"))
+  if err != nil {
+    t.Fatal(err)
+  }
+  defer c.Close()
+  if _, err := c.Transport("/memory").Listen(ma.StringCast("/memory/identity")); err != nil {
+    t.Fatal(err)
+  }
+  c.Conn.Close()
+
+  for _, cn := range []string{"bob", ""} {
+    if _, err := xclient.ResumeClient(saddr, as(cn), c.Session); !errors.Is(err, xrpc.ErrPermissionDenied) {
+      t.Fatalf("resuming alice's session as %q: %v", cn, err)
+    }
+  }
+  c2, err := xclient.ResumeClient(saddr, as("alice"), c.Session)
+  if err != nil {
+    t.Fatal("resuming alice's session as alice:", err)
+  }
+  defer c2.Close()
+  if n := numListeners(t, c2); n != 1 {
+    t.Fatalf("resumed session has %d listeners, expected 1", n)
+  }
+}
+
+// testCA issues certificates for tests.
+type testCA struct {
+  cert *x509.Certificate
+  key  *ecdsa.PrivateKey
+  pool *x509.CertPool
+}
+
+func newTestCA(t *testing.T) *testCA {
+  key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+  if err != nil {
+    t.Fatal(err)
+  }
+  tmpl := &x509.Certificate{
+    SerialNumber:          big.NewInt(1),
+    Subject:               pkix.Name{CommonName: "test ca"},
+    NotBefore:             time.Now().Add(-time.Hour),
+    NotAfter:              time.Now().Add(time.Hour),
+    IsCA:                  true,
+    BasicConstraintsValid: true,
+    KeyUsage:              x509.KeyUsageCertSign,
+  }
+  der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+  if err != nil {
+    t.Fatal(err)
+  }
+  cert, err := x509.ParseCertificate(der)
+  if err != nil {
+    t.Fatal(err)
+  }
+  pool := x509.NewCertPool()
+  pool.AddCert(cert)
+  return &testCA{cert, key, pool}
+}
+
+// issue returns a certificate for cn, valid for 127.0.0.1 too.
+func (ca *testCA) issue(t *testing.T, cn string) tls.Certificate {
+  key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+  if err != nil {
+    t.Fatal(err)
+  }
+  tmpl := &x509.Certificate{
+    SerialNumber: big.NewInt(time.Now().UnixNano()),
+    Subject:      pkix.Name{CommonName: cn},
+    NotBefore:    time.Now().Add(-time.Hour),
+    NotAfter:     time.Now().Add(time.Hour),
+    IPAddresses:  []net.IP{net.IPv4(127, 0, 0, 1)},
+    KeyUsage:     x509.KeyUsageDigitalSignature,
+    ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth, x509.ExtKeyUsageClientAuth},
+  }
+  der, err := x509.CreateCertificate(rand.Reader, tmpl, ca.cert, &key.PublicKey, ca.key)
+  if err != nil {
+    t.Fatal(err)
+  }
+  return tls.Certificate{Certificate: [][]byte{der}, PrivateKey: key}
+}
@@ -0,0 +1,185 @@
+package xtpserver
+
+import (
+  "context"
+  "errors"
+  "fmt"
+  "sync/atomic"
+  "time"
+
+  pb "github.com/libp2p/go-xtp-ctl/pb"
+  xrpc "github.com/libp2p/go-xtp-ctl/rpc"
+)
+
+// stats are the traffic counters of a descriptor. Counting something
+// also counts it in the stats of the descriptor's parents: a stream's
+// bytes add up in its conn, the conn's in its listener or dialer, and
+// those in their transport.
+type stats struct {
+  // atomic. first, for alignment.
+  bytesIn  int64
+  bytesOut int64
+  conns    int64
+  streams  int64
+  errors   int64
+  last     int64 // unix nanoseconds of the last traffic
+
+  opened time.Time
+  up     *stats // the parent's stats
+}
+
+func newStats(up *stats) *stats {
+  return &stats{opened: time.Now(), up: up}
+}
+
+func (s *stats) addIn(n int) {
+  now := time.Now().UnixNano()
+  for ; s != nil; s = s.up {
+    atomic.AddInt64(&s.bytesIn, int64(n))
+    atomic.StoreInt64(&s.last, now)
+  }
+}
+
+func (s *stats) addOut(n int) {
+  now := time.Now().UnixNano()
+  for ; s != nil; s = s.up {
+    atomic.AddInt64(&s.bytesOut, int64(n))
+    atomic.StoreInt64(&s.last, now)
+  }
+}
+
+func (s *stats) addConn() {
+  for ; s != nil; s = s.up {
+    atomic.AddInt64(&s.conns, 1)
+  }
+}
+
+func (s *stats) addStream() {
+  for ; s != nil; s = s.up {
+    atomic.AddInt64(&s.streams, 1)
+  }
+}
+
+func (s *stats) addError() {
+  for ; s != nil; s = s.up {
+    atomic.AddInt64(&s.errors, 1)
+  }
+}
+
+func (s *stats) PB(id int64) *pb.Stats {
+  in := atomic.LoadInt64(&s.bytesIn)
+  out := atomic.LoadInt64(&s.bytesOut)
+  conns := atomic.LoadInt64(&s.conns)
+  streams := atomic.LoadInt64(&s.streams)
+  errs := atomic.LoadInt64(&s.errors)
+  last := atomic.LoadInt64(&s.last)
+  opened := s.opened.UnixNano()
+
+  return &pb.Stats{
+    Id:           &id,
+    BytesIn:      &in,
+    BytesOut:     &out,
+    Conns:        &conns,
+    Streams:      &streams,
+    Errors:       &errs,
+    OpenedAt:     &opened,
+    LastActivity: &last,
+  }
+}
+
+// statsOf returns the stats of descriptor v.
+func statsOf(v interface{}) *stats {
+  switch v := v.(type) {
+  case *transport:
+    return v.stats
+  case *listener:
+    return v.stats
+  case *dialer:
+    return v.stats
+  case *conn:
+    return v.stats
+  case *stream:
+    return v.stats
+  default:
+    return nil
+  }
+}
+
+// opFailed records that an operation on descriptor v failed with err.
+func (sc *ServerClient) opFailed(v interface{}, err error) {
+  if errors.Is(err, context.Canceled) {
+    return // the client aborted it. not a failure.
+  }
+  statsOf(v).addError()
+  sc.emit(pb.WatchRes_Error, v, err)
+}
+
+// Stats returns the stats of descriptors ids, or of all the descriptors
+// the client may see.
+func (sc *ServerClient) Stats(ids []int64) ([]*pb.Stats, error) {
+  if len(ids) > 0 {
+    var ss []*pb.Stats
+    for _, id := range ids {
+      v := sc.Find(id)
+      st := statsOf(v)
+      if _, t := watchKey(v); t != nil && !sc.allowsTransport(t) {
+        st = nil // hidden by the client's policy.
+      }
+      if st == nil {
+        return nil, fmt.Errorf("id %d: %w", id, xrpc.ErrNotFound)
+      }
+      ss = append(ss, st.PB(id))
+    }
+    return ss, nil
+  }
+
+  sc.RLock()
+  defer sc.RUnlock()
+
+  var ss []*pb.Stats
+  for _, t := range sc.transports {
+    if !sc.allowsTransport(t) {
+      continue // hidden by the client's policy.
+    }
+    ss = append(ss, t.stats.PB(t.id))
+    ss = append(ss, t.Stats()...)
+  }
+  return ss, nil
+}
+
+// Stats returns the stats of the descriptors of t.
+func (t *transport) Stats() []*pb.Stats {
+  t.RLock()
+  defer t.RUnlock()
+
+  var ss []*pb.Stats
+  for _, l := range t.listeners {
+    ss = append(ss, l.stats.PB(l.id))
+  }
+  for _, d := range t.dialers {
+    ss = append(ss, d.stats.PB(d.id))
+  }
+  for _, c := range t.conns {
+    ss = append(ss, c.stats.PB(c.id))
+    c.RLock()
+    for _, s := range c.streams {
+      ss = append(ss, s.stats.PB(s.id))
+    }
+    c.RUnlock()
+  }
+  return ss
+}
+
+func handleStatsReq(sc *ServerClient, s *rpcCall, req *pb.StatsReq) error {
+  for _, id := range req.Ids {
+    if id < pb.MinId {
+      return xrpc.ErrInvalidMessage
+    }
+  }
+
+  ss, err := sc.Stats(req.Ids)
+  if err != nil {
+    return err
+  }
+  return xrpc.StatsRes(s, ss, nil)
+}
@@ -1,6 +1,9 @@
 package xtpserver
 
 import (
+  "io"
+  "sync"
+
   xnet "github.com/libp2p/go-xtp-ctl/net"
 )
 
@@ -8,12 +11,179 @@ type stream struct {
   id    int64
   rawS  xnet.Stream
   conn  *conn
+  stats *stats
+
+  lk    sync.Mutex
+  ctls  IoStream // the client's xtp-ctl stream this stream is spliced to, if any.
+
+  // shutdown state, see splice.
+  wclosed  bool  // rawS write side closed
+  rclosed  bool  // the client stopped reading
+  outDone  bool  // rawS reached EOF
+
+  once sync.Once
+  done chan struct{} // closed when the stream should be torn down
 }
 
 func newStream(id int64, c *conn, s xnet.Stream) *stream {
-  return &stream{id, s, c}
+  return &stream{
+    id:    id,
+    rawS:  s,
+    conn:  c,
+    stats: newStats(c.stats),
+    done:  make(chan struct{}),
+  }
 }
 
 func (s *stream) Close() error {
+  s.lk.Lock()
+  ctls := s.ctls
+  s.lk.Unlock()
+
+  if ctls != nil {
+    ctls.Close()
+  }
   return s.rawS.Close()
 }
+
+// finish tells splice to tear the stream down.
+func (s *stream) finish() {
+  s.once.Do(func() { close(s.done) })
+}
+
+// splice pumps data between the client's xtp-ctl stream ctls and the
+// underlying stream, in both directions, until the stream is done. Then
+// it is torn down: closed on both ends and removed from its conn.
+//
+// Half-closes travel in-band, as the end of ctls: the client's reaches
+// rawS after its data, and the remote's reaches the client the same
+// way. The client stops reading with a ShutdownReq (see shutdownRead).
+// The stream is done when either side fails, or when the client is done
+// writing and the remote is done writing too (or the client stopped
+// reading).
+func (s *stream) splice(ctls IoStream) {
+  s.lk.Lock()
+  s.ctls = ctls
+  s.lk.Unlock()
+
+  go func() {
+    if s.pumpIn(ctls) {
+      s.finish()
+    }
+  }()
+  go func() {
+    if s.pumpOut(ctls) {
+      s.finish()
+    }
+  }()
+
+  <-s.done
+  s.conn.rmStream(s)
+  s.Close()
+}
+
+// pumpIn copies the client's data from ctls to rawS, until ctls ends. It
+// returns whether the stream is done.
+func (s *stream) pumpIn(ctls IoStream) bool {
+  buf := make([]byte, 32<<10)
+  for {
+    n, err := ctls.Read(buf)
+    if n > 0 {
+      s.conn.xport.sc.throttle(n, s.done)
+      if _, err := s.rawS.Write(buf[:n]); err != nil {
+        s.stats.addError()
+        return true
+      }
+      s.stats.addOut(n)
+      s.conn.xport.sc.metrics().addOut(n)
+    }
+    if err == nil {
+      continue
+    }
+    if err != io.EOF {
+      return true
+    }
+
+    // the client half-closed, after all its data. pass it on to the
+    // remote, and wait for the other way to finish too, unless it
+    // already has (or a half-close is impossible).
+    s.lk.Lock()
+    s.wclosed = true
+    otherDone := s.outDone || s.rclosed
+    s.lk.Unlock()
+    return otherDone || xnet.CloseWrite(s.rawS) != nil
+  }
+}
+
+// pumpOut copies the remote's data from rawS to ctls. It returns whether
+// the stream is done.
+func (s *stream) pumpOut(ctls IoStream) bool {
+  buf := make([]byte, 32<<10)
+  for {
+    n, err := s.rawS.Read(buf)
+    if n > 0 {
+      s.stats.addIn(n)
+      s.conn.xport.sc.metrics().addIn(n)
+      s.conn.xport.sc.throttle(n, s.done)
+      s.lk.Lock()
+      rclosed := s.rclosed
+      s.lk.Unlock()
+
+      if !rclosed { // otherwise, drop it.
+        if _, err := ctls.Write(buf[:n]); err != nil {
+          return true
+        }
+      }
+    }
+    if err == nil {
+      continue
+    }
+    if err != io.EOF {
+      s.stats.addError()
+      return true
+    }
+
+    // the remote half-closed. pass it on to the client, and wait for it
+    // to finish writing too, unless it already has (or a half-close is
+    // impossible).
+    s.lk.Lock()
+    s.outDone = true
+    wclosed := s.wclosed
+    s.lk.Unlock()
+    if wclosed {
+      return true
+    }
+    _, half := s.rawS.(xnet.HalfCloseStream)
+    return !half || closeWrite(ctls) != nil
+  }
+}
+
+// closeWrite half-closes ctls, if it can: the client reads io.EOF.
+func closeWrite(ctls IoStream) error {
+  if hs, ok := ctls.(interface{ CloseWrite() error }); ok {
+    return hs.CloseWrite()
+  }
+  return xnet.ErrNoHalfClose
+}
+
+// shutdownRead stops sending rawS's data to the client. If the client is
+// done writing too, the stream is done.
+func (s *stream) shutdownRead() error {
+  s.lk.Lock()
+  s.rclosed = true
+  wclosed := s.wclosed
+  s.lk.Unlock()
+
+  xnet.CloseRead(s.rawS) // best effort. we drop the data anyway.
+  if wclosed {
+    s.finish()
+  }
+  return nil
+}
+
+// reset aborts the stream.
+func (s *stream) reset() error {
+  err := xnet.Reset(s.rawS)
+  s.finish()
+  return err
+}
@@ -1,19 +1,24 @@
 package xtpserver
 
 import (
+  "context"
   "sync"
 
   ma "github.com/multiformats/go-multiaddr"
   pb "github.com/libp2p/go-xtp-ctl/pb"
   xnet "github.com/libp2p/go-xtp-ctl/net"
+  xtptrace "github.com/libp2p/go-xtp-ctl/trace"
+
+  "go.opentelemetry.io/otel/attribute"
 )
 
 type transport struct {
   sync.RWMutex
 
-  id   int64
-  rawT xnet.Transport
-  sc   *ServerClient
+  id    int64
+  rawT  xnet.TransportContext
+  sc    *ServerClient
+  stats *stats
 
   listeners map[int64]*listener
   dialers   map[int64]*dialer
@@ -22,9 +27,10 @@ type transport struct {
 
 func newTransport(id int64, sc *ServerClient, t xnet.Transport) *transport {
   return &transport{
-    id:   id,
-    rawT: t,
-    sc:   sc,
+    id:    id,
+    rawT:  xnet.TransportWithContext(t),
+    sc:    sc,
+    stats: newStats(nil),
 
     listeners: make(map[int64]*listener),
     dialers:   make(map[int64]*dialer),
@@ -47,8 +53,12 @@ func (t *transport) addListener(l *listener) {
 
 func (t *transport) rmListener(l *listener) {
   t.Lock()
+  _, found := t.listeners[l.id]
   delete(t.listeners, l.id)
   t.Unlock()
+  if found {
+    t.sc.closed(l)
+  }
 }
 
 func (t *transport) dialer(id int64) *dialer {
@@ -66,8 +76,12 @@ func (t *transport) addDialer(d *dialer) {
 
 func (t *transport) rmDialer(d *dialer) {
   t.Lock()
+  _, found := t.dialers[d.id]
   delete(t.dialers, d.id)
   t.Unlock()
+  if found {
+    t.sc.closed(d)
+  }
 }
 
 func (t *transport) conn(id int64) *conn {
@@ -85,8 +99,12 @@ func (t *transport) addConn(c *conn) {
 
 func (t *transport) rmConn(c *conn) {
   t.Lock()
+  _, found := t.conns[c.id]
   delete(t.conns, c.id)
   t.Unlock()
+  if found {
+    t.sc.closed(c)
+  }
 }
 
 func (t *transport) Close() error {
@@ -96,15 +114,18 @@ func (t *transport) Close() error {
   for id, l := range t.listeners {
     l.Close()
     delete(t.listeners, id)
+    t.sc.closed(l)
   }
 
-  for id, _ := range t.dialers {
+  for id, d := range t.dialers {
     delete(t.dialers, id)
+    t.sc.closed(d)
   }
 
   for id, c := range t.conns {
     delete(t.conns, id)
     c.Close()
+    t.sc.closed(c)
   }
 
   return nil
@@ -139,86 +160,119 @@ func (t *transport) Find(id int64) interface{} {
   return nil
 }
 
-func (t *transport) List(types pb.ListReqTypes) []*pb.ListRes_Item {
+// List returns the descriptors of t of the given types, with their stats
+// if withStats.
+func (t *transport) List(types pb.ListReqTypes, withStats bool) []*pb.ListRes_Item {
   t.RLock()
-  defer t.Unlock()
+  defer t.RUnlock()
 
   var items []*pb.ListRes_Item
 
-  addItem := func(i *pb.ListRes_Item, err error) {
-    if err != nil {
+  addItem := func(i *pb.ListRes_Item, err error, st *stats) {
+    if err == nil {
+      if withStats {
+        i.Stats = st.PB(i.GetId())
+      }
       items = append(items, i)
     } else {
-      // internal error. TODO: log it
+      t.sc.log.Error("list: encoding descriptor", "err", err)
     }
   }
 
   if types.Listeners {
     for _, l := range t.listeners {
       i, err := pb.ListRes_Item_Listener(l.PB())
-      addItem(i, err)
+      addItem(i, err, l.stats)
     }
   }
 
   if types.Dialers {
     for _, d := range t.dialers {
       i, err := pb.ListRes_Item_Dialer(d.PB())
-      addItem(i, err)
+      addItem(i, err, d.stats)
     }
   }
 
   if types.Conns {
     for _, c := range t.conns {
       i, err := pb.ListRes_Item_Conn(c.PB())
-      addItem(i, err)
+      addItem(i, err, c.stats)
     }
   }
 
   if types.Streams {
     for _, c := range t.conns {
-      c.Lock()
+      c.RLock()
       for _, s := range c.streams {
         i, err := pb.ListRes_Item_Stream(s.PB())
-        addItem(i, err)
+        addItem(i, err, s.stats)
       }
-      c.Unlock()
+      c.RUnlock()
     }
   }
   return items
 }
 
-func (t *transport) Listen(laddr ma.Multiaddr) (*listener, error) {
+func (t *transport) Listen(ctx context.Context, laddr ma.Multiaddr) (_ *listener, err error) {
+  _, span := xtptrace.Start(ctx, "transport.Listen", attribute.Int64("id", t.id))
+  defer func() { xtptrace.End(span, err) }()
+
+  if err := t.sc.reserve(resListener); err != nil {
+    return nil, err
+  }
   l, err := t.rawT.Listen(laddr)
   if err != nil {
+    t.sc.release(resListener)
+    t.sc.opFailed(t, err)
     return nil, err
   }
   id := t.sc.NextId()
 
   l2 := newListener(id, t, l)
   t.addListener(l2)
+  t.sc.emit(pb.WatchRes_Opened, l2, nil)
   return l2, nil
 }
 
-func (t *transport) Dialer(laddr ma.Multiaddr) (*dialer, error) {
+func (t *transport) Dialer(ctx context.Context, laddr ma.Multiaddr) (_ *dialer, err error) {
+  _, span := xtptrace.Start(ctx, "transport.Dialer", attribute.Int64("id", t.id))
+  defer func() { xtptrace.End(span, err) }()
+
+  if err := t.sc.reserve(resDialer); err != nil {
+    return nil, err
+  }
   d, err := t.rawT.Dialer(laddr)
   if err != nil {
+    t.sc.release(resDialer)
+    t.sc.opFailed(t, err)
     return nil, err
   }
   id := t.sc.NextId()
 
   d2 := newDialer(id, t, d)
   t.addDialer(d2)
+  t.sc.emit(pb.WatchRes_Opened, d2, nil)
   return d2, nil
 }
 
-func (t *transport) Dial(raddr ma.Multiaddr) (*conn, error) {
-  c, err := t.rawT.Dial(raddr)
+func (t *transport) Dial(ctx context.Context, raddr ma.Multiaddr) (_ *conn, err error) {
+  ctx, span := xtptrace.Start(ctx, "transport.Dial", attribute.Int64("id", t.id))
+  defer func() { xtptrace.End(span, err) }()
+
+  if err := t.sc.reserve(resConn); err != nil {
+    return nil, err
+  }
+  c, err := t.rawT.DialContext(ctx, raddr)
   if err != nil {
+    t.sc.release(resConn)
+    t.sc.opFailed(t, err)
     return nil, err
   }
   id := t.sc.NextId()
 
-  c2 := newConn(id, t, c)
+  c2 := newConn(id, t, c, t.stats)
   t.addConn(c2)
+  t.stats.addConn()
+  t.sc.emit(pb.WatchRes_Dialed, c2, nil)
   return c2, nil
 }
@@ -0,0 +1,160 @@
+package xtpserver
+
+import (
+  "errors"
+
+  pb "github.com/libp2p/go-xtp-ctl/pb"
+  xrpc "github.com/libp2p/go-xtp-ctl/rpc"
+)
+
+// watchBacklog is how many events may wait for a slow watcher. Beyond
+// that, the watch fails rather than block the server.
+const watchBacklog = 256
+
+// errWatchOverflow ends a watch that fell behind, and lost events.
+var errWatchOverflow = errors.New("watch fell behind, events lost")
+
+// watcher is a WatchReq in progress, on one of the client's xtp-ctl
+// streams.
+type watcher struct {
+  req      *pb.WatchReq
+  events   chan *pb.WatchRes
+  overflow chan struct{} // closed when events was full
+}
+
+func (sc *ServerClient) watch(req *pb.WatchReq) *watcher {
+  w := &watcher{
+    req:      req,
+    events:   make(chan *pb.WatchRes, watchBacklog),
+    overflow: make(chan struct{}),
+  }
+
+  sc.wlk.Lock()
+  if sc.watchers == nil {
+    sc.watchers = make(map[*watcher]struct{})
+  }
+  sc.watchers[w] = struct{}{}
+  sc.wlk.Unlock()
+  return w
+}
+
+func (sc *ServerClient) unwatch(w *watcher) {
+  sc.wlk.Lock()
+  delete(sc.watchers, w)
+  sc.wlk.Unlock()
+}
+
+// emit sends event ev about descriptor v (a *transport, *listener,
+// *dialer, *conn or *stream) to the watchers interested in it. err is
+// the failure, for WatchRes_Error. It never blocks.
+func (sc *ServerClient) emit(ev pb.WatchRes_Event, v interface{}, err error) {
+  typ, t := watchKey(v)
+  if t == nil || !sc.allowsTransport(t) {
+    return // hidden by the client's policy.
+  }
+  sc.wlk.Lock()
+  var ws []*watcher
+  for w := range sc.watchers {
+    if w.req.Watches(typ, t.id) {
+      ws = append(ws, w)
+    }
+  }
+  sc.wlk.Unlock()
+  if len(ws) == 0 {
+    return
+  }
+
+  item, err2 := watchItem(v)
+  if err2 != nil {
+    sc.log.Error("watch: encoding descriptor", "event", ev.String(), "err", err2)
+    return
+  }
+  res := &pb.WatchRes{Event: &ev, Item: item}
+  if err != nil {
+    estr := err.Error()
+    code := xrpc.ErrCode(err)
+    res.Error = &estr
+    res.ErrCode = &code
+  }
+
+  sc.wlk.Lock()
+  defer sc.wlk.Unlock()
+  for _, w := range ws {
+    if _, ok := sc.watchers[w]; !ok {
+      continue // gone meanwhile.
+    }
+    select {
+    case w.events <- res:
+    default:
+      // too slow. drop the watcher, and tell it so.
+      delete(sc.watchers, w)
+      close(w.overflow)
+    }
+  }
+}
+
+// watchKey returns the type of descriptor v, and its transport.
+func watchKey(v interface{}) (pb.TType, *transport) {
+  switch v := v.(type) {
+  case *transport:
+    return pb.TType_TTypeTransport, v
+  case *listener:
+    return pb.TType_TTypeListener, v.xport
+  case *dialer:
+    return pb.TType_TTypeDialer, v.xport
+  case *conn:
+    return pb.TType_TTypeConn, v.xport
+  case *stream:
+    return pb.TType_TTypeStream, v.conn.xport
+  default:
+    return pb.TType_TTypeNil, nil
+  }
+}
+
+// watchItem encodes descriptor v like ListRes does.
+func watchItem(v interface{}) (*pb.ListRes_Item, error) {
+  switch v := v.(type) {
+  case *transport:
+    return pb.ListRes_Item_Transport(v.PB())
+  case *listener:
+    return pb.ListRes_Item_Listener(v.PB())
+  case *dialer:
+    return pb.ListRes_Item_Dialer(v.PB())
+  case *conn:
+    return pb.ListRes_Item_Conn(v.PB())
+  case *stream:
+    return pb.ListRes_Item_Stream(v.PB())
+  default:
+    return nil, errors.New("unknown type")
+  }
+}
+
+// handleWatchReq streams events to the client, until it closes s (or
+// sends another rpc), or falls behind.
+func handleWatchReq(sc *ServerClient, s *rpcCall, req *pb.WatchReq) error {
+  w := sc.watch(req)
+  defer sc.unwatch(w)
+
+  // events that happen from here on are sent. say so.
+  watching := pb.WatchRes_Watching
+  if err := xrpc.WatchRes(s, &pb.WatchRes{Event: &watching}, nil); err != nil {
+    return err
+  }
+  s.answered()
+
+  ctx, cancel := s.watchContext()
+  defer cancel()
+
+  for {
+    select {
+    case res := <-w.events:
+      if err := xrpc.WatchRes(s, res, nil); err != nil {
+        return err
+      }
+    case <-w.overflow:
+      return errWatchOverflow
+    case <-ctx.Done():
+      return nil
+    }
+  }
+}
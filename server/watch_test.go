@@ -0,0 +1,174 @@
+package xtpserver_test
+
+import (
+  "strings"
+  "testing"
+  "time"
+
+  xclient "github.com/libp2p/go-xtp-ctl/client"
+  ximpls "github.com/libp2p/go-xtp-ctl/impls"
+  pb "github.com/libp2p/go-xtp-ctl/pb"
+  "github.com/libp2p/go-xtp-ctl/xtptest"
+  ma "github.com/multiformats/go-multiaddr"
+)
+
+func watch(t *testing.T, c *xclient.Client, types []pb.TType, tids ...int64) *xclient.Watch {
+  t.Helper()
+  w, err := c.Watch(types, tids...)
+  if err != nil {
+    t.Fatal(err)
+  }
+  t.Cleanup(func() { w.Close() })
+  return w
+}
+
+// next returns the next event of w.
+func next(t *testing.T, w *xclient.Watch) *pb.WatchRes {
+  t.Helper()
+  type result struct {
+    res *pb.WatchRes
+    err error
+  }
+  ch := make(chan result, 1)
+  go func() {
+    res, err := w.Next()
+    ch <- result{res, err}
+  }()
+  select {
+  case r := <-ch:
+    if r.err != nil {
+      t.Fatal("watch:", r.err)
+    }
+    return r.res
+  case <-time.After(5 * time.Second):
+    t.Fatal("watch: no event")
+    return nil
+  }
+}
+
+func requireEvent(t *testing.T, w *xclient.Watch, ev pb.WatchRes_Event, typ pb.TType, id int64) *pb.WatchRes {
+  t.Helper()
+  res := next(t, w)
+  if res.GetEvent() != ev || res.GetItem().GetType() != typ || id != 0 && res.GetItem().GetId() != id {
+    t.Fatalf("got %s of %s %d, expected %s of %s %d", res.GetEvent(), res.GetItem().GetType(), res.GetItem().GetId(), ev, typ, id)
+  }
+  return res
+}
+
+func idOf(v interface{}) int64 {
+  return v.(interface{ Id() int64 }).Id()
+}
+
+func TestWatchEvents(t *testing.T) {
+  h := xtptest.NewMemory(t, &ximpls.MemoryTransport{})
+  w := watch(t, h.Client, nil)
+  mt := h.Client.Transport("/memory")
+
+  l, err := mt.Listen(ma.StringCast("/memory/a"))
+  if err != nil {
+    t.Fatal(err)
+  }
+  requireEvent(t, w, pb.WatchRes_Opened, pb.TType_TTypeListener, idOf(l))
+  d, err := mt.Dialer(ma.StringCast("/memory/d"))
+  if err != nil {
+    t.Fatal(err)
+  }
+  requireEvent(t, w, pb.WatchRes_Opened, pb.TType_TTypeDialer, idOf(d))
+
+  if _, err := d.Dial(ma.StringCast("/memory/nobody")); err == nil {
+    t.Fatal("dialed nobody")
+  }
+  res := requireEvent(t, w, pb.WatchRes_Error, pb.TType_TTypeDialer, idOf(d))
+  if res.GetErrCode() != pb.ErrCode_ErrCodeConnRefused || res.GetError() == "" {
+    t.Fatal("dial error event:", res.GetErrCode(), res.GetError())
+  }
+
+  go l.Accept()
+  c, err := d.Dial(l.Multiaddr())
+  if err != nil {
+    t.Fatal(err)
+  }
+  // the two ends of the conn, in either order.
+  got := map[pb.WatchRes_Event]int64{}
+  for i := 0; i < 2; i++ {
+    res := next(t, w)
+    got[res.GetEvent()] = res.GetItem().GetId()
+  }
+  if got[pb.WatchRes_Dialed] != idOf(c) || got[pb.WatchRes_Accepted] == 0 {
+    t.Fatal("conn events:", got)
+  }
+
+  c.Close()
+  requireEvent(t, w, pb.WatchRes_Closed, pb.TType_TTypeConn, idOf(c))
+  d.Close()
+  requireEvent(t, w, pb.WatchRes_Closed, pb.TType_TTypeDialer, idOf(d))
+  l.Close()
+  // the listener's conn closes with it.
+  for {
+    res := next(t, w)
+    if res.GetEvent() != pb.WatchRes_Closed {
+      t.Fatal("expected closes, got", res.GetEvent())
+    }
+    if res.GetItem().GetType() == pb.TType_TTypeListener {
+      break
+    }
+  }
+}
+
+func TestWatchFilters(t *testing.T) {
+  h := xtptest.NewMemory(t, &ximpls.MemoryTransport{}, &ximpls.TCPTransport{})
+  mt, tt := h.Client.Transport("/memory"), h.Client.Transport("/tcp")
+  dialers := watch(t, h.Client, []pb.TType{pb.TType_TTypeDialer})
+  memory := watch(t, h.Client, nil, idOf(mt))
+
+  if _, err := tt.Listen(ma.StringCast("/ip4/127.0.0.1/tcp/0")); err != nil {
+    t.Fatal(err)
+  }
+  ml, err := mt.Listen(ma.StringCast("/memory/a"))
+  if err != nil {
+    t.Fatal(err)
+  }
+  d, err := tt.Dialer(ma.StringCast("/ip4/127.0.0.1/tcp/0"))
+  if err != nil {
+    t.Fatal(err)
+  }
+  d2, err := mt.Dialer(ma.StringCast("/memory/d"))
+  if err != nil {
+    t.Fatal(err)
+  }
+
+  requireEvent(t, dialers, pb.WatchRes_Opened, pb.TType_TTypeDialer, idOf(d))
+  requireEvent(t, dialers, pb.WatchRes_Opened, pb.TType_TTypeDialer, idOf(d2))
+  requireEvent(t, memory, pb.WatchRes_Opened, pb.TType_TTypeListener, idOf(ml))
+  requireEvent(t, memory, pb.WatchRes_Opened, pb.TType_TTypeDialer, idOf(d2))
+}
+
+// TestWatchOverflow ends a watch that does not keep up.
+func TestWatchOverflow(t *testing.T) {
+  h := xtptest.NewMemory(t, &ximpls.MemoryTransport{})
+  w := watch(t, h.Client, nil)
+  mt := h.Client.Transport("/memory")
+
+  const n = 5000 // events, beyond the backlog and what the stream buffers.
+  for i := 0; i < n; i++ {
+    if _, err := mt.Dial(ma.StringCast("/memory/nobody")); err == nil {
+      t.Fatal("dialed nobody")
+    }
+  }
+
+  for i := 0; ; i++ {
+    res, err := w.Next()
+    if err != nil {
+      if !strings.Contains(err.Error(), "fell behind") {
+        t.Fatal("expected the watch to fall behind, got", err)
+      }
+      if i >= n {
+        t.Fatalf("got all %d events, then %s", i, err)
+      }
+      return
+    }
+    if res.GetEvent() != pb.WatchRes_Error {
+      t.Fatal("unexpected event", res.GetEvent())
+    }
+  }
+}
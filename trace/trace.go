@@ -0,0 +1,40 @@
+// Package xtptrace traces xtp-ctl rpcs with OpenTelemetry, across clients
+// and servers: the W3C trace context of a request travels in
+// RPC.traceparent (see xtpctlrpc), so one trace covers both ends of an
+// rpc. Spans go to the global TracerProvider, so tracing is off until one
+// is set:
+//
+//   exp := tracetest.NewInMemoryExporter()
+//   otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSyncer(exp)))
+//   ...
+//   for _, s := range exp.GetSpans() { ... }
+//
+package xtptrace
+
+import (
+  "context"
+
+  "go.opentelemetry.io/otel"
+  "go.opentelemetry.io/otel/attribute"
+  "go.opentelemetry.io/otel/codes"
+  "go.opentelemetry.io/otel/trace"
+)
+
+// Name is the instrumentation name of xtp-ctl's tracer.
+const Name = "github.com/libp2p/go-xtp-ctl"
+
+// Start starts a span named name, a child of the span (or remote span
+// context) in ctx, with the tracer of the global TracerProvider. It
+// returns ctx with the new span.
+func Start(ctx context.Context, name string, attrs ...attribute.KeyValue) (context.Context, trace.Span) {
+  return otel.Tracer(Name).Start(ctx, name, trace.WithAttributes(attrs...))
+}
+
+// End ends span, failed if err is not nil.
+func End(span trace.Span, err error) {
+  if err != nil {
+    span.RecordError(err)
+    span.SetStatus(codes.Error, err.Error())
+  }
+  span.End()
+}
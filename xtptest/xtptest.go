@@ -0,0 +1,214 @@
+// Package xtptest runs an xtp-ctl server and client together, for end to
+// end tests of the wire protocol:
+//
+//   func TestListen(t *testing.T) {
+//     h := xtptest.New(t, &xtpimpls.MemoryTransport{})
+//     l, err := h.Client.Transport("/memory").Listen(ma.StringCast("/memory/a"))
+//     ...
+//     h.RequireCount(pb.TType_TTypeListener, 1)
+//   }
+//
+package xtptest
+
+import (
+  "fmt"
+  "sync"
+  "testing"
+
+  ma "github.com/multiformats/go-multiaddr"
+  proto "github.com/gogo/protobuf/proto"
+  xclient "github.com/libp2p/go-xtp-ctl/client"
+  ximpls "github.com/libp2p/go-xtp-ctl/impls"
+  xnet "github.com/libp2p/go-xtp-ctl/net"
+  pb "github.com/libp2p/go-xtp-ctl/pb"
+  xrpc "github.com/libp2p/go-xtp-ctl/rpc"
+  xserver "github.com/libp2p/go-xtp-ctl/server"
+)
+
+// TCPAddr is the (ephemeral) address New serves on.
+var TCPAddr = ma.StringCast("/ip4/127.0.0.1/tcp/0")
+
+// Harness is an xtp-ctl server with a client connected to it. Both are
+// closed when the test ends.
+type Harness struct {
+  T      testing.TB
+  Server *xserver.Server
+  Client *xclient.Client
+
+  dial    func() (*xclient.Client, error)
+  lk      sync.Mutex
+  clients []*xclient.Client
+}
+
+// New starts a server on TCPAddr, offering xports, and connects a client.
+func New(t testing.TB, xports ...xnet.Transport) *Harness {
+  t.Helper()
+
+  s, err := xserver.NewServer(TCPAddr, xports)
+  if err != nil {
+    t.Fatal("xtptest: starting server:", err)
+  }
+  saddr := s.Listener.Multiaddr()
+  return start(t, s, func() (*xclient.Client, error) {
+    return xclient.NewClient(saddr)
+  })
+}
+
+// NewMemory is New, with the server listening on a /memory address
+// instead of a socket.
+func NewMemory(t testing.TB, xports ...xnet.Transport) *Harness {
+  t.Helper()
+
+  tpt := &ximpls.MemoryTransport{}
+  saddr := ma.StringCast(fmt.Sprintf("/memory/xtptest-%p", t))
+  l, err := tpt.Listen(saddr)
+  if err != nil {
+    t.Fatal("xtptest: starting server:", err)
+  }
+  s := &xserver.Server{Listener: l, Xports: xports}
+  return start(t, s, func() (*xclient.Client, error) {
+    c, err := tpt.Dial(saddr)
+    if err != nil {
+      return nil, err
+    }
+    return xclient.NewClientConn(c)
+  })
+}
+
+func start(t testing.TB, s *xserver.Server, dial func() (*xclient.Client, error)) *Harness {
+  t.Helper()
+
+  h := &Harness{T: t, Server: s, dial: dial}
+  go s.Serve()
+  t.Cleanup(h.Close)
+
+  h.Client = h.NewClient()
+  return h
+}
+
+// NewClient connects another client to the server.
+func (h *Harness) NewClient() *xclient.Client {
+  h.T.Helper()
+
+  c, err := h.dial()
+  if err != nil {
+    h.T.Fatal("xtptest: connecting client:", err)
+  }
+  h.lk.Lock()
+  h.clients = append(h.clients, c)
+  h.lk.Unlock()
+  return c
+}
+
+// Close closes the clients and the server. It is called when the test ends.
+func (h *Harness) Close() {
+  h.lk.Lock()
+  cs := h.clients
+  h.clients = nil
+  h.lk.Unlock()
+
+  for _, c := range cs {
+    c.Close()
+  }
+  h.Server.Close()
+}
+
+// Stream opens a new xtp-ctl stream, for sending rpcs by hand. It is
+// closed when the test ends.
+func (h *Harness) Stream() xnet.Stream {
+  h.T.Helper()
+
+  s, err := h.Client.Conn.Dial()
+  if err != nil {
+    h.T.Fatal("xtptest: opening stream:", err)
+  }
+  h.T.Cleanup(func() { s.Close() })
+  return s
+}
+
+// RoundTrip sends an rpc of type typ with message m on a new stream, and
+// returns the raw response, so tests can check the wire types.
+func (h *Harness) RoundTrip(typ pb.RPC_Type, m proto.Message) *pb.RPC {
+  h.T.Helper()
+
+  s := h.Stream()
+  if err := xrpc.WriteRPCMsg(s, typ, m, nil); err != nil {
+    h.T.Fatal("xtptest: sending rpc:", err)
+  }
+  res := &pb.RPC{}
+  if err := xrpc.ReadRPC(s, res); err != nil {
+    h.T.Fatal("xtptest: reading rpc:", err)
+  }
+  return res
+}
+
+// List returns the server's descriptors of the given types (all, if none
+// are given) for h.Client.
+func (h *Harness) List(types ...pb.TType) []*pb.ListRes_Item {
+  h.T.Helper()
+
+  if len(types) == 0 {
+    types = allTypes
+  }
+  s := h.Stream()
+  items, err := xrpc.ListReq(s, types)
+  if err != nil {
+    h.T.Fatal("xtptest: listing:", err)
+  }
+  s.Close()
+  return items
+}
+
+var allTypes = []pb.TType{
+  pb.TType_TTypeTransport,
+  pb.TType_TTypeListener,
+  pb.TType_TTypeDialer,
+  pb.TType_TTypeConn,
+  pb.TType_TTypeStream,
+}
+
+// Ids returns the ids of the server's descriptors of type typ.
+func (h *Harness) Ids(typ pb.TType) []int64 {
+  h.T.Helper()
+
+  var ids []int64
+  for _, i := range h.List(typ) {
+    if i.GetType() == typ {
+      ids = append(ids, i.GetId())
+    }
+  }
+  return ids
+}
+
+// RequireCount fails the test unless the server has n descriptors of
+// type typ.
+func (h *Harness) RequireCount(typ pb.TType, n int) {
+  h.T.Helper()
+
+  if ids := h.Ids(typ); len(ids) != n {
+    h.T.Fatalf("xtptest: expected %d %s descriptors, found %d: %v", n, typ, len(ids), ids)
+  }
+}
+
+// RequireId fails the test unless the server has descriptor id, of type typ.
+func (h *Harness) RequireId(typ pb.TType, id int64) {
+  h.T.Helper()
+
+  for _, id2 := range h.Ids(typ) {
+    if id2 == id {
+      return
+    }
+  }
+  h.T.Fatalf("xtptest: %s descriptor %d not found", typ, id)
+}
+
+// RequireNoId fails the test if the server has descriptor id, of any type.
+func (h *Harness) RequireNoId(id int64) {
+  h.T.Helper()
+
+  for _, i := range h.List() {
+    if i.GetId() == id {
+      h.T.Fatalf("xtptest: descriptor %d (%s) still open", id, i.GetType())
+    }
+  }
+}